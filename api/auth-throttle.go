@@ -0,0 +1,60 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package api
+
+import (
+	"math"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/ratelimit"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	authFailuresMu sync.Mutex
+	authFailures   *ratelimit.ClientLimiters // built from cfg.AuthFailureLimit on first use
+)
+
+// Takes an attempt from the failed authentication budget of the client address before its
+// password is checked. settle hands the attempt back when the credentials were valid, so only
+// failures count. A client that used up its budget gets the seconds to wait before retrying.
+func throttleAuthentication(client string) (settle func(authenticated bool), retryAfter int) {
+	if cfg.AuthFailureLimit <= 0 {
+		return func(bool) {}, 0
+	}
+	now := time.Now()
+	authFailuresMu.Lock()
+	if authFailures == nil {
+		authFailures = ratelimit.NewClientLimiters(rate.Limit(float64(cfg.AuthFailureLimit)/60), cfg.AuthFailureLimit)
+	}
+	reservation := authFailures.Limiter(client, now).ReserveN(now, 1)
+	authFailuresMu.Unlock()
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return nil, int(math.Ceil(delay.Seconds()))
+	}
+	return func(authenticated bool) {
+		if authenticated {
+			reservation.CancelAt(now)
+		}
+	}, 0
+}
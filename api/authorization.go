@@ -0,0 +1,197 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package api
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/identity"
+	"policy-opa-pdp/pkg/log"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// The actions authorized by the authorization policy.
+const (
+	actionDecision    = "decision"
+	actionStatistics  = "statistics"
+	actionHealthcheck = "healthcheck"
+)
+
+// the query answered by the authorization policy
+const authzQuery = "data.pdp.authz.allow"
+
+// the built-in authorization policy, used unless AUTHZ_POLICY_FILE is set
+//
+//go:embed authz.rego
+var defaultAuthzPolicy string
+
+var (
+	authzMu       sync.Mutex
+	authzPrepared *rego.PreparedEvalQuery
+)
+
+// Compiles the authorization policy, the one in AUTHZ_POLICY_FILE or the built-in one. It is
+// evaluated by an OPA instance of its own, apart from the policies deployed by PAP.
+func InitializeAuthorization() error {
+	authzMu.Lock()
+	defer authzMu.Unlock()
+	prepared, err := prepareAuthorization()
+	if err != nil {
+		return err
+	}
+	authzPrepared = prepared
+	return nil
+}
+
+// compiles the configured authorization policy
+func prepareAuthorization() (*rego.PreparedEvalQuery, error) {
+	name, policy := "authz.rego", defaultAuthzPolicy
+	if cfg.AuthzPolicyFile != "" {
+		content, err := os.ReadFile(cfg.AuthzPolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the authorization policy: %w", err)
+		}
+		name, policy = cfg.AuthzPolicyFile, string(content)
+	}
+	prepared, err := rego.New(
+		rego.Query(authzQuery),
+		rego.Module(name, policy),
+		rego.SetRegoVersion(ast.RegoV1),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization policy %s: %w", name, err)
+	}
+	return &prepared, nil
+}
+
+// returns the compiled authorization policy, compiling it on first use
+func authorizationQuery() (*rego.PreparedEvalQuery, error) {
+	authzMu.Lock()
+	defer authzMu.Unlock()
+	if authzPrepared == nil {
+		prepared, err := prepareAuthorization()
+		if err != nil {
+			return nil, err
+		}
+		authzPrepared = prepared
+	}
+	return authzPrepared, nil
+}
+
+// Returns whether the caller may perform the action, policyName is only set for decisions.
+// The action is refused unless the policy allows it.
+func authorized(ctx context.Context, caller identity.Identity, action, policyName string) (bool, error) {
+	query, err := authorizationQuery()
+	if err != nil {
+		return false, err
+	}
+	roles := caller.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	input := map[string]interface{}{
+		"caller": map[string]interface{}{
+			"name":   caller.Name,
+			"method": caller.Method,
+			"roles":  roles,
+		},
+		"action": action,
+	}
+	if action == actionDecision {
+		input["policyName"] = policyName
+	}
+	results, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return false, err
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return false, nil
+	}
+	allowed, _ := results[0].Expressions[0].Value.(bool)
+	return allowed, nil
+}
+
+// handles authorization of the authenticated caller for the action
+func authorize(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if checkAuthorized(res, req, action, "") {
+			next(res, req)
+		}
+	}
+}
+
+// handles authorization of decisions, which depends on the requested policy. The body is read
+// up to cfg.MaxDecisionBody bytes and handed on to the decision handler.
+func authorizeDecision(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(res, req.Body, int64(cfg.MaxDecisionBody)))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Warnf("Refused decision request of more than %d bytes", tooLarge.Limit)
+			http.Error(res, fmt.Sprintf("Request body larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(res, "Failed to read the request body", http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		// a body that cannot be parsed is authorized without a policy name, it is refused by the decision handler
+		var decisionReq struct {
+			PolicyName string `json:"policyName"`
+		}
+		_ = json.Unmarshal(body, &decisionReq)
+		if checkAuthorized(res, req, actionDecision, decisionReq.PolicyName) {
+			next(res, req)
+		}
+	}
+}
+
+// evaluates the authorization policy and writes the error response when the caller is refused
+func checkAuthorized(res http.ResponseWriter, req *http.Request, action, policyName string) bool {
+	caller, _ := identity.FromContext(req.Context())
+	allowed, err := authorized(req.Context(), caller, action, policyName)
+	if err != nil {
+		log.Errorf("Authorization of %s for %s failed: %v", caller, action, err)
+		http.Error(res, "Authorization failed", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		if policyName != "" {
+			log.Warnf("%s is not authorized for %s of %s", caller, action, policyName)
+		} else {
+			log.Warnf("%s is not authorized for %s", caller, action)
+		}
+		http.Error(res, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
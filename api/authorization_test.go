@@ -0,0 +1,199 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/identity"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// compiles the authorization policy in the file, the built-in one when empty, and restores it after the test
+func setupAuthorization(t *testing.T, policyFile string) {
+	authzPolicyFile := cfg.AuthzPolicyFile
+	t.Cleanup(func() {
+		cfg.AuthzPolicyFile = authzPolicyFile
+		authzPrepared = nil
+	})
+	cfg.AuthzPolicyFile = policyFile
+	assert.NoError(t, InitializeAuthorization())
+}
+
+func TestAuthorized_DefaultPolicy(t *testing.T) {
+	setupAuthorization(t, "")
+
+	admin := identity.Identity{Name: "policyadmin", Method: identity.MethodBasic, Roles: []string{consts.ApiUserRole}}
+	pep := identity.Identity{Name: "spiffe://example.org/pep", Method: identity.MethodMTLS}
+	abacClient := identity.Identity{Name: "abac-client", Method: identity.MethodJWT, Roles: []string{"decision:abac."}}
+	monitor := identity.Identity{Name: "prometheus", Method: identity.MethodJWT, Roles: []string{"monitor"}}
+	noRoles := identity.Identity{Name: "nobody", Method: identity.MethodJWT}
+	fileUser := identity.Identity{Name: "grafana", Method: identity.MethodBasic, Roles: []string{"monitor"}}
+	fileUserNoRoles := identity.Identity{Name: "legacy", Method: identity.MethodBasic}
+
+	tests := []struct {
+		caller     identity.Identity
+		action     string
+		policyName string
+		allowed    bool
+	}{
+		{admin, actionDecision, "any.policy", true},
+		{admin, actionStatistics, "", true},
+		{admin, "statistics-reset", "", true},
+		{pep, actionDecision, "any.policy", true},
+		{pep, actionHealthcheck, "", true},
+		{pep, "statistics-reset", "", false},
+		{abacClient, actionDecision, "abac.policy", true},
+		{abacClient, actionDecision, "role.policy", false},
+		{abacClient, actionStatistics, "", false},
+		{monitor, actionStatistics, "", true},
+		{monitor, actionHealthcheck, "", true},
+		{monitor, actionDecision, "abac.policy", false},
+		{noRoles, actionDecision, "abac.policy", false},
+		{fileUser, actionStatistics, "", true},
+		{fileUser, actionDecision, "abac.policy", false},
+		{fileUserNoRoles, actionStatistics, "", false},
+		{fileUserNoRoles, actionDecision, "abac.policy", false},
+		{fileUserNoRoles, "statistics-reset", "", false},
+		{identity.Identity{}, actionStatistics, "", false},
+	}
+	for _, tt := range tests {
+		allowed, err := authorized(context.Background(), tt.caller, tt.action, tt.policyName)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.allowed, allowed, "%s %s %s", tt.caller, tt.action, tt.policyName)
+	}
+}
+
+func TestAuthorized_PolicyFile(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "authz.rego")
+	assert.NoError(t, os.WriteFile(policyFile, []byte(`package pdp.authz
+
+default allow := false
+
+allow if input.caller.name == "pep"
+`), 0644))
+	setupAuthorization(t, policyFile)
+
+	allowed, err := authorized(context.Background(), identity.Identity{Name: "pep", Method: identity.MethodJWT}, actionDecision, "any")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = authorized(context.Background(), identity.Identity{Name: "policyadmin", Method: identity.MethodBasic}, actionDecision, "any")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestInitializeAuthorization_Invalid(t *testing.T) {
+	setupAuthorization(t, "")
+
+	cfg.AuthzPolicyFile = filepath.Join(t.TempDir(), "missing.rego")
+	assert.Error(t, InitializeAuthorization())
+
+	cfg.AuthzPolicyFile = filepath.Join(t.TempDir(), "invalid.rego")
+	assert.NoError(t, os.WriteFile(cfg.AuthzPolicyFile, []byte("package pdp.authz\n\nallow if {"), 0644))
+	assert.ErrorContains(t, InitializeAuthorization(), "invalid authorization policy")
+}
+
+func TestAuthorizeDecision(t *testing.T) {
+	setupAuthorization(t, "")
+
+	var received string
+	handler := authorizeDecision(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received = string(body)
+		res.WriteHeader(http.StatusOK)
+	}))
+	caller := identity.Identity{Name: "abac-client", Method: identity.MethodJWT, Roles: []string{"decision:abac."}}
+
+	tests := []struct {
+		body       string
+		statusCode int
+	}{
+		{`{"policyName": "abac.policy", "input": {}}`, http.StatusOK},
+		{`{"policyName": "role.policy", "input": {}}`, http.StatusForbidden},
+		{`not json`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		received = ""
+		req := httptest.NewRequest(http.MethodPost, "/policy/pdpo/v1/decision", strings.NewReader(tt.body))
+		req = req.WithContext(identity.NewContext(req.Context(), caller))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, tt.statusCode, rr.Code, tt.body)
+		if tt.statusCode == http.StatusOK {
+			assert.Equal(t, tt.body, received, "the decision handler receives the whole body")
+		}
+	}
+}
+
+func TestAuthorizeDecision_BodyTooLarge(t *testing.T) {
+	setupAuthorization(t, "")
+	maxDecisionBody := cfg.MaxDecisionBody
+	defer func() { cfg.MaxDecisionBody = maxDecisionBody }()
+	body := `{"policyName": "abac.policy", "input": {}}`
+	cfg.MaxDecisionBody = len(body)
+
+	var received string
+	handler := authorizeDecision(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		content, _ := io.ReadAll(req.Body)
+		received = string(content)
+		res.WriteHeader(http.StatusOK)
+	}))
+	caller := identity.Identity{Name: "abac-client", Method: identity.MethodJWT, Roles: []string{"decision:abac."}}
+	serve := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/policy/pdpo/v1/decision", strings.NewReader(body))
+		req = req.WithContext(identity.NewContext(req.Context(), caller))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, serve(body).Code)
+	assert.Equal(t, body, received, "the decision handler receives the whole body")
+
+	received = ""
+	rr := serve(body + " ")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Empty(t, received, "a body over the limit is not handed on")
+}
+
+func TestAuthorize(t *testing.T) {
+	setupAuthorization(t, "")
+
+	handler := authorize(actionStatistics, http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	for caller, statusCode := range map[string]int{"monitor": http.StatusOK, "decision": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/policy/pdpo/v1/statistics", nil)
+		req = req.WithContext(identity.NewContext(req.Context(), identity.Identity{Name: caller, Method: identity.MethodJWT, Roles: []string{caller}}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, statusCode, rr.Code, caller)
+	}
+}
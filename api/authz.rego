@@ -0,0 +1,44 @@
+# Authorization of the PDP API. The api package asks data.pdp.authz.allow whether the caller may
+# perform an action, with the input
+#
+#   {"caller": {"name": "...", "method": "basic|mtls|jwt", "roles": ["..."]},
+#    "action": "decision|statistics|healthcheck", "policyName": "..."}
+#
+# where policyName is only set for decisions. Actions not granted below, such as those of
+# administrative endpoints, are only allowed to the admin role. Set AUTHZ_POLICY_FILE to replace
+# this policy, it must define data.pdp.authz.allow.
+package pdp.authz
+
+default allow := false
+
+# client certificates may ask for decisions and monitor the PDP, Basic auth users and bearer tokens
+# carry their own roles, the API_USER credential holds the admin role
+
+roles contains role if {
+	input.caller.method == "mtls"
+	some role in ["decision", "monitor"]
+}
+
+roles contains role if some role in input.caller.roles
+
+allow if "admin" in roles
+
+allow if {
+	input.action in {"statistics", "healthcheck"}
+	"monitor" in roles
+}
+
+allow if {
+	input.action == "decision"
+	"decision" in roles
+}
+
+# "decision:<prefix>" roles may only evaluate the policies whose name starts with the prefix
+allow if {
+	input.action == "decision"
+	some role in roles
+	startswith(role, "decision:")
+	prefix := substring(role, count("decision:"), -1)
+	prefix != ""
+	startswith(input.policyName, prefix)
+}
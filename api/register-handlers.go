@@ -18,16 +18,29 @@
 
 // Package api provides HTTP handlers for the policy-opa-pdp service.
 // This package includes handlers for decision making, bundle serving, health checks, and readiness probes.
-// It also includes basic authentication middleware for securing certain endpoints.
+// It also includes authentication middleware for securing certain endpoints, callers
+// authenticate with a verified client certificate, a JWT bearer token or basic authentication,
+// the bundle endpoint is secured with the bundle token instead. Authenticated callers are
+// authorized per endpoint and per policy name by a Rego policy, see authz.rego.
 package api
 
 import (
 	"net/http"
 	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
 	"policy-opa-pdp/pkg/bundleserver"
+	"policy-opa-pdp/pkg/credentials"
 	"policy-opa-pdp/pkg/decision"
 	"policy-opa-pdp/pkg/healthcheck"
+	"policy-opa-pdp/pkg/identity"
+	"policy-opa-pdp/pkg/jwtauth"
+	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/metrics"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/ratelimit"
+	"strconv"
+	"strings"
 )
 
 // RegisterHandlers registers the HTTP handlers for the service.
@@ -35,11 +48,13 @@ func RegisterHandlers() {
 
 	// Handler for OPA decision making
 	opaDecisionHandler := http.HandlerFunc(decision.OpaDecision)
-	http.Handle("/policy/pdpo/v1/decision", basicAuth(opaDecisionHandler))
+	http.Handle("/policy/pdpo/v1/decision", authenticate(authorizeDecision(opaDecisionHandler)))
 
-	//This api is used internally by OPA-SDK
-	bundleServerHandler := http.HandlerFunc(bundleserver.GetBundle)
-	http.Handle("/opa/bundles/", bundleServerHandler)
+	//This api is used internally by OPA-SDK, which is given the bundle token through its config
+	if cfg.ServeBundles {
+		bundleServerHandler := http.HandlerFunc(bundleserver.GetBundle)
+		http.Handle(consts.BundleServerPath+"/", bundleserver.Authenticate(bundleServerHandler))
+	}
 
 	// Handler for kubernetes readiness probe
 	readinessProbeHandler := http.HandlerFunc(readinessProbe)
@@ -47,11 +62,11 @@ func RegisterHandlers() {
 
 	// Handler for health checks
 	healthCheckHandler := http.HandlerFunc(healthcheck.HealthCheckHandler)
-	http.HandleFunc("/policy/pdpo/v1/healthcheck", basicAuth(healthCheckHandler))
+	http.HandleFunc("/policy/pdpo/v1/healthcheck", authenticate(authorize(actionHealthcheck, healthCheckHandler)))
 
 	// Handler for statistics report
 	statisticsReportHandler := http.HandlerFunc(metrics.FetchCurrentStatistics)
-	http.HandleFunc("/policy/pdpo/v1/statistics", basicAuth(statisticsReportHandler))
+	http.HandleFunc("/policy/pdpo/v1/statistics", authenticate(authorize(actionStatistics, statisticsReportHandler)))
 
 }
 
@@ -59,24 +74,79 @@ func RegisterHandlers() {
 func basicAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		user, pass, ok := req.BasicAuth()
-		if !ok || !validateCredentials(user, pass) {
-			res.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		if !ok || !cfg.AllowBasicAuth {
+			unauthorized(res)
+			return
+		}
+		client := ratelimit.ClientAddress(req)
+		settle, retryAfter := throttleAuthentication(client)
+		if settle == nil {
+			log.Warnf("Refused Basic auth of %s from %s, too many failed attempts", user, client)
+			res.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(res, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		roles, valid := credentials.Authenticate(user, pass)
+		settle(valid)
+		if !valid {
+			unauthorized(res)
 			return
 		}
-		next(res, req)
+		caller := identity.Identity{Name: user, Method: identity.MethodBasic, Roles: roles}
+		next(res, req.WithContext(identity.NewContext(req.Context(), caller)))
 	}
 }
 
-// validates Credentials for http server
-func validateCredentials(username, password string) bool {
-	validUser := cfg.Username
-	validPass := cfg.Password
-	return username == validUser && password == validPass
+// handles authentication, a verified client certificate or a bearer token authenticates the caller without a password
+func authenticate(next http.HandlerFunc) http.HandlerFunc {
+	passwordAuth := basicAuth(next)
+	return func(res http.ResponseWriter, req *http.Request) {
+		if caller, ok := identity.FromRequest(req); ok {
+			next(res, req.WithContext(identity.NewContext(req.Context(), caller)))
+			return
+		}
+		if token, ok := bearerToken(req); ok && jwtauth.Enabled() {
+			caller, err := jwtauth.Authenticate(req.Context(), token)
+			if err != nil {
+				log.Warnf("Bearer token refused: %v", err)
+				res.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(res, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next(res, req.WithContext(identity.NewContext(req.Context(), caller)))
+			return
+		}
+		passwordAuth(res, req)
+	}
 }
 
-// handles readiness probe endpoint
+// returns the token of a bearer authorization header
+func bearerToken(req *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// refuses an unauthenticated request, challenging for the enabled authentication schemes
+func unauthorized(res http.ResponseWriter) {
+	if cfg.AllowBasicAuth {
+		res.Header().Add("WWW-Authenticate", `Basic realm="Restricted"`)
+	}
+	if jwtauth.Enabled() {
+		res.Header().Add("WWW-Authenticate", "Bearer")
+	}
+	http.Error(res, "Unauthorized", http.StatusUnauthorized)
+}
+
+// handles readiness probe endpoint, a terminated PDP no longer accepts traffic
 func readinessProbe(res http.ResponseWriter, req *http.Request) {
+	if pdpstate.GetCurrentState() == model.Terminated {
+		res.WriteHeader(http.StatusServiceUnavailable)
+		res.Write([]byte("Terminated"))
+		return
+	}
 	res.WriteHeader(http.StatusOK)
 	res.Write([]byte("Ready"))
 }
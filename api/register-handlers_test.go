@@ -20,13 +20,23 @@
 package api
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"net/http"
 	"net/http/httptest"
 	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
 	"policy-opa-pdp/pkg/bundleserver"
 	"policy-opa-pdp/pkg/decision"
 	"policy-opa-pdp/pkg/healthcheck"
+	"policy-opa-pdp/pkg/identity"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpstate"
+	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // Mock configuration
@@ -44,7 +54,7 @@ func TestRegisterHandlers(t *testing.T) {
 		statusCode int
 	}{
 		{"/policy/pdpo/v1/decision", decision.OpaDecision, http.StatusUnauthorized},
-		{"/opa/bundles/", bundleserver.GetBundle, http.StatusInternalServerError},
+		{"/opa/bundles/", bundleserver.GetBundle, http.StatusUnauthorized},
 		{"/ready", readinessProbe, http.StatusOK},
 		{"/policy/pdpo/v1/healthcheck", healthcheck.HealthCheckHandler, http.StatusUnauthorized},
 	}
@@ -95,6 +105,103 @@ func TestBasicAuth(t *testing.T) {
 	}
 }
 
+func TestBasicAuth_ThrottlesFailedAttempts(t *testing.T) {
+	authFailureLimit := cfg.AuthFailureLimit
+	defer func() {
+		cfg.AuthFailureLimit = authFailureLimit
+		authFailures = nil
+	}()
+	cfg.AuthFailureLimit = 2
+	authFailures = nil
+
+	handler := basicAuth(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	serve := func(remoteAddr, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.SetBasicAuth("testuser", password)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("192.0.2.1:1234", "testpass").Code, "valid credentials do not count")
+	}
+	assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.1:1234", "wrongpass").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.1:1235", "wrongpass").Code)
+
+	rr := serve("192.0.2.1:1236", "testpass")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "the address is refused once its failures are used up")
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve("192.0.2.2:1234", "testpass").Code, "other addresses are not refused")
+}
+
+func TestAuthenticate(t *testing.T) {
+	allowBasicAuth, jwtJwks := cfg.AllowBasicAuth, cfg.JwtJwks
+	defer func() { cfg.AllowBasicAuth, cfg.JwtJwks = allowBasicAuth, jwtJwks }()
+
+	var caller identity.Identity
+	handler := authenticate(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		caller, _ = identity.FromContext(req.Context())
+		res.WriteHeader(http.StatusOK)
+	}))
+	clientCert := &x509.Certificate{Subject: pkix.Name{CommonName: "pep"}}
+
+	tests := []struct {
+		name           string
+		tlsState       *tls.ConnectionState
+		authorization  string
+		allowBasicAuth bool
+		jwtJwks        string
+		statusCode     int
+		challenge      []string
+		caller         identity.Identity
+	}{
+		{"verified certificate", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert}}}, "", false, "", http.StatusOK,
+			nil, identity.Identity{Name: "pep", Subject: "CN=pep", Method: identity.MethodMTLS}},
+		{"unverified certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}, "", true, "", http.StatusUnauthorized,
+			[]string{`Basic realm="Restricted"`}, identity.Identity{}},
+		{"password", &tls.ConnectionState{}, "Basic dGVzdHVzZXI6dGVzdHBhc3M=", true, "", http.StatusOK,
+			nil, identity.Identity{Name: "testuser", Method: identity.MethodBasic, Roles: []string{consts.ApiUserRole}}},
+		{"password disabled", nil, "Basic dGVzdHVzZXI6dGVzdHBhc3M=", false, "/nonexistent/jwks.json", http.StatusUnauthorized,
+			[]string{"Bearer"}, identity.Identity{}},
+		{"bearer tokens disabled", nil, "Bearer token", true, "", http.StatusUnauthorized,
+			[]string{`Basic realm="Restricted"`}, identity.Identity{}},
+		{"invalid bearer token", nil, "Bearer token", true, "/nonexistent/jwks.json", http.StatusUnauthorized,
+			[]string{`Bearer error="invalid_token"`}, identity.Identity{}},
+		{"no credentials", nil, "", true, "/nonexistent/jwks.json", http.StatusUnauthorized,
+			[]string{`Basic realm="Restricted"`, "Bearer"}, identity.Identity{}},
+	}
+
+	for _, tt := range tests {
+		caller = identity.Identity{}
+		cfg.AllowBasicAuth, cfg.JwtJwks = tt.allowBasicAuth, tt.jwtJwks
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.TLS = tt.tlsState
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != tt.statusCode {
+			t.Errorf("%s: authenticate returned wrong status code: got %v want %v", tt.name, status, tt.statusCode)
+		}
+		if challenge := rr.Header().Values("WWW-Authenticate"); !reflect.DeepEqual(challenge, tt.challenge) {
+			t.Errorf("%s: authenticate challenged with %v want %v", tt.name, challenge, tt.challenge)
+		}
+		if !reflect.DeepEqual(caller, tt.caller) {
+			t.Errorf("%s: handler saw caller %+v want %+v", tt.name, caller, tt.caller)
+		}
+	}
+}
+
 func TestReadinessProbe(t *testing.T) {
 	req, err := http.NewRequest("GET", "/ready", nil)
 	if err != nil {
@@ -114,3 +221,18 @@ func TestReadinessProbe(t *testing.T) {
 		t.Errorf("readinessProbe returned unexpected body: got %v want %v", rr.Body.String(), expected)
 	}
 }
+
+func TestReadinessProbe_Terminated(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Terminated
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+
+	rr := httptest.NewRecorder()
+	readinessProbe(rr, httptest.NewRequest("GET", "/ready", nil))
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("readinessProbe returned wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+	}
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -19,15 +19,18 @@
 // Package cfg provides configuration settings for the policy-opa-pdp service.
 // This package includes variables for various configuration settings such as log level,
 // Kafka server details, and credentials.It also includes functions to initialize these
-// settings and retrieve environment variables with default values.
+// settings and retrieve environment variables with default values. Secrets are held as Secret
+// values, which redact themselves when logged, and may also be read from mounted files.
 package cfg
 
 import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"policy-opa-pdp/consts"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // LogLevel        - The log level for the application.
@@ -35,21 +38,99 @@ import (
 // Topic           - The Kafka topic to subscribe to.
 // GroupId         - The Kafka consumer group ID.
 // Username        - The username for basic authentication.
-// Password        - The password for basic authentication.
+// Password        - The password for basic authentication, read from API_PASSWORD_FILE when set.
 // UseSASLForKAFKA - Flag to indicate if SASL should be used for Kafka.
 // KAFKA_USERNAME  - The Kafka username for SASL authentication.
-// KAFKA_PASSWORD  - The Kafka password for SASL authentication.
+// KAFKA_PASSWORD  - The Kafka password for SASL authentication, from the JAAS config in JAASLOGIN or JAASLOGIN_FILE.
+// SafeModePolicy   - The fallback policy evaluated for decisions while the PDP is in SAFE state.
+// SafeModeDecision - The decision served in SAFE state when no fallback policy is configured.
+// PersistenceDir   - The directory the PDP state is persisted in, persistence is disabled when empty.
+// PdpNameTemplate  - The template the PDP name is built from, e.g. "opa-{{.PodName}}".
+// PodName          - The name of the pod the PDP runs in.
+// PodNamespace     - The namespace of the pod the PDP runs in.
+// NodeName         - The name of the node the PDP runs on.
+// PdpGroups        - The PDP groups this PDP belongs to, the first one is its primary group.
+// HeartbeatJitter  - The random deviation applied to each heartbeat interval, in percent.
+// PapTransport     - The transport used to talk to PAP, "kafka" or "memory" to run without a broker.
+// DeadLetterTopic  - The topic malformed PAP messages are published to, requires the Kafka transport.
+// DeadLetterFile   - The file malformed PAP messages are appended to, takes precedence over the topic.
+// BundleToken      - The bearer token bundle requests must carry, read from BUNDLE_TOKEN_FILE when set and generated at startup when empty.
+// ServeBundles     - Whether the bundle is served over HTTP, disable it when the SDK loads the bundle from a file:// resource.
+// BundleSigningKey - The file holding the key bundles are signed with, a PEM private key or the HS256 secret. Bundles are unsigned when empty.
+// BundleSigningAlg - The algorithm bundles are signed with, RS256, ES256 or HS256.
+// BundleVerifyKey  - The file holding the key the OPA SDK verifies bundles with, derived from the signing key when empty.
+// BundleKeyId      - The id of the bundle signing key.
+// TlsCertFile      - The certificate the HTTP server presents, reloaded when the file changes.
+// TlsKeyFile       - The private key of the HTTP server certificate.
+// TlsMinVersion    - The minimum TLS version the HTTP server accepts, 1.2 or 1.3.
+// AllowPlainHTTP   - Serves the HTTP API without TLS, only when explicitly set to true.
+// TlsClientCaFile  - The CA client certificates are verified with, callers may then authenticate with a certificate.
+// BundleCaFile     - The CA the OPA SDK verifies the HTTPS bundle server certificate with.
+// JwtJwks          - The JWKS bearer tokens are verified with, a file or an http(s) URL. Bearer tokens are refused when empty.
+// JwtIssuer        - The issuer bearer tokens must carry.
+// JwtAudience      - The audience bearer tokens must carry.
+// JwtRolesClaim    - The claim holding the roles of a bearer token caller, nested claims are separated by dots.
+// AllowBasicAuth   - Whether callers may authenticate with the Basic auth credential, disable it once all callers use tokens or certificates.
+// AuthzPolicyFile  - The Rego policy authorizing API callers, the built-in policy is used when empty.
+// CredentialsFile  - The file of API users with bcrypt or argon2id password hashes, replaces Username and Password when set.
+// AuthFailureLimit - The failed Basic auth attempts per minute allowed from each client address, further attempts are refused without checking the password. Unlimited when 0.
+// DecisionRate     - The decisions per second served to all clients together, unlimited when 0.
+// DecisionBurst    - The decisions served at once above DecisionRate, DecisionRate when 0.
+// ClientRate       - The decisions per second served to each client, identified by its caller identity or address, unlimited when 0.
+// ClientBurst      - The decisions served to a client at once above ClientRate, ClientRate when 0.
+// MaxInFlight      - The decisions evaluated at the same time, further requests are refused, unlimited when 0.
+// DecisionTimeout  - The maximum time a decision may take in milliseconds, callers may lower it. Unlimited when 0.
+// MaxDecisionBody  - The maximum size of a decision request body in bytes, larger requests are refused.
 var (
-	LogLevel        string
-	BootstrapServer string
-	Topic           string
-	GroupId         string
-	Username        string
-	Password        string
-	UseSASLForKAFKA string
-	KAFKA_USERNAME  string
-	KAFKA_PASSWORD  string
-	JAASLOGIN       string
+	LogLevel         string
+	BootstrapServer  string
+	Topic            string
+	GroupId          string
+	Username         string
+	Password         Secret
+	UseSASLForKAFKA  string
+	KAFKA_USERNAME   string
+	KAFKA_PASSWORD   Secret
+	JAASLOGIN        string
+	SafeModePolicy   string
+	SafeModeDecision string
+	PersistenceDir   string
+	PdpNameTemplate  string
+	PodName          string
+	PodNamespace     string
+	NodeName         string
+	PdpGroups        []string
+	HeartbeatJitter  int
+	PapTransport     string
+	DeadLetterTopic  string
+	DeadLetterFile   string
+	BundleToken      Secret
+	ServeBundles     bool
+	BundleSigningKey string
+	BundleSigningAlg string
+	BundleVerifyKey  string
+	BundleKeyId      string
+	TlsCertFile      string
+	TlsKeyFile       string
+	TlsMinVersion    string
+	AllowPlainHTTP   bool
+	TlsClientCaFile  string
+	BundleCaFile     string
+	JwtJwks          string
+	JwtIssuer        string
+	JwtAudience      string
+	JwtRolesClaim    string
+	AllowBasicAuth   bool
+	AuthzPolicyFile  string
+	CredentialsFile  string
+	AuthFailureLimit int
+	DecisionRate     int
+	DecisionBurst    int
+	ClientRate       int
+	ClientBurst      int
+	MaxInFlight      int
+	DecisionTimeout  int
+	MaxDecisionBody  int
 )
 
 // Initializes the configuration settings.
@@ -67,16 +148,65 @@ func init() {
 	Topic = getEnv("PAP_TOPIC", "policy-pdp-pap")
 	GroupId = getEnv("GROUPID", "opa-pdp")
 	Username = getEnv("API_USER", "policyadmin")
-	Password = getEnv("API_PASSWORD", "zb!XztG34")
+	Password = getSecret("API_PASSWORD", consts.DefaultApiPassword)
 	UseSASLForKAFKA = getEnv("UseSASLForKAFKA", "false")
-	KAFKA_USERNAME, KAFKA_PASSWORD = getSaslJAASLOGINFromEnv(JAASLOGIN)
-	log.Debugf("Username: %s", KAFKA_USERNAME)
-	log.Debugf("Password: %s", KAFKA_PASSWORD)
+	SafeModePolicy = getEnv("SAFE_MODE_POLICY", "")
+	SafeModeDecision = getEnv("SAFE_MODE_DECISION", "DENY")
+	PersistenceDir = getEnv("PERSISTENCE_DIR", "")
+	PdpNameTemplate = getEnv("PDP_NAME", "")
+	PodName = getEnv("POD_NAME", "")
+	PodNamespace = getEnv("POD_NAMESPACE", "")
+	NodeName = getEnv("NODE_NAME", "")
+	PdpGroups = getEnvAsList("PDP_GROUP", consts.PdpGroup)
+	HeartbeatJitter = getEnvAsInt("HEARTBEAT_JITTER_PERCENT", 0)
+	PapTransport = getEnv("PAP_TRANSPORT", "kafka")
+	DeadLetterTopic = getEnv("DEAD_LETTER_TOPIC", "")
+	DeadLetterFile = getEnv("DEAD_LETTER_FILE", "")
+	BundleToken = getSecret("BUNDLE_TOKEN", "")
+	ServeBundles = getEnv("SERVE_BUNDLES", "true") != "false"
+	BundleSigningKey = getEnv("BUNDLE_SIGNING_KEY", "")
+	BundleSigningAlg = getEnv("BUNDLE_SIGNING_ALG", "RS256")
+	BundleVerifyKey = getEnv("BUNDLE_VERIFICATION_KEY", "")
+	BundleKeyId = getEnv("BUNDLE_KEY_ID", "opa-pdp")
+	TlsCertFile = getEnv("TLS_CERT_FILE", "")
+	TlsKeyFile = getEnv("TLS_KEY_FILE", "")
+	TlsMinVersion = getEnv("TLS_MIN_VERSION", "1.2")
+	AllowPlainHTTP = getEnv("ALLOW_PLAIN_HTTP", "false") == "true"
+	TlsClientCaFile = getEnv("TLS_CLIENT_CA_FILE", "")
+	BundleCaFile = getEnv("BUNDLE_TLS_CA_FILE", "")
+	JwtJwks = getEnv("JWT_JWKS", "")
+	JwtIssuer = getEnv("JWT_ISSUER", "")
+	JwtAudience = getEnv("JWT_AUDIENCE", "")
+	JwtRolesClaim = getEnv("JWT_ROLES_CLAIM", "roles")
+	AllowBasicAuth = getEnv("ALLOW_BASIC_AUTH", "true") != "false"
+	AuthzPolicyFile = getEnv("AUTHZ_POLICY_FILE", "")
+	CredentialsFile = getEnv("API_CREDENTIALS_FILE", "")
+	AuthFailureLimit = getEnvAsInt("AUTH_FAILURE_LIMIT", 10)
+	DecisionRate = getEnvAsInt("DECISION_RATE_LIMIT", 0)
+	DecisionBurst = getEnvAsInt("DECISION_RATE_BURST", 0)
+	ClientRate = getEnvAsInt("CLIENT_RATE_LIMIT", 0)
+	ClientBurst = getEnvAsInt("CLIENT_RATE_BURST", 0)
+	MaxInFlight = getEnvAsInt("MAX_INFLIGHT_DECISIONS", 0)
+	DecisionTimeout = getEnvAsInt("DECISION_TIMEOUT_MS", 5000)
+	MaxDecisionBody = getEnvAsInt("MAX_DECISION_BODY_BYTES", 1048576)
+	var kafkaPassword string
+	KAFKA_USERNAME, kafkaPassword = getSaslJAASLOGINFromEnv(JAASLOGIN)
+	KAFKA_PASSWORD = Secret(kafkaPassword)
 
 	log.Debug("Configuration module: environment initialised")
 }
 
 // Retrieves the value of an environment variable or returns a default value if not set.
+// Returns an error for settings the PDP cannot run with, checked once at startup.
+func Validate() error {
+	switch SafeModeDecision {
+	case "PERMIT", "DENY", "NOTAPPLICABLE":
+	default:
+		return fmt.Errorf("SAFE_MODE_DECISION must be PERMIT, DENY or NOTAPPLICABLE, got %q", SafeModeDecision)
+	}
+	return nil
+}
+
 func getEnv(key string, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -97,6 +227,20 @@ func getEnvAsInt(name string, defaultVal int) int {
 	return defaultVal
 }
 
+// Retrieves a comma separated environment variable as a list or returns the default value if it is not set or empty.
+func getEnvAsList(name string, defaultVal string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(name, defaultVal), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return []string{defaultVal}
+	}
+	return values
+}
+
 // Retrieves the log level from an environment variable or returns a default value if not set.
 func getLogLevel(key string, defaultVal string) log.Level {
 	logLevelStr := getEnv(key, defaultVal)
@@ -110,13 +254,12 @@ func getLogLevel(key string, defaultVal string) log.Level {
 
 func getSaslJAASLOGINFromEnv(JAASLOGIN string) (string, string) {
 	// Retrieve the value of the environment variable
-	decodingConfigBytes := getEnv("JAASLOGIN", "JAASLOGIN")
+	decodingConfigBytes := getSecret("JAASLOGIN", "JAASLOGIN").Value()
 	if decodingConfigBytes == "" {
 		return "", ""
 	}
 
 	decodedConfig := string(decodingConfigBytes)
-	fmt.Println("decodedConfig", decodedConfig)
 
 	// Extract username and password using regex
 	usernamePattern := `username=["'](.+?)["']`
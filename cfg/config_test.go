@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -131,3 +131,30 @@ func TestGetSaslJAASLOGINFromEnv_MissingEnv(t *testing.T) {
 	assert.Empty(t, username, "Expected username to be empty for missing environment variable")
 	assert.Empty(t, password, "Expected password to be empty for missing environment variable")
 }
+
+func TestValidate(t *testing.T) {
+	safeModeDecision := SafeModeDecision
+	defer func() { SafeModeDecision = safeModeDecision }()
+
+	for _, decision := range []string{"PERMIT", "DENY", "NOTAPPLICABLE"} {
+		SafeModeDecision = decision
+		assert.NoError(t, Validate(), decision)
+	}
+	for _, decision := range []string{"MAYBE", "INDETERMINATE", "deny", ""} {
+		SafeModeDecision = decision
+		assert.ErrorContains(t, Validate(), "SAFE_MODE_DECISION", decision)
+	}
+}
+
+func TestGetEnvAsList(t *testing.T) {
+	key := "TEST_LIST_ENV"
+
+	os.Setenv(key, " tenantA, tenantB,,")
+	defer os.Unsetenv(key)
+	assert.Equal(t, []string{"tenantA", "tenantB"}, getEnvAsList(key, "opaGroup"))
+
+	os.Setenv(key, " , ")
+	assert.Equal(t, []string{"opaGroup"}, getEnvAsList(key, "opaGroup"))
+
+	assert.Equal(t, []string{"opaGroup"}, getEnvAsList("NON_EXISTENT_LIST_ENV", "opaGroup"))
+}
@@ -0,0 +1,76 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the text a secret is formatted as
+const redacted = "[REDACTED]"
+
+// Secret is a sensitive configuration value such as a password. It redacts itself when it is
+// formatted, logged or marshalled, so it does not leak by accident, Value returns the secret.
+type Secret string
+
+// Returns the secret value.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// Returns the redacted secret, an empty secret stays empty so a missing value can be told apart.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// Formats the redacted secret with every verb, including %x and %#v which would bypass String.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'q' {
+		fmt.Fprintf(f, "%q", s.String())
+		return
+	}
+	fmt.Fprint(f, s.String())
+}
+
+// Marshals the redacted secret, for JSON and other text based encodings.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Retrieves a secret from the file named by the <key>_FILE environment variable, such as a mounted
+// Kubernetes or Docker secret, else from the environment variable or returns the default value.
+// A secret file that cannot be read yields an empty secret rather than the default value.
+func getSecret(key string, defaultVal string) Secret {
+	if file := os.Getenv(key + "_FILE"); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Errorf("Failed to read %s from %s: %v", key, file, err)
+			return ""
+		}
+		return Secret(strings.TrimRight(string(content), "\r\n"))
+	}
+	return Secret(getEnv(key, defaultVal))
+}
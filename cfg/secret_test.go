@@ -0,0 +1,83 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret_Redacted(t *testing.T) {
+	secret := Secret("zb!XztG34")
+	assert.Equal(t, "zb!XztG34", secret.Value())
+
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%x", "%X", "%q", "%10s", "%d"} {
+		formatted := fmt.Sprintf(format, secret)
+		assert.NotContains(t, formatted, "zb!XztG34", format)
+		assert.Contains(t, formatted, redacted, format)
+	}
+	assert.Equal(t, "password [REDACTED]", fmt.Sprint("password ", secret))
+
+	// secrets nested in structs are redacted too
+	config := struct {
+		User     string
+		Password Secret
+	}{"policyadmin", secret}
+	assert.NotContains(t, fmt.Sprintf("%+v", config), "zb!XztG34")
+	marshalled, err := json.Marshal(config)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"User": "policyadmin", "Password": "[REDACTED]"}`, string(marshalled))
+
+	assert.Equal(t, "", Secret("").String())
+}
+
+func TestGetSecret(t *testing.T) {
+	os.Setenv("TEST_SECRET", "from-env")
+	defer os.Unsetenv("TEST_SECRET")
+	assert.Equal(t, "from-env", getSecret("TEST_SECRET", "default").Value())
+	assert.Equal(t, "default", getSecret("NON_EXISTENT_SECRET", "default").Value())
+
+	// a secret file takes precedence, its trailing newline is not part of the secret
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+	os.Setenv("TEST_SECRET_FILE", secretFile)
+	defer os.Unsetenv("TEST_SECRET_FILE")
+	assert.Equal(t, "from-file", getSecret("TEST_SECRET", "default").Value())
+
+	// an unreadable file does not fall back to the default value
+	os.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	assert.Equal(t, "", getSecret("TEST_SECRET", "default").Value())
+}
+
+func TestGetSaslJAASLOGINFromFile(t *testing.T) {
+	jaasFile := filepath.Join(t.TempDir(), "jaas.conf")
+	assert.NoError(t, os.WriteFile(jaasFile, []byte(`org.apache.kafka.common.security.scram.ScramLoginModule required username="fileUser" password="filePassword";`), 0600))
+	os.Setenv("JAASLOGIN_FILE", jaasFile)
+	defer os.Unsetenv("JAASLOGIN_FILE")
+
+	username, password := getSaslJAASLOGINFromEnv("JAASLOGIN")
+	assert.Equal(t, "fileUser", username)
+	assert.Equal(t, "filePassword", password)
+}
@@ -23,19 +23,29 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	h "policy-opa-pdp/api"
 	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
 	"policy-opa-pdp/pkg/bundleserver"
+	"policy-opa-pdp/pkg/credentials"
+	"policy-opa-pdp/pkg/deadletter"
+	"policy-opa-pdp/pkg/jwtauth"
 	"policy-opa-pdp/pkg/kafkacomm"
 	"policy-opa-pdp/pkg/kafkacomm/handler"
 	"policy-opa-pdp/pkg/kafkacomm/publisher"
 	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/opasdk"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdphealth"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/persistence"
+	"policy-opa-pdp/pkg/servertls"
+	"policy-opa-pdp/pkg/transport"
 	"syscall"
 	"time"
 )
@@ -47,33 +57,50 @@ var (
 
 // Declare function variables for dependency injection makes it more testable
 var (
-	initializeHandlersFunc    = initializeHandlers
-	initializeBundleFunc      = initializeBundle
-	startHTTPServerFunc       = startHTTPServer
-	shutdownHTTPServerFunc    = shutdownHTTPServer
-	waitForServerFunc         = waitForServer
-	initializeOPAFunc         = initializeOPA
-	startKafkaConsAndProdFunc = startKafkaConsAndProd
-	registerPDPFunc           = registerPDP
-	handleMessagesFunc        = handleMessages
-	handleShutdownFunc        = handleShutdown
+	initializeHandlersFunc     = initializeHandlers
+	registerStateObserversFunc = registerStateObservers
+	registerHealthChecksFunc   = registerHealthChecks
+	restorePersistedStateFunc  = restorePersistedState
+	initializeBundleFunc       = initializeBundle
+	startHTTPServerFunc        = startHTTPServer
+	shutdownHTTPServerFunc     = shutdownHTTPServer
+	waitForServerFunc          = waitForServer
+	initializeOPAFunc          = initializeOPA
+	startKafkaConsAndProdFunc  = startKafkaConsAndProd
+	startTransportFunc         = startTransport
+	configureDeadLetterFunc    = configureDeadLetter
+	registerPDPFunc            = registerPDP
+	startHeartbeatFunc         = startHeartbeat
+	handleMessagesFunc         = handleMessages
+	handleShutdownFunc         = handleShutdown
 )
 
 // main function
 func main() {
 	log.Debugf("Starting OPA PDP Service")
+	if err := cfg.Validate(); err != nil {
+		log.Errorf("Invalid configuration: %v", err)
+		return
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize Handlers and Build Bundle
 	initializeHandlersFunc()
-	if err := initializeBundleFunc(exec.Command); err != nil {
+	restorePersistedStateFunc()
+	registerStateObserversFunc()
+	registerHealthChecksFunc()
+	if err := initializeBundleFunc(); err != nil {
 		log.Warnf("Failed to initialize bundle: %s", err)
 	}
 
 	// Start HTTP Server
-	server := startHTTPServerFunc()
+	server, err := startHTTPServerFunc()
+	if err != nil {
+		log.Errorf("HTTP server could not be started: %v", err)
+		return
+	}
 	defer shutdownHTTPServerFunc(server)
 
 	// Wait for server to be up
@@ -87,34 +114,40 @@ func main() {
 		return
 	}
 
-	// Start Kafka Consumer and producer
-	kc, producer, err := startKafkaConsAndProdFunc()
-	if err != nil || kc == nil {
-		log.Warnf("Kafka consumer initialization failed: %v", err)
+	// Start the transport to PAP, Kafka consumer and producer unless configured otherwise
+	pdpTransport, err := startTransportFunc()
+	if err != nil {
+		log.Warnf("PAP transport initialization failed: %v", err)
+		return
 	}
-	defer producer.Close()
 
-	sender := &publisher.RealPdpStatusSender{}
+	configureDeadLetterFunc(pdpTransport)
+
+	sender := &publisher.RealPdpStatusSender{Transport: pdpTransport}
 	// pdp registration
 	isRegistered := registerPDPFunc(sender)
 	if !isRegistered {
+		pdpTransport.Close()
 		return
 	}
 
+	// heartbeats run until shutdown, a restored PDP keeps its persisted interval
+	startHeartbeatFunc(ctx, sender)
+
 	// start pdp message handler in a seperate routine
-	handleMessagesFunc(ctx, kc, sender)
+	handleMessagesFunc(ctx, pdpTransport, sender)
 
 	// Handle OS Interrupts and Graceful Shutdown
 	interruptChannel := make(chan os.Signal, 1)
 	signal.Notify(interruptChannel, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
-	handleShutdownFunc(kc, interruptChannel, cancel)
+	handleShutdownFunc(pdpTransport, interruptChannel, cancel)
 }
 
-// starts pdpMessage Handler in a seperate routine which handles incoming messages on Kfka topic
-func handleMessages(ctx context.Context, kc *kafkacomm.KafkaConsumer, sender *publisher.RealPdpStatusSender) {
+// starts pdpMessage Handler in a seperate routine which handles incoming messages from PAP
+func handleMessages(ctx context.Context, pdpTransport transport.Transport, sender *publisher.RealPdpStatusSender) {
 
 	go func() {
-		err := handler.PdpMessageHandler(ctx, kc, topic, sender)
+		err := handler.PdpMessageHandler(ctx, pdpTransport, sender)
 		if err != nil {
 			log.Warnf("Erro in PdpUpdate Message Handler: %v", err)
 		}
@@ -131,24 +164,93 @@ func registerPDP(sender publisher.PdpStatusSender) bool {
 	return true
 }
 
+// starts the heartbeat sent to PAP, PDP_UPDATE messages change its interval
+func startHeartbeat(ctx context.Context, sender publisher.PdpStatusSender) {
+	heartbeater := publisher.NewHeartbeater(sender, cfg.HeartbeatJitter)
+	publisher.SetDefaultHeartbeater(heartbeater)
+	heartbeater.UpdateInterval(pdpattributes.GetPdpHeartbeatInterval())
+	heartbeater.Start(ctx)
+}
+
 // Register Handlers
 func initializeHandlers() {
 	h.RegisterHandlers()
 }
 
+// reacts to PDP state changes requested by PAP, the returned function removes the observers
+func registerStateObservers() (unregister func()) {
+	unsubscribes := []func(){pdpstate.Subscribe(func(oldState, newState model.PdpState) {
+		if newState == model.Terminated {
+			log.Infof("PDP terminated by PAP, stopping heartbeat")
+			publisher.StopHeartbeat()
+		}
+	})}
+	if persistence.Enabled() {
+		unsubscribes = append(unsubscribes, pdpstate.Subscribe(func(oldState, newState model.PdpState) {
+			if err := persistence.SaveState(newState); err != nil {
+				log.Warnf("Failed to persist PDP state: %v", err)
+			}
+		}))
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+}
+
+// registers the health checks of the PDP components reported to PAP
+func registerHealthChecks() {
+	pdphealth.Register("opa", opasdk.CheckHealth)
+	pdphealth.Register("bundle", bundleserver.CheckBundle)
+	pdphealth.Register("bundle-verification", bundleserver.CheckVerification)
+}
+
+// restores the PDP from the persistence directory, if one is configured
+func restorePersistedState() {
+	if err := persistence.Restore(); err != nil {
+		log.Warnf("Failed to restore persisted PDP state: %v", err)
+	}
+}
+
 // build bundle tar file
-func initializeBundle(execCmd func(string, ...string) *exec.Cmd) error {
-	return bundleserver.BuildBundle(execCmd)
+func initializeBundle() error {
+	return bundleserver.BuildBundle()
 }
 
-func startHTTPServer() *http.Server {
+// serves HTTPS unless plain HTTP is explicitly allowed
+func startHTTPServer() (*http.Server, error) {
+	if err := credentials.Initialize(); err != nil {
+		return nil, err
+	}
+	if err := jwtauth.Initialize(); err != nil {
+		return nil, err
+	}
+	if err := h.InitializeAuthorization(); err != nil {
+		return nil, err
+	}
 	server := &http.Server{Addr: consts.ServerPort}
+	if cfg.AllowPlainHTTP {
+		log.Warnf("ALLOW_PLAIN_HTTP is set, the PDP API is served without TLS")
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Errorf("Server error: %s", err)
+			}
+		}()
+		return server, nil
+	}
+
+	tlsConfig, err := servertls.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = tlsConfig
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			log.Errorf("Server error: %s", err)
 		}
 	}()
-	return server
+	return server, nil
 }
 
 func shutdownHTTPServer(server *http.Server) {
@@ -166,13 +268,10 @@ func waitForServer() {
 	time.Sleep(time.Duration(consts.SERVER_WAIT_UP_TIME) * time.Second)
 }
 
+// creates the OPA instance, which keeps polling the bundle until the PDP stops
 func initializeOPA() error {
-	opa, err := opasdk.GetOPASingletonInstance()
-	if err != nil {
-		return err
-	}
-	defer opa.Stop(context.Background())
-	return nil
+	_, err := opasdk.GetOPASingletonInstance()
+	return err
 }
 
 func startKafkaConsAndProd() (*kafkacomm.KafkaConsumer, *kafkacomm.KafkaProducer, error) {
@@ -189,7 +288,48 @@ func startKafkaConsAndProd() (*kafkacomm.KafkaConsumer, *kafkacomm.KafkaProducer
 	return kc, producer, nil
 }
 
-func handleShutdown(kc *kafkacomm.KafkaConsumer, interruptChannel chan os.Signal, cancel context.CancelFunc) {
+// creates the transport to PAP selected by PAP_TRANSPORT
+func startTransport() (transport.Transport, error) {
+	if cfg.PapTransport == "memory" {
+		log.Warnf("Using the in-memory PAP transport, no messages are exchanged with a broker")
+		memoryTransport := transport.NewInMemory(consts.InMemoryTransportBuffer)
+		go drainInMemorySent(memoryTransport)
+		return memoryTransport, nil
+	}
+	kc, producer, err := startKafkaConsAndProdFunc()
+	if err != nil {
+		return nil, err
+	}
+	if kc == nil {
+		return nil, fmt.Errorf("Kafka consumer is nil")
+	}
+	return kafkacomm.NewKafkaTransport(kc, producer, topic), nil
+}
+
+// drains the messages sent on the in-memory transport, nobody else reads them when running standalone
+func drainInMemorySent(memoryTransport *transport.InMemory) {
+	for range memoryTransport.Sent() {
+	}
+}
+
+// malformed PAP messages are written to DEAD_LETTER_FILE or, with Kafka, to DEAD_LETTER_TOPIC
+func configureDeadLetter(pdpTransport transport.Transport) {
+	switch {
+	case cfg.DeadLetterFile != "":
+		log.Infof("Writing malformed PAP messages to %s", cfg.DeadLetterFile)
+		deadletter.SetSink(deadletter.NewFileSink(cfg.DeadLetterFile))
+	case cfg.DeadLetterTopic != "":
+		sender, ok := pdpTransport.(deadletter.TopicSender)
+		if !ok {
+			log.Warnf("Dead-letter topic %s needs the Kafka transport, malformed PAP messages are only counted", cfg.DeadLetterTopic)
+			return
+		}
+		log.Infof("Publishing malformed PAP messages to %s", cfg.DeadLetterTopic)
+		deadletter.SetSink(deadletter.NewTopicSink(sender, cfg.DeadLetterTopic))
+	}
+}
+
+func handleShutdown(pdpTransport transport.Transport, interruptChannel chan os.Signal, cancel context.CancelFunc) {
 
 myLoop:
 	for {
@@ -203,24 +343,19 @@ myLoop:
 	log.Debugf("Loop Exited and shutdown started")
 	signal.Stop(interruptChannel)
 
-	if kc == nil {
-		log.Debugf("kc is nil so skipping")
+	if pdpTransport == nil {
+		log.Debugf("transport is nil so skipping")
 		return
 	}
 
-	if err := kc.Consumer.Unsubscribe(); err != nil {
-		log.Warnf("Failed to unsubscribe consumer: %v", err)
-	} else {
-		log.Debugf("Consumer Unsubscribed....")
-	}
-	if err := kc.Consumer.Close(); err != nil {
-		log.Debug("Failed to close consumer......")
+	if err := pdpTransport.Close(); err != nil {
+		log.Warnf("Failed to close transport: %v", err)
 	} else {
-		log.Debugf("Consumer closed....")
+		log.Debugf("Transport closed....")
 	}
 
 	handler.SetShutdownFlag()
-	publisher.StopTicker()
+	publisher.StopHeartbeat()
 
 	time.Sleep(time.Duration(consts.SHUTDOWN_WAIT_TIME) * time.Second)
 }
@@ -23,14 +23,19 @@ import (
 	"context"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/deadletter"
 	"policy-opa-pdp/pkg/kafkacomm"
 	"policy-opa-pdp/pkg/kafkacomm/mocks"
 	"policy-opa-pdp/pkg/kafkacomm/publisher"
 	"policy-opa-pdp/pkg/kafkacomm/handler"
 	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdphealth"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/transport"
 	"fmt"
 	"testing"
 	"time"
@@ -106,7 +111,7 @@ func TestHandleShutdown(t *testing.T) {
 	}()
 	done := make(chan bool)
 	go func() {
-		handleShutdown(mockKafkaConsumer, interruptChannel, cancel)
+		handleShutdown(kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, topic), interruptChannel, cancel)
 		done <- true
 	}()
 
@@ -128,8 +133,11 @@ func TestMainFunction(t *testing.T) {
 		log.Debug("Handlers initialized")
 	}
 
+	registerStateObserversFunc = func() func() { return func() {} }
+	registerHealthChecksFunc = func() {}
+
 	// Mock initializeBundle
-	initializeBundleFunc = func(cmdFn func(string, ...string) *exec.Cmd) error {
+	initializeBundleFunc = func() error {
 		return nil // no error expected
 	}
 
@@ -137,8 +145,8 @@ func TestMainFunction(t *testing.T) {
 	testServer := &http.Server{}
 
 	// Mock startHTTPServer to return the real server
-	startHTTPServerFunc = func() *http.Server {
-		return testServer
+	startHTTPServerFunc = func() (*http.Server, error) {
+		return testServer, nil
 	}
 
 	// Mock shutdownHTTPServer to call Shutdown on the real server
@@ -168,13 +176,13 @@ func TestMainFunction(t *testing.T) {
 		return false // Simulate successful registration
 	}
 
-	handleMessagesFunc = func(ctx context.Context, kc *kafkacomm.KafkaConsumer, sender *publisher.RealPdpStatusSender) {
+	handleMessagesFunc = func(ctx context.Context, pdpTransport transport.Transport, sender *publisher.RealPdpStatusSender) {
 		return
 	}
 
 	// Mock handleShutdown
 	interruptChannel := make(chan os.Signal, 1)
-	handleShutdownFunc = func(kc *kafkacomm.KafkaConsumer, interruptChan chan os.Signal, cancel context.CancelFunc) {
+	handleShutdownFunc = func(pdpTransport transport.Transport, interruptChan chan os.Signal, cancel context.CancelFunc) {
 		interruptChannel <- os.Interrupt
 		cancel()
 	}
@@ -204,20 +212,102 @@ func TestMainFunction(t *testing.T) {
 
 // Test to validate that the OPA bundle initialization process works as expected.
 func TestInitializeBundle(t *testing.T) {
-	mockExecCmd := func(name string, arg ...string) *exec.Cmd {
-		return exec.Command("echo")
-	}
-	err := initializeBundle(mockExecCmd)
+	policies, data := consts.Policies, consts.Data
+	bundleFile := consts.BundleTarGzFile
+	defer func() { consts.Policies, consts.Data, consts.BundleTarGzFile = policies, data, bundleFile }()
+	consts.Policies, consts.Data = t.TempDir(), t.TempDir()
+	consts.BundleTarGzFile = filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+	err := initializeBundle()
 	assert.NoError(t, err, "Expected no error from initializeBundle")
 }
 
+// Test to verify that terminating the PDP stops the heartbeat.
+func TestRegisterStateObservers(t *testing.T) {
+	t.Cleanup(registerStateObservers())
+	t.Cleanup(pdpstate.Reset)
+
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(nil)
+	heartbeater := publisher.NewHeartbeater(mockSender, 0)
+	heartbeater.UpdateInterval(60000)
+	heartbeater.Start(context.Background())
+	publisher.SetDefaultHeartbeater(heartbeater)
+	defer publisher.SetDefaultHeartbeater(nil)
+
+	assert.NoError(t, pdpstate.TransitionTo(model.Terminated))
+	assert.Equal(t, model.Terminated, pdpstate.GetState())
+	assert.False(t, heartbeater.IsRunning(), "Expected the heartbeat to stop when terminated")
+}
+
+// Test to verify that the heartbeat runs until the context is cancelled.
+func TestStartHeartbeat(t *testing.T) {
+	sent := make(chan struct{}, 1)
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Run(func(mock.Arguments) {
+		select {
+		case sent <- struct{}{}:
+		default:
+		}
+	}).Return(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer publisher.SetDefaultHeartbeater(nil)
+
+	startHeartbeat(ctx, mockSender)
+	publisher.UpdateHeartbeatInterval(10)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Error("Expected a heartbeat to be sent")
+	}
+}
+
+// Test to verify that the OPA and bundle health checks are registered.
+func TestRegisterHealthChecks(t *testing.T) {
+	defer pdphealth.Reset()
+	registerHealthChecks()
+
+	consts.BundleTarGzFile = "nonexistent-file.tar.gz"
+	report := pdphealth.Evaluate()
+	assert.Equal(t, model.NotHealthy, report.Status)
+	assert.Contains(t, report.Failures, "bundle")
+	assert.NotContains(t, report.Failures, "bundle-verification")
+}
+
 // Test to verify that the HTTP server starts successfully.
 func TestStartHTTPServer(t *testing.T) {
-	server := startHTTPServer()
+	cfg.AllowPlainHTTP = true
+	defer func() { cfg.AllowPlainHTTP = false }()
+
+	server, err := startHTTPServer()
 	time.Sleep(1 * time.Second)
+	assert.NoError(t, err)
 	assert.NotNil(t, server, "Server should be initialized")
 }
 
+// Test to verify that the HTTP server is not started without TLS unless plain HTTP is allowed.
+func TestStartHTTPServer_RequiresTLS(t *testing.T) {
+	cfg.AllowPlainHTTP = false
+	cfg.TlsCertFile, cfg.TlsKeyFile = "", ""
+
+	server, err := startHTTPServer()
+	assert.Error(t, err)
+	assert.Nil(t, server)
+}
+
+// Test to verify that the HTTP server is not started with an incomplete bearer token configuration.
+func TestStartHTTPServer_InvalidJwtConfig(t *testing.T) {
+	cfg.AllowPlainHTTP = true
+	defer func() { cfg.AllowPlainHTTP = false }()
+	cfg.JwtJwks, cfg.JwtIssuer = "/app/config/jwks.json", ""
+	defer func() { cfg.JwtJwks = "" }()
+
+	server, err := startHTTPServer()
+	assert.ErrorContains(t, err, "JWT_ISSUER")
+	assert.Nil(t, server)
+}
+
 // Test to validate the initialization of the OPA (Open Policy Agent) instance.
 func TestInitializeOPA(t *testing.T) {
 	err := initializeOPA()
@@ -266,7 +356,10 @@ func TestRegisterPDP_Failure(t *testing.T) {
 
 // Test to verify that the HTTP Server starts successfully and can be shut down gracefully.
 func TestStartAndShutDownHTTPServer(t *testing.T) {
- testServer := startHTTPServer()
+ cfg.AllowPlainHTTP = true
+ defer func() { cfg.AllowPlainHTTP = false }()
+ testServer, err := startHTTPServer()
+ assert.NoError(t, err)
 
  time.Sleep(1 * time.Second)
 
@@ -321,7 +414,7 @@ func TestHandleShutdown_ErrorScenario(t *testing.T) {
  
     done := make(chan bool)
     go func() {
-        handleShutdown(mockKafkaConsumer, interruptChannel, cancel)
+        handleShutdown(kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, topic), interruptChannel, cancel)
         done <- true
     }()
  
@@ -407,13 +500,25 @@ func TestHandleMessages(t *testing.T) {
 
 
     ctx := context.Background()
-     handleMessages(ctx, mockConsumer, mockSender)
+     handleMessages(ctx, kafkacomm.NewKafkaTransport(mockConsumer, nil, topic), mockSender)
+
+}
+
+// Test that an invalid configuration stops the main function before anything is started.
+func TestMain_InvalidConfiguration(t *testing.T) {
+	safeModeDecision, initializeHandlers := cfg.SafeModeDecision, initializeHandlersFunc
+	defer func() { cfg.SafeModeDecision, initializeHandlersFunc = safeModeDecision, initializeHandlers }()
+	cfg.SafeModeDecision = "MAYBE"
+	initialized := false
+	initializeHandlersFunc = func() { initialized = true }
 
+	main()
+	assert.False(t, initialized, "Expected the PDP not to start with an invalid SAFE_MODE_DECISION")
 }
 
 // Test to simulate a failure during OPA bundle initialization in the main function.
 func TestMain_InitializeBundleFailure(t *testing.T) {
-    initializeBundleFunc = func(cmdFn func(string, ...string) *exec.Cmd) error {
+    initializeBundleFunc = func() error {
         return errors.New("bundle initialization error") // Simulate error
     }
 
@@ -452,7 +557,7 @@ func TestMain_KafkaInitializationFailure(t *testing.T) {
 
 // Test to validate the main function's handling of shutdown signals.
 func TestMain_HandleShutdownWithSignals(t *testing.T) {
-    handleShutdownFunc = func(kc *kafkacomm.KafkaConsumer, interruptChan chan os.Signal, cancel context.CancelFunc) {
+    handleShutdownFunc = func(pdpTransport transport.Transport, interruptChan chan os.Signal, cancel context.CancelFunc) {
         go func() {
             interruptChan <- os.Interrupt // Simulate SIGTERM
         }()
@@ -609,14 +714,14 @@ func TestHandleMessages_ErrorInPdpMessageHandler(t *testing.T) {
  }
 
  // Patch the PdpMessageHandler to return an error
- patch := monkey.Patch(handler.PdpMessageHandler, func(ctx context.Context, kc *kafkacomm.KafkaConsumer, topic string, p publisher.PdpStatusSender) error {
+ patch := monkey.Patch(handler.PdpMessageHandler, func(ctx context.Context, pdpTransport transport.Transport, p publisher.PdpStatusSender) error {
   return errors.New("simulated error in PdpMessageHandler")
  })
  defer patch.Unpatch()
 
  // Call handleMessages
  ctx := context.Background()
- handleMessages(ctx, mockConsumer, mockSender)
+ handleMessages(ctx, kafkacomm.NewKafkaTransport(mockConsumer, nil, topic), mockSender)
 
  // No crash means the error branch was executed.
  assert.True(t, true, "handleMessages executed successfully")
@@ -638,3 +743,78 @@ func TestShutdownHTTPServer_Errors(t *testing.T) {
     assert.True(t, true, "Shutdown error")
 }
 
+func TestStartTransport_Memory(t *testing.T) {
+	cfg.PapTransport = "memory"
+	defer func() { cfg.PapTransport = "kafka" }()
+
+	pdpTransport, err := startTransport()
+	assert.NoError(t, err)
+	assert.IsType(t, &transport.InMemory{}, pdpTransport)
+
+	sender := &publisher.RealPdpStatusSender{Transport: pdpTransport}
+	for i := 0; i <= consts.InMemoryTransportBuffer; i++ {
+		assert.NoError(t, sender.SendPdpStatus(model.PdpStatus{}), "Sent messages should be drained when running standalone")
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, pdpTransport.Close())
+}
+
+func TestStartTransport_Kafka(t *testing.T) {
+	originalStartKafkaConsAndProd := startKafkaConsAndProdFunc
+	defer func() { startKafkaConsAndProdFunc = originalStartKafkaConsAndProd }()
+
+	startKafkaConsAndProdFunc = func() (*kafkacomm.KafkaConsumer, *kafkacomm.KafkaProducer, error) {
+		return &kafkacomm.KafkaConsumer{}, &kafkacomm.KafkaProducer{}, nil
+	}
+	pdpTransport, err := startTransport()
+	assert.NoError(t, err)
+	assert.IsType(t, &kafkacomm.KafkaTransport{}, pdpTransport)
+
+	startKafkaConsAndProdFunc = func() (*kafkacomm.KafkaConsumer, *kafkacomm.KafkaProducer, error) {
+		return nil, nil, errors.New("kafka initialization failed")
+	}
+	pdpTransport, err = startTransport()
+	assert.EqualError(t, err, "kafka initialization failed")
+	assert.Nil(t, pdpTransport)
+}
+
+type recordingTopicSender struct {
+	topic string
+}
+
+func (r *recordingTopicSender) SendTo(topic string, message []byte) error {
+	r.topic = topic
+	return nil
+}
+
+func (r *recordingTopicSender) Receive(ctx context.Context) (*transport.Message, error) { return nil, nil }
+func (r *recordingTopicSender) Send(message []byte) error                              { return nil }
+func (r *recordingTopicSender) Close() error                                            { return nil }
+
+func TestConfigureDeadLetter(t *testing.T) {
+	defer deadletter.SetSink(nil)
+	defer func() { cfg.DeadLetterFile, cfg.DeadLetterTopic = "", "" }()
+
+	// a topic needs a transport able to send to other topics
+	cfg.DeadLetterTopic = "policy-pdp-pap-dlq"
+	sender := &recordingTopicSender{}
+	configureDeadLetter(sender)
+	deadletter.Publish([]byte("payload"), nil, "reason")
+	assert.Equal(t, "policy-pdp-pap-dlq", sender.topic)
+
+	// the file takes precedence over the topic
+	cfg.DeadLetterFile = filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	configureDeadLetter(sender)
+	deadletter.Publish([]byte("payload"), nil, "reason")
+	_, err := os.Stat(cfg.DeadLetterFile)
+	assert.NoError(t, err)
+
+	// the in-memory transport cannot publish to a topic, letters are only counted
+	deadletter.SetSink(nil)
+	cfg.DeadLetterFile = ""
+	memoryTransport := transport.NewInMemory(1)
+	defer memoryTransport.Close()
+	configureDeadLetter(memoryTransport)
+	deadletter.Publish([]byte("payload"), nil, "reason")
+	assert.Empty(t, memoryTransport.Sent())
+}
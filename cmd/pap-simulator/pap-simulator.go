@@ -0,0 +1,163 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The pap-simulator command plays PAP towards a PDP and runs the lifecycle scenario of the
+// papsim package: registration, deployment, activation, health and topic checks, heartbeat
+// timing, undeployment and deactivation. With -transport=memory it runs against an in-process
+// PDP without a broker, with -transport=kafka against a PDP listening on the PAP topic.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/kafkacomm"
+	"policy-opa-pdp/pkg/kafkacomm/handler"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/papsim"
+	"policy-opa-pdp/pkg/transport"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// options of a simulator run
+type options struct {
+	transport         string
+	bootstrapServer   string
+	topic             string
+	groupId           string
+	pdpName           string
+	pdpGroup          string
+	subgroup          string
+	policies          string
+	heartbeatInterval time.Duration
+	tolerancePercent  int
+	timeout           time.Duration
+}
+
+// Declare function variables for dependency injection makes it more testable
+var (
+	startKafkaTransportFunc = startKafkaTransport
+	startInMemoryPdpFunc    = startInMemoryPdp
+)
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.transport, "transport", "kafka", `the transport to the PDP, "kafka" or "memory" for an in-process PDP`)
+	flag.StringVar(&opts.bootstrapServer, "kafka", cfg.BootstrapServer, "the Kafka bootstrap server address")
+	flag.StringVar(&opts.topic, "topic", cfg.Topic, "the PDP-PAP topic")
+	flag.StringVar(&opts.groupId, "group-id", "pap-simulator", "the Kafka consumer group of the simulator, must differ from the PDP's")
+	flag.StringVar(&opts.pdpName, "pdp-name", "", "the name of a running PDP, the simulator waits for a registration when empty")
+	flag.StringVar(&opts.pdpGroup, "pdp-group", consts.PdpGroup, "the group of the PDP given by -pdp-name")
+	flag.StringVar(&opts.subgroup, "subgroup", consts.PdpType, "the subgroup the PDP is assigned to")
+	flag.StringVar(&opts.policies, "policies", "", `comma separated policies to deploy as "name:version"`)
+	flag.DurationVar(&opts.heartbeatInterval, "heartbeat", 2*time.Second, "the heartbeat interval sent to the PDP")
+	flag.IntVar(&opts.tolerancePercent, "tolerance", 50, "the allowed heartbeat timing deviation, in percent of the interval")
+	flag.DurationVar(&opts.timeout, "timeout", 30*time.Second, "the time to wait for each PDP_STATUS")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := run(ctx, opts); err != nil {
+		log.Errorf("PAP simulator: %v", err)
+		os.Exit(1)
+	}
+}
+
+// runs the lifecycle scenario with the given options
+func run(ctx context.Context, opts options) error {
+	var papTransport transport.Transport
+	switch opts.transport {
+	case "memory":
+		papTransport = startInMemoryPdpFunc(ctx).Peer()
+	case "kafka":
+		kafkaTransport, err := startKafkaTransportFunc(opts)
+		if err != nil {
+			return err
+		}
+		papTransport = kafkaTransport
+	default:
+		return fmt.Errorf("unknown transport %q", opts.transport)
+	}
+	defer papTransport.Close()
+
+	sim := papsim.New(papTransport, opts.subgroup)
+	sim.Timeout = opts.timeout
+	sim.Start(ctx)
+	defer sim.Stop()
+
+	scenario := papsim.Lifecycle(opts.heartbeatInterval, opts.tolerancePercent, parsePolicies(opts.policies)...)
+	if opts.pdpName != "" {
+		// the PDP registered before the simulator started, skip waiting for its registration
+		sim.SetTarget(papsim.Target{Name: opts.pdpName, Group: opts.pdpGroup, Subgroup: opts.subgroup})
+		scenario.Steps = scenario.Steps[1:]
+	}
+	return scenario.Run(ctx, sim)
+}
+
+// parses comma separated "name:version" policy identifiers
+func parsePolicies(policies string) []model.ToscaConceptIdentifier {
+	var ids []model.ToscaConceptIdentifier
+	for _, policy := range strings.Split(policies, ",") {
+		if policy = strings.TrimSpace(policy); policy != "" {
+			ids = append(ids, *model.ParseToscaConceptIdentifier(policy))
+		}
+	}
+	return ids
+}
+
+// creates a Kafka transport on the PAP topic with the simulator's own consumer group
+func startKafkaTransport(opts options) (transport.Transport, error) {
+	cfg.BootstrapServer = opts.bootstrapServer
+	cfg.Topic = opts.topic
+	cfg.GroupId = opts.groupId
+	kc, err := kafkacomm.NewKafkaConsumer()
+	if err != nil {
+		return nil, err
+	}
+	producer, err := kafkacomm.GetKafkaProducer(opts.bootstrapServer, opts.topic)
+	if err != nil {
+		return nil, err
+	}
+	return kafkacomm.NewKafkaTransport(kc, producer, opts.topic), nil
+}
+
+// runs the PDP message handler, heartbeats and registration on an in-memory transport
+func startInMemoryPdp(ctx context.Context) *transport.InMemory {
+	pdpTransport := transport.NewInMemory(consts.InMemoryTransportBuffer)
+	sender := &publisher.RealPdpStatusSender{Transport: pdpTransport}
+	heartbeater := publisher.NewHeartbeater(sender, cfg.HeartbeatJitter)
+	publisher.SetDefaultHeartbeater(heartbeater)
+	heartbeater.Start(ctx)
+	go func() {
+		if err := handler.PdpMessageHandler(ctx, pdpTransport, sender); err != nil {
+			log.Warnf("Error in PDP Message Handler: %v", err)
+		}
+	}()
+	if err := publisher.SendPdpPapRegistration(sender); err != nil {
+		log.Warnf("Failed to register the in-process PDP: %v", err)
+	}
+	return pdpTransport
+}
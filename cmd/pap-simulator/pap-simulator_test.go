@@ -0,0 +1,102 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/transport"
+	"testing"
+	"time"
+)
+
+func memoryOptions() options {
+	return options{
+		transport:         "memory",
+		subgroup:          "opa",
+		policies:          "role:1.0.0, abac:1.0.1",
+		heartbeatInterval: 200 * time.Millisecond,
+		tolerancePercent:  50,
+		timeout:           2 * time.Second,
+	}
+}
+
+func TestRun_InMemory(t *testing.T) {
+	defer publisher.StopHeartbeat()
+	assert.NoError(t, pdpstate.TransitionTo(model.Passive))
+
+	err := run(context.Background(), memoryOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, model.Passive, pdpstate.GetState())
+}
+
+func TestRun_InMemoryWithPdpName(t *testing.T) {
+	defer publisher.StopHeartbeat()
+	assert.NoError(t, pdpstate.TransitionTo(model.Passive))
+	opts := memoryOptions()
+	opts.pdpName = pdpattributes.PdpName
+	opts.pdpGroup = pdpattributes.GetPdpGroup()
+
+	err := run(context.Background(), opts)
+	assert.NoError(t, err)
+}
+
+func TestRun_UnknownTransport(t *testing.T) {
+	opts := memoryOptions()
+	opts.transport = "pigeon"
+	assert.EqualError(t, run(context.Background(), opts), `unknown transport "pigeon"`)
+}
+
+func TestRun_KafkaFailure(t *testing.T) {
+	original := startKafkaTransportFunc
+	defer func() { startKafkaTransportFunc = original }()
+	startKafkaTransportFunc = func(opts options) (transport.Transport, error) {
+		return nil, errors.New("kafka initialization failed")
+	}
+
+	opts := memoryOptions()
+	opts.transport = "kafka"
+	assert.EqualError(t, run(context.Background(), opts), "kafka initialization failed")
+}
+
+func TestRun_ScenarioFailure(t *testing.T) {
+	original := startInMemoryPdpFunc
+	defer func() { startInMemoryPdpFunc = original }()
+	// a PDP that never registers
+	startInMemoryPdpFunc = func(ctx context.Context) *transport.InMemory {
+		return transport.NewInMemory(1)
+	}
+
+	opts := memoryOptions()
+	opts.timeout = 50 * time.Millisecond
+	err := run(context.Background(), opts)
+	assert.ErrorContains(t, err, `failed at step "expect registration"`)
+}
+
+func TestParsePolicies(t *testing.T) {
+	assert.Equal(t, []model.ToscaConceptIdentifier{{Name: "role", Version: "1.0.0"}, {Name: "abac", Version: ""}},
+		parsePolicies(" role:1.0.0,,abac "))
+	assert.Empty(t, parsePolicies(""))
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -27,19 +27,17 @@ package consts
 //	LogMaxSize          - The maximum size of the log file in megabytes.
 //	LogMaxBackups       - The maximum number of backup log files to retain.
 //	OpasdkConfigPath    - The file path for the OPA SDK configuration.
-//	Opa                 - The file path for the OPA binary.
-//	BuildBundle         - The command to build the bundle.
 //	Policies            - The directory path for policies.
 //	Data                - The directory path for policy data.
-//	Output              - The output flag for bundle commands.
 //	BundleTarGz         - The name of the bundle tar.gz file.
 //	BundleTarGzFile     - The file path for the bundle tar.gz file.
-//	PdpGroup            - The default PDP group.
+//	BundleRevisionHeader - The response header carrying the revision of the served bundle
+//	BundleServerPath    - The path the bundle server is registered on
+//	PdpGroup            - The default PDP group, used when PDP_GROUP is not configured.
 //	PdpType             - The type of PDP.
 //	ServerPort          - The port on which the server listens.
 //	SERVER_WAIT_UP_TIME - The time to wait for the server to be up, in seconds.
 //	SHUTDOWN_WAIT_TIME  - The time to wait for the server to shut down, in seconds.
-//	V1_COMPATIBLE       - The flag for v1 compatibility.
 //	LatestVersion       - The Version set in response for decision
 //	MinorVersion        - The Minor version set in response header for decision
 //	PatchVersion        - The Patch Version set in response header for decison
@@ -47,30 +45,60 @@ package consts
 //	HealtCheckStatus    - The bool flag for Healthy field in HealtCheck response
 //	OkCode              - The Code for HealthCheck response
 //	HealthCheckMessage  - The Healtcheck Message
+//	TestDecisionHeader  - The response header marking decisions made while the PDP is in TEST state
+//	PassiveRetryAfterSeconds - The Retry-After value returned for decisions while the PDP is PASSIVE
+//	PersistenceSnapshotFile - The name of the file holding the persisted PDP snapshot
+//	ProcessedRequestCacheSize - The number of processed PAP request ids remembered to detect duplicates
+//	InMemoryTransportBuffer - The number of messages buffered in each direction by the in-memory PAP transport
+//	MessageQueueSize    - The number of PAP messages of one type queued before polling waits for processing
+//	JwksCacheSeconds    - The time the JWKS bearer tokens are verified with is cached, in seconds
+//	JwksMinRefreshSeconds - The minimum time between two JWKS fetches triggered by an unknown key id, in seconds
+//	JwksFetchTimeoutSeconds - The timeout of a JWKS fetch from a URL, in seconds
+//	DefaultApiPassword  - The built-in Basic auth password, used when API_PASSWORD is not set
+//	CredentialsCacheSize - The number of verified API credentials remembered to skip hashing them again
+//	ApiUserRole         - The role the API_USER Basic auth credential holds
+//	InFlightRetryAfterSeconds - The Retry-After value returned for decisions refused while the in-flight limit is reached
+//	ClientLimiterIdleSeconds - The time after which the rate limit state of an idle client is dropped, in seconds
+//	DecisionTimeoutHeader - The request header a caller lowers the decision timeout with, in milliseconds
+//	OpaReadyTimeoutSeconds - The time the startup waits for the OPA instance to activate its bundles, in seconds
 var (
 	LogFilePath      = "/var/logs/logs.log"
 	LogMaxSize       = 10
 	LogMaxBackups    = 3
 	OpasdkConfigPath = "/app/config/config.json"
-	Opa              = "/app/opa"
-	BuildBundle      = "build"
 	Policies         = "/opt/policies"
 	Data             = "/opt/data"
-	Output           = "-o"
 	BundleTarGz      = "bundle.tar.gz"
 	BundleTarGzFile  = "/app/bundles/bundle.tar.gz"
 	PdpGroup         = "opaGroup"
 	//This is a workaround as currently opa-pdp is not defined in the PapDB  defaultGroup configuration  and creating it manually overrides the existing configuration, so currently PdpGroup is opaGroup and it will be changed to defaultGroup once added in the configuration.
-	PdpType             = "opa"
-	ServerPort          = ":8282"
-	SERVER_WAIT_UP_TIME = 5
-	SHUTDOWN_WAIT_TIME  = 5
-	V1_COMPATIBLE       = "--v1-compatible"
-	LatestVersion       = "1.0.0"
-	MinorVersion        = "0"
-	PatchVersion        = "0"
-	OpaPdpUrl           = "self"
-	HealtCheckStatus    = true
-	OkCode              = int32(200)
-	HealthCheckMessage  = "alive"
+	PdpType                   = "opa"
+	ServerPort                = ":8282"
+	SERVER_WAIT_UP_TIME       = 5
+	SHUTDOWN_WAIT_TIME        = 5
+	LatestVersion             = "1.0.0"
+	MinorVersion              = "0"
+	PatchVersion              = "0"
+	OpaPdpUrl                 = "self"
+	HealtCheckStatus          = true
+	OkCode                    = int32(200)
+	HealthCheckMessage        = "alive"
+	TestDecisionHeader        = "X-PDP-Test-Decision"
+	PassiveRetryAfterSeconds  = 30
+	PersistenceSnapshotFile   = "pdp-snapshot.json"
+	ProcessedRequestCacheSize = 100
+	InMemoryTransportBuffer   = 100
+	MessageQueueSize          = 10
+	BundleRevisionHeader      = "X-Bundle-Revision"
+	BundleServerPath          = "/opa/bundles"
+	JwksCacheSeconds          = 600
+	JwksMinRefreshSeconds     = 30
+	JwksFetchTimeoutSeconds   = 10
+	DefaultApiPassword        = "zb!XztG34"
+	CredentialsCacheSize      = 1000
+	ApiUserRole               = "admin"
+	InFlightRetryAfterSeconds = 1
+	ClientLimiterIdleSeconds  = 300
+	DecisionTimeoutHeader     = "X-Decision-Timeout"
+	OpaReadyTimeoutSeconds    = 30
 )
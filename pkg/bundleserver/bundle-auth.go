@@ -0,0 +1,91 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package bundleserver
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/log"
+	"strings"
+	"sync"
+)
+
+var (
+	bundleToken     string
+	bundleTokenOnce sync.Once
+)
+
+// Returns the bearer token bundle requests must carry, BUNDLE_TOKEN when configured,
+// otherwise a random token generated once per process and only known to the OPA SDK.
+func Token() string {
+	bundleTokenOnce.Do(func() {
+		if cfg.BundleToken != "" {
+			bundleToken = cfg.BundleToken.Value()
+			return
+		}
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			log.Errorf("Failed to generate the bundle token, bundle requests will be rejected: %v", err)
+			return
+		}
+		bundleToken = hex.EncodeToString(random)
+	})
+	return bundleToken
+}
+
+// Rejects bundle requests that do not carry the bundle token as a bearer token.
+func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		expected := Token()
+		if !ok || expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			log.Warnf("Rejected unauthenticated bundle request from %s", req.RemoteAddr)
+			res.Header().Set("WWW-Authenticate", `Bearer realm="Restricted"`)
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(res, req)
+	}
+}
+
+// Has the bundle services of an OPA SDK config authenticate with the bundle client plugin, which
+// adds the bundle token and verifies the downloaded bundles. Services with their own credentials
+// are left alone.
+func injectCredentials(config map[string]interface{}, services map[string]map[string]interface{}) {
+	injected := 0
+	for _, fields := range services {
+		if _, exists := fields["credentials"]; exists {
+			continue
+		}
+		fields["credentials"] = map[string]interface{}{"plugin": ClientPluginName}
+		injected++
+	}
+	if injected > 0 {
+		configuredPlugins, _ := config["plugins"].(map[string]interface{})
+		if configuredPlugins == nil {
+			configuredPlugins = map[string]interface{}{}
+			config["plugins"] = configuredPlugins
+		}
+		configuredPlugins[ClientPluginName] = map[string]interface{}{}
+	}
+	log.Debugf("Added bundle credentials to %d OPA SDK service(s)", injected)
+}
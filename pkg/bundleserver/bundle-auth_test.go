@@ -0,0 +1,117 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package bundleserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"policy-opa-pdp/cfg"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	token := Token()
+	assert.Len(t, token, 64, "Expected a random 32 byte hex token")
+	assert.Equal(t, token, Token(), "Expected the same token for the lifetime of the process")
+}
+
+func TestAuthenticate(t *testing.T) {
+	handler := Authenticate(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		statusCode    int
+	}{
+		{"valid token", "Bearer " + Token(), http.StatusOK},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"basic auth", "Basic dGVzdHVzZXI6dGVzdHBhc3M=", http.StatusUnauthorized},
+		{"no credentials", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/opa/bundles/bundle.tar.gz", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+			assert.Equal(t, tt.statusCode, rr.Code)
+			if tt.statusCode == http.StatusUnauthorized {
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+// prepares the SDK config without switching bundle services to HTTPS
+func prepareConfig(t *testing.T, config string) io.Reader {
+	allowPlainHTTP := cfg.AllowPlainHTTP
+	defer func() { cfg.AllowPlainHTTP = allowPlainHTTP }()
+	cfg.AllowPlainHTTP = true
+	prepared, err := PrepareSDKConfig(strings.NewReader(config))
+	assert.NoError(t, err)
+	return prepared
+}
+
+func decodeConfig(t *testing.T, config io.Reader) map[string]interface{} {
+	var parsed map[string]interface{}
+	assert.NoError(t, json.NewDecoder(config).Decode(&parsed))
+	return parsed
+}
+
+func TestInjectCredentials(t *testing.T) {
+	config := `{
+  "services": [
+    {"name": "opa-bundle-server", "url": "http://localhost:8282/opa/bundles"},
+    {"name": "own-credentials", "url": "http://localhost:8282/opa/bundles/", "credentials": {"bearer": {"token_path": "/run/token"}}},
+    {"name": "elsewhere", "url": "https://bundles.example.com"}
+  ],
+  "bundles": {"opabundle": {"service": "opa-bundle-server", "resource": "bundle.tar.gz", "polling": {"min_delay_seconds": 60}}}
+}`
+
+	parsed := decodeConfig(t, prepareConfig(t, config))
+
+	services := parsed["services"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"plugin": ClientPluginName}, services[0].(map[string]interface{})["credentials"])
+	assert.Equal(t, map[string]interface{}{"bearer": map[string]interface{}{"token_path": "/run/token"}}, services[1].(map[string]interface{})["credentials"])
+	assert.NotContains(t, services[2].(map[string]interface{}), "credentials")
+	assert.Contains(t, parsed["plugins"], ClientPluginName)
+
+	bundles := parsed["bundles"].(map[string]interface{})
+	assert.Equal(t, float64(60), bundles["opabundle"].(map[string]interface{})["polling"].(map[string]interface{})["min_delay_seconds"])
+}
+
+func TestInjectCredentials_ServiceMap(t *testing.T) {
+	injected := prepareConfig(t, `{"services": {"opa-bundle-server": {"url": "http://localhost:8282/opa/bundles"}}}`)
+
+	parsed := decodeConfig(t, injected)
+	service := parsed["services"].(map[string]interface{})["opa-bundle-server"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"plugin": ClientPluginName}, service["credentials"])
+	assert.Contains(t, parsed["plugins"], ClientPluginName)
+}
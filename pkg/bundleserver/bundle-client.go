@@ -0,0 +1,121 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package bundleserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/plugins"
+	"github.com/open-policy-agent/opa/plugins/rest"
+)
+
+// ClientPluginName is the OPA SDK plugin the bundle services authenticate with, it adds the
+// bundle token to their requests and verifies the bundles they download.
+const ClientPluginName = "opa-pdp-bundle-client"
+
+// Returns the plugins PrepareSDKConfig configures, to be passed to sdk.New.
+func SDKPlugins() map[string]plugins.Factory {
+	return map[string]plugins.Factory{ClientPluginName: clientPluginFactory{}}
+}
+
+type clientPluginFactory struct{}
+
+func (clientPluginFactory) Validate(*plugins.Manager, []byte) (interface{}, error) {
+	return nil, nil
+}
+
+func (clientPluginFactory) New(manager *plugins.Manager, _ interface{}) plugins.Plugin {
+	return &clientPlugin{manager: manager}
+}
+
+// clientPlugin is an HTTP auth plugin of the OPA SDK
+type clientPlugin struct {
+	manager *plugins.Manager
+}
+
+func (p *clientPlugin) Start(context.Context) error {
+	p.manager.UpdatePluginStatus(ClientPluginName, &plugins.Status{State: plugins.StateOK})
+	return nil
+}
+
+func (p *clientPlugin) Stop(context.Context) {
+	p.manager.UpdatePluginStatus(ClientPluginName, &plugins.Status{State: plugins.StateNotReady})
+}
+
+func (p *clientPlugin) Reconfigure(context.Context, interface{}) {}
+
+// Creates the HTTP client of a bundle service the way OPA does for bearer tokens, with a
+// transport that verifies the downloaded bundles.
+func (p *clientPlugin) NewClient(config rest.Config) (*http.Client, error) {
+	tlsConfig, err := rest.DefaultTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	client := rest.DefaultRoundTripperClient(tlsConfig, *config.ResponseHeaderTimeoutSeconds)
+	client.Transport = &verifyingTransport{next: client.Transport}
+	return client, nil
+}
+
+func (p *clientPlugin) Prepare(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+Token())
+	return nil
+}
+
+// verifyingTransport verifies the signature of the bundles downloaded through it, so a bundle
+// the OPA SDK refuses is recognised from the bytes it received
+type verifyingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *verifyingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	keys, err := loadSigningKeys()
+	if err != nil || keys == nil {
+		return res, nil
+	}
+
+	content, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(content))
+	recordVerification(req.URL.String(), verifyBundle(content, keys))
+	return res, nil
+}
+
+// Returns the verification error of a bundle, nil when it verifies or cannot be read even
+// without verification, which the OPA SDK reports as a load failure.
+func verifyBundle(content []byte, keys *signingKeys) error {
+	_, err := bundle.NewReader(bytes.NewReader(content)).WithBundleVerificationConfig(verificationConfigOf(keys)).Read()
+	if err == nil {
+		return nil
+	}
+	if _, readErr := bundle.NewReader(bytes.NewReader(content)).WithSkipBundleVerification(true).Read(); readErr != nil {
+		return nil
+	}
+	return err
+}
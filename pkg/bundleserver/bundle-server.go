@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telecom
+//   Copyright (C) 2024-2025: Deutsche Telecom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -18,49 +18,266 @@
 
 // Package bundleserver provides functionalities for serving and building OPA bundles.
 // This package includes functions to handle HTTP requests for bundles and
-// to build OPA bundles using specified commands
+// to build OPA bundles in-process from the policy and data directories
 package bundleserver
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"policy-opa-pdp/consts"
 	"policy-opa-pdp/pkg/log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/compile"
+	"github.com/open-policy-agent/opa/loader"
 )
 
-// handles HTTP requests to serve the OPA bundle
+// Describes the bundle last built by BuildBundle.
+type BundleInfo struct {
+	Revision string
+	Manifest bundle.Manifest
+	BuiltAt  time.Time
+}
+
+// A problem found in one of the policy or data files while building the bundle,
+// File, Row and Col are empty when the problem is not tied to a location.
+type CompileError struct {
+	File    string `json:"file,omitempty"`
+	Row     int    `json:"row,omitempty"`
+	Col     int    `json:"col,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e CompileError) Error() string {
+	if e.File == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Row, e.Col, e.Message)
+}
+
+// Returned by BuildBundle when the policies or data could not be loaded or compiled.
+type BuildError struct {
+	Errors []CompileError
+}
+
+func (e *BuildError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, compileErr := range e.Errors {
+		messages[i] = compileErr.Error()
+	}
+	return fmt.Sprintf("bundle build failed with %d error(s): %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+var (
+	currentBundle   *BundleInfo
+	currentBundleMu sync.RWMutex
+)
+
+// handles HTTP requests to serve the OPA bundle, the ETag is a hash of the bundle content
+// so a bundle plugin polling with If-None-Match gets a 304 while the bundle is unchanged
 func GetBundle(res http.ResponseWriter, req *http.Request) {
 	log.Debugf("PDP received a Bundle request.")
 
-	file, err := os.Open(consts.BundleTarGzFile)
-
+	content, modTime, err := readBundle()
 	if err != nil {
 		log.Warnf("Bundle server could not serve the request ::: %s", err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	defer file.Close()
 
+	sum := sha256.Sum256(content)
+	res.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	res.Header().Set("Cache-Control", "no-cache")
+	if info := CurrentBundle(); info != nil {
+		res.Header().Set(consts.BundleRevisionHeader, info.Revision)
+	}
 	res.Header().Set("Content-Type", "application/octet-stream")
 	res.Header().Set("Content-Disposition", "attachment; filename="+consts.BundleTarGz)
 	res.Header().Set("Content-Transfer-Encoding", "binary")
-	res.Header().Set("Expires", "0")
-	http.ServeContent(res, req, "Bundle Request Response", time.Now(), file)
+	// ServeContent answers If-None-Match and If-Modified-Since with 304 Not Modified
+	http.ServeContent(res, req, consts.BundleTarGz, modTime, bytes.NewReader(content))
 }
 
-// builds the OPA bundle using specified commands
-func BuildBundle(cmdFunc func(string, ...string) *exec.Cmd) error {
-	cmd := cmdFunc(consts.Opa, consts.BuildBundle, consts.V1_COMPATIBLE, consts.Policies, consts.Data, consts.Output, consts.BundleTarGzFile)
-	log.Debugf("Before calling combinedoutput")
-	output, err := cmd.CombinedOutput()
+func readBundle() ([]byte, time.Time, error) {
+	file, err := os.Open(consts.BundleTarGzFile)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer file.Close()
 
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	content, err := io.ReadAll(file)
 	if err != nil {
-		log.Warnf("Error output : %s", string(output))
-		log.Warnf("Failed to build Bundle: %v", err)
+		return nil, time.Time{}, err
+	}
+	return content, stat.ModTime(), nil
+}
+
+// Reports whether the bundle can be served, used as the health check of the bundle server.
+func CheckBundle() error {
+	_, err := os.Stat(consts.BundleTarGzFile)
+	return err
+}
+
+// Returns the revision and manifest of the last bundle built, nil before the first successful build.
+func CurrentBundle() *BundleInfo {
+	currentBundleMu.RLock()
+	defer currentBundleMu.RUnlock()
+	return currentBundle
+}
+
+// builds the OPA bundle from the policy and data directories and writes it to the bundle file,
+// load and compile failures are returned as a *BuildError
+func BuildBundle() error {
+	compiler := compile.New().
+		WithPaths(consts.Policies, consts.Data).
+		WithRegoVersion(ast.RegoV1)
+
+	if err := compiler.Build(context.Background()); err != nil {
+		buildErr := newBuildError(err)
+		for _, compileErr := range buildErr.Errors {
+			log.Warnf("Bundle compile error: %s", compileErr.Error())
+		}
+		return buildErr
+	}
+
+	b := compiler.Bundle()
+	revision, err := bundleRevision(b)
+	if err != nil {
+		return fmt.Errorf("failed to compute bundle revision: %w", err)
+	}
+	b.Manifest.Revision = revision
+
+	if err := signBundle(b); err != nil {
+		log.Warnf("Failed to sign Bundle: %v", err)
 		return err
 	}
-	log.Debug("Bundle Built Sucessfully....")
+
+	if err := writeBundle(b); err != nil {
+		log.Warnf("Failed to write Bundle: %v", err)
+		return err
+	}
+
+	currentBundleMu.Lock()
+	currentBundle = &BundleInfo{Revision: revision, Manifest: b.Manifest, BuiltAt: time.Now()}
+	currentBundleMu.Unlock()
+
+	log.Debugf("Bundle Built Sucessfully with revision %s", revision)
 	return nil
 }
+
+// the revision is a hash of the bundle content so the same policies and data always give the same revision
+func bundleRevision(b *bundle.Bundle) (string, error) {
+	hash := sha256.New()
+
+	modules := make([]bundle.ModuleFile, len(b.Modules))
+	copy(modules, b.Modules)
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	for _, module := range modules {
+		hash.Write([]byte(module.Path))
+		hash.Write([]byte{0})
+		hash.Write(module.Raw)
+		hash.Write([]byte{0})
+	}
+
+	// encoding/json sorts map keys, which keeps the data encoding stable
+	data, err := json.Marshal(b.Data)
+	if err != nil {
+		return "", err
+	}
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writes to a temporary file first so a bundle request never sees a partially written bundle,
+// an unchanged bundle is left alone so its Last-Modified time stays accurate
+func writeBundle(b *bundle.Bundle) error {
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(*b); err != nil {
+		return err
+	}
+
+	if existing, err := os.ReadFile(consts.BundleTarGzFile); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		log.Debugf("Bundle content is unchanged, keeping %s", consts.BundleTarGzFile)
+		return nil
+	}
+
+	dir := filepath.Dir(consts.BundleTarGzFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".bundle-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), consts.BundleTarGzFile)
+}
+
+// flattens loader and compiler errors into compile errors carrying the file and line where possible
+func newBuildError(err error) *BuildError {
+	buildErr := &BuildError{}
+	for _, e := range flattenErrors(err) {
+		var astErr *ast.Error
+		if errors.As(e, &astErr) {
+			compileErr := CompileError{Code: astErr.Code, Message: astErr.Message}
+			if astErr.Location != nil {
+				compileErr.File = astErr.Location.File
+				compileErr.Row = astErr.Location.Row
+				compileErr.Col = astErr.Location.Col
+			}
+			buildErr.Errors = append(buildErr.Errors, compileErr)
+			continue
+		}
+		buildErr.Errors = append(buildErr.Errors, CompileError{Message: e.Error()})
+	}
+	return buildErr
+}
+
+func flattenErrors(err error) []error {
+	var astErrs ast.Errors
+	if errors.As(err, &astErrs) {
+		flattened := make([]error, len(astErrs))
+		for i, e := range astErrs {
+			flattened[i] = e
+		}
+		return flattened
+	}
+	var loaderErrs loader.Errors
+	if errors.As(err, &loaderErrs) {
+		flattened := []error{}
+		for _, e := range loaderErrs {
+			flattened = append(flattened, flattenErrors(e)...)
+		}
+		return flattened
+	}
+	return []error{err}
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -20,29 +20,40 @@
 package bundleserver
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
+	"sync"
 	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	bundleplugin "github.com/open-policy-agent/opa/plugins/bundle"
+	"github.com/open-policy-agent/opa/sdk"
+	"github.com/stretchr/testify/assert"
 )
 
-// Mock function for exec.Command
-func mockCmd(command string, args ...string) *exec.Cmd {
-	cs := []string{"-test.run=TestHelperProcess", "--", command}
-	cs = append(cs, args...)
-	cmd := exec.Command(os.Args[0], cs...)
-	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
-	return cmd
-}
+// points the bundle build at temporary policy and data directories
+func setupBundleDirs(t *testing.T, policy string, data string) {
+	policies, dataDir, bundleFile := consts.Policies, consts.Data, consts.BundleTarGzFile
+	t.Cleanup(func() { consts.Policies, consts.Data, consts.BundleTarGzFile = policies, dataDir, bundleFile })
 
-// TestHelperProcess is a helper process used by mockCmd
-func TestHelperProcess(*testing.T) {
-	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
-		return
+	consts.Policies, consts.Data = t.TempDir(), t.TempDir()
+	consts.BundleTarGzFile = filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if policy != "" {
+		if err := os.WriteFile(filepath.Join(consts.Policies, "policy.rego"), []byte(policy), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+	}
+	if data != "" {
+		if err := os.WriteFile(filepath.Join(consts.Data, "data.json"), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
 	}
-	os.Exit(0)
 }
 
 func TestGetBundle(t *testing.T) {
@@ -94,25 +105,183 @@ func TestGetBundle_FileNotFound(t *testing.T) {
 }
 
 func TestBuildBundle(t *testing.T) {
-	err := BuildBundle(mockCmd)
-	if err != nil {
-		t.Errorf("BuildBundle() error = %v, wantErr %v", err, nil)
+	setupBundleDirs(t, "package example\n\nallow if input.user == \"admin\"\n", `{"example": {"users": ["admin"]}}`)
+
+	err := BuildBundle()
+	assert.NoError(t, err)
+
+	file, err := os.Open(consts.BundleTarGzFile)
+	assert.NoError(t, err)
+	defer file.Close()
+	b, err := bundle.NewReader(file).Read()
+	assert.NoError(t, err)
+	assert.Len(t, b.Modules, 1)
+	assert.Equal(t, map[string]interface{}{"example": map[string]interface{}{"users": []interface{}{"admin"}}}, b.Data)
+
+	info := CurrentBundle()
+	assert.NotNil(t, info)
+	assert.NotEmpty(t, info.Revision)
+	assert.Equal(t, info.Revision, b.Manifest.Revision)
+	assert.Equal(t, b.Manifest.Revision, info.Manifest.Revision)
+}
+
+func TestBuildBundle_RevisionFollowsContent(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	assert.NoError(t, BuildBundle())
+	first := CurrentBundle().Revision
+
+	assert.NoError(t, BuildBundle())
+	assert.Equal(t, first, CurrentBundle().Revision, "the same content gives the same revision")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(consts.Policies, "policy.rego"), []byte("package example\n\nallow := false\n"), 0644))
+	assert.NoError(t, BuildBundle())
+	assert.NotEqual(t, first, CurrentBundle().Revision, "changed content gives a new revision")
+}
+
+func TestBuildBundle_CompileErrors(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow if {\n\tinput.user == undefined_var\n}\n", "")
+
+	err := BuildBundle()
+	var buildErr *BuildError
+	assert.True(t, errors.As(err, &buildErr))
+	assert.NotEmpty(t, buildErr.Errors)
+	assert.Equal(t, filepath.Join(consts.Policies, "policy.rego"), buildErr.Errors[0].File)
+	assert.Equal(t, 4, buildErr.Errors[0].Row)
+	assert.Equal(t, "rego_unsafe_var_error", buildErr.Errors[0].Code)
+	assert.Contains(t, err.Error(), "policy.rego:4")
+
+	_, statErr := os.Stat(consts.BundleTarGzFile)
+	assert.True(t, os.IsNotExist(statErr), "no bundle is written on failure")
+}
+
+func TestBuildBundle_ParseErrors(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow if {\n", "")
+
+	err := BuildBundle()
+	var buildErr *BuildError
+	assert.True(t, errors.As(err, &buildErr))
+	assert.NotEmpty(t, buildErr.Errors)
+	assert.Equal(t, filepath.Join(consts.Policies, "policy.rego"), buildErr.Errors[0].File)
+	assert.Equal(t, "rego_parse_error", buildErr.Errors[0].Code)
+}
+
+func TestBuildBundle_MissingDirectory(t *testing.T) {
+	setupBundleDirs(t, "", "")
+	consts.Policies = filepath.Join(consts.Policies, "missing")
+
+	err := BuildBundle()
+	var buildErr *BuildError
+	assert.True(t, errors.As(err, &buildErr))
+	assert.Len(t, buildErr.Errors, 1)
+	assert.Empty(t, buildErr.Errors[0].File)
+	assert.NotEmpty(t, buildErr.Errors[0].Message)
+}
+
+func TestGetBundle_ConditionalGet(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	assert.NoError(t, BuildBundle())
+
+	rr := httptest.NewRecorder()
+	GetBundle(rr, httptest.NewRequest(http.MethodGet, "/opa/bundles/", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, CurrentBundle().Revision, rr.Header().Get(consts.BundleRevisionHeader))
+
+	stat, err := os.Stat(consts.BundleTarGzFile)
+	assert.NoError(t, err)
+	assert.Equal(t, stat.ModTime().UTC().Format(http.TimeFormat), rr.Header().Get("Last-Modified"))
+
+	// an unchanged bundle is answered with 304
+	req := httptest.NewRequest(http.MethodGet, "/opa/bundles/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	GetBundle(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+
+	// rebuilding the same content keeps the ETag and the modification time
+	assert.NoError(t, BuildBundle())
+	rebuilt, err := os.Stat(consts.BundleTarGzFile)
+	assert.NoError(t, err)
+	assert.Equal(t, stat.ModTime(), rebuilt.ModTime())
+
+	// changed content gives a new ETag and revision
+	assert.NoError(t, os.WriteFile(filepath.Join(consts.Policies, "policy.rego"), []byte("package example\n\nallow := false\n"), 0644))
+	assert.NoError(t, BuildBundle())
+	req = httptest.NewRequest(http.MethodGet, "/opa/bundles/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	GetBundle(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	assert.Equal(t, CurrentBundle().Revision, rr.Header().Get(consts.BundleRevisionHeader))
+}
+
+// the bundle plugin of the OPA SDK polls with the ETag of its bundle and gets 304 while the
+// bundle is unchanged
+func TestGetBundle_ConditionalPollsOfOPA(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	signingKey := cfg.BundleSigningKey
+	defer func() { cfg.BundleSigningKey = signingKey }()
+	cfg.BundleSigningKey = ""
+	assert.NoError(t, BuildBundle())
+
+	var mu sync.Mutex
+	var statusCodes []int
+	server := httptest.NewServer(Authenticate(func(res http.ResponseWriter, req *http.Request) {
+		rr := httptest.NewRecorder()
+		GetBundle(rr, req)
+		mu.Lock()
+		statusCodes = append(statusCodes, rr.Code)
+		mu.Unlock()
+		for key, values := range rr.Header() {
+			res.Header()[key] = values
+		}
+		res.WriteHeader(rr.Code)
+		res.Write(rr.Body.Bytes())
+	}))
+	defer server.Close()
+
+	config := prepareConfig(t, `{
+  "services": [{"name": "opa-bundle-server", "url": "`+server.URL+consts.BundleServerPath+`"}],
+  "bundles": {"opabundle": {"service": "opa-bundle-server", "resource": "bundle.tar.gz", "trigger": "manual"}}
+}`)
+	opa, err := sdk.New(context.Background(), sdk.Options{Config: config, V1Compatible: true, Plugins: SDKPlugins(), Ready: make(chan struct{})})
+	if !assert.NoError(t, err) {
+		return
 	}
+	defer opa.Stop(context.Background())
+	plugin := opa.Plugin(bundleplugin.Name).(*bundleplugin.Plugin)
+
+	assert.NoError(t, plugin.Trigger(context.Background()))
+	assert.NoError(t, plugin.Trigger(context.Background()))
+	assert.NoError(t, os.WriteFile(filepath.Join(consts.Policies, "policy.rego"), []byte("package example\n\nallow := false\n"), 0644))
+	assert.NoError(t, BuildBundle())
+	assert.NoError(t, plugin.Trigger(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified, http.StatusOK}, statusCodes)
+	result, err := opa.Decision(context.Background(), sdk.DecisionOptions{Path: "/example/allow"})
+	assert.NoError(t, err)
+	assert.Equal(t, false, result.Result)
 }
 
-func TestBuildBundle_CommandFailure(t *testing.T) {
-	// Mock function to simulate command failure
-	mockCmdFail := func(command string, args ...string) *exec.Cmd {
-		cs := []string{"-test.run=TestHelperProcess", "--", command}
-		cs = append(cs, args...)
-		cmd := exec.Command(os.Args[0], cs...)
-		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
-		cmd.Stderr = os.Stderr
-		return cmd
+func TestCheckBundle(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "bundle-*.tar.gz")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
 	}
+	defer os.Remove(tmpFile.Name())
 
-	err := BuildBundle(mockCmdFail)
-	if err == nil {
-		t.Errorf("BuildBundle() error = nil, wantErr %v", "command failure")
+	consts.BundleTarGzFile = tmpFile.Name()
+	if err := CheckBundle(); err != nil {
+		t.Errorf("CheckBundle() error = %v, wantErr %v", err, nil)
+	}
+
+	consts.BundleTarGzFile = "nonexistent-file.tar.gz"
+	if err := CheckBundle(); err == nil {
+		t.Errorf("CheckBundle() expected an error for a missing bundle")
 	}
 }
@@ -0,0 +1,208 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package bundleserver
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/metrics"
+	"sync"
+
+	"github.com/open-policy-agent/opa/bundle"
+)
+
+// the algorithms bundles can be signed with
+var signingAlgorithms = map[string]bool{"RS256": true, "ES256": true, "HS256": true}
+
+// The keys bundles are signed and verified with.
+type signingKeys struct {
+	algorithm       string
+	keyID           string
+	signingKey      string
+	verificationKey string
+}
+
+var (
+	verificationFailure   string
+	verificationFailureMu sync.Mutex
+)
+
+// Reads the signing key and its verification key, nil when bundle signing is not configured.
+// Without BUNDLE_VERIFICATION_KEY the verification key is derived from the signing key.
+func loadSigningKeys() (*signingKeys, error) {
+	if cfg.BundleSigningKey == "" {
+		return nil, nil
+	}
+	if !signingAlgorithms[cfg.BundleSigningAlg] {
+		return nil, fmt.Errorf("unsupported bundle signing algorithm %q, use RS256, ES256 or HS256", cfg.BundleSigningAlg)
+	}
+
+	signingKey, err := os.ReadFile(cfg.BundleSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("error reading bundle signing key: %w", err)
+	}
+	keys := &signingKeys{algorithm: cfg.BundleSigningAlg, keyID: cfg.BundleKeyId, signingKey: string(signingKey)}
+
+	if cfg.BundleVerifyKey != "" {
+		verificationKey, err := os.ReadFile(cfg.BundleVerifyKey)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bundle verification key: %w", err)
+		}
+		keys.verificationKey = string(verificationKey)
+	} else if keys.verificationKey, err = verificationKeyOf(keys.algorithm, signingKey); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// HS256 verifies with the shared secret, RS256 and ES256 with the public key of the signing key.
+func verificationKeyOf(algorithm string, signingKey []byte) (string, error) {
+	if algorithm == "HS256" {
+		return string(signingKey), nil
+	}
+
+	block, _ := pem.Decode(signingKey)
+	if block == nil {
+		return "", errors.New("bundle signing key is not PEM encoded")
+	}
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error parsing bundle signing key: %w", err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return "", errors.New("bundle signing key has no public key")
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("error encoding bundle verification key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})), nil
+}
+
+// signs the bundle files into .signatures.json when signing is configured
+func signBundle(b *bundle.Bundle) error {
+	keys, err := loadSigningKeys()
+	if err != nil || keys == nil {
+		return err
+	}
+	signingConfig := bundle.NewSigningConfig(keys.signingKey, keys.algorithm, "")
+	if err := b.GenerateSignature(signingConfig, keys.keyID, false); err != nil {
+		return fmt.Errorf("error signing bundle: %w", err)
+	}
+	log.Debugf("Bundle signed with key %s using %s", keys.keyID, keys.algorithm)
+	return nil
+}
+
+// Adds the verification key to an OPA SDK config and has the bundles served by the bundle server,
+// over HTTP or from the bundle file, verified with it. Bundles with their own signing config are left alone.
+func injectVerification(config map[string]interface{}, services map[string]map[string]interface{}) error {
+	keys, err := loadSigningKeys()
+	if err != nil || keys == nil {
+		return err
+	}
+
+	keyConfigs, _ := config["keys"].(map[string]interface{})
+	if keyConfigs == nil {
+		keyConfigs = map[string]interface{}{}
+		config["keys"] = keyConfigs
+	}
+	keyConfigs[keys.keyID] = map[string]interface{}{
+		"algorithm": keys.algorithm,
+		"key":       keys.verificationKey,
+	}
+
+	bundles, _ := config["bundles"].(map[string]interface{})
+	for name, source := range bundles {
+		fields, ok := source.(map[string]interface{})
+		if !ok || !isServedBundle(fields, services) {
+			continue
+		}
+		if _, exists := fields["signing"]; exists {
+			continue
+		}
+		fields["signing"] = map[string]interface{}{"keyid": keys.keyID}
+		log.Debugf("OPA SDK verifies bundle %s with key %s", name, keys.keyID)
+	}
+	return nil
+}
+
+func isServedBundle(fields map[string]interface{}, services map[string]map[string]interface{}) bool {
+	if resource, _ := fields["resource"].(string); resource == "file://"+consts.BundleTarGzFile {
+		return true
+	}
+	service, _ := fields["service"].(string)
+	_, served := services[service]
+	return served
+}
+
+// Counts and remembers a bundle downloaded from the source that failed signature verification,
+// until a bundle from it verifies.
+func recordVerification(source string, verificationErr error) {
+	verificationFailureMu.Lock()
+	defer verificationFailureMu.Unlock()
+	if verificationErr == nil {
+		verificationFailure = ""
+		return
+	}
+
+	failure := fmt.Sprintf("bundle from %s failed signature verification: %v", source, verificationErr)
+	// the SDK downloads a refused bundle again on every poll, it is counted once
+	if failure == verificationFailure {
+		return
+	}
+	log.Errorf("Bundle from %s refused, signature verification failed: %v", source, verificationErr)
+	metrics.IncrementBundleVerificationFailureCount()
+	verificationFailure = failure
+}
+
+// the verification config of bundles signed with the keys
+func verificationConfigOf(keys *signingKeys) *bundle.VerificationConfig {
+	return bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{
+		keys.keyID: {Key: keys.verificationKey, Algorithm: keys.algorithm},
+	}, keys.keyID, "", nil)
+}
+
+// Reports whether the last bundle downloaded by the OPA SDK from the bundle server passed signature verification,
+// used as the health check of bundle verification.
+func CheckVerification() error {
+	verificationFailureMu.Lock()
+	defer verificationFailureMu.Unlock()
+	if verificationFailure != "" {
+		return errors.New(verificationFailure)
+	}
+	return nil
+}
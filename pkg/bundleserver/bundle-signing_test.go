@@ -0,0 +1,245 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package bundleserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/metrics"
+	"sync"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	bundleplugin "github.com/open-policy-agent/opa/plugins/bundle"
+	"github.com/open-policy-agent/opa/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+// writes a signing key for the algorithm and configures bundle signing with it
+func setupSigningKey(t *testing.T, algorithm string) {
+	signingKey, signingAlg, verifyKey, keyId := cfg.BundleSigningKey, cfg.BundleSigningAlg, cfg.BundleVerifyKey, cfg.BundleKeyId
+	t.Cleanup(func() {
+		cfg.BundleSigningKey, cfg.BundleSigningAlg, cfg.BundleVerifyKey, cfg.BundleKeyId = signingKey, signingAlg, verifyKey, keyId
+	})
+
+	var key []byte
+	switch algorithm {
+	case "RS256":
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		key = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	case "ES256":
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		der, err := x509.MarshalECPrivateKey(privateKey)
+		assert.NoError(t, err)
+		key = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	case "HS256":
+		key = []byte("a-shared-secret-of-sufficient-length")
+	}
+
+	cfg.BundleSigningKey = filepath.Join(t.TempDir(), "signing.key")
+	assert.NoError(t, os.WriteFile(cfg.BundleSigningKey, key, 0600))
+	cfg.BundleSigningAlg = algorithm
+	cfg.BundleVerifyKey = ""
+	cfg.BundleKeyId = "test-key"
+}
+
+// reads the bundle file, verifying its signature with the configured keys
+func readVerifiedBundle(t *testing.T, content []byte) (bundle.Bundle, error) {
+	keys, err := loadSigningKeys()
+	assert.NoError(t, err)
+	return bundle.NewReader(bytes.NewReader(content)).WithBundleVerificationConfig(verificationConfigOf(keys)).Read()
+}
+
+func TestBuildBundle_Signed(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "ES256", "HS256"} {
+		t.Run(algorithm, func(t *testing.T) {
+			setupBundleDirs(t, "package example\n\nallow := true\n", `{"example": {"users": ["admin"]}}`)
+			setupSigningKey(t, algorithm)
+
+			assert.NoError(t, BuildBundle())
+			content, err := os.ReadFile(consts.BundleTarGzFile)
+			assert.NoError(t, err)
+
+			b, err := readVerifiedBundle(t, content)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, b.Signatures.Signatures)
+			assert.Equal(t, CurrentBundle().Revision, b.Manifest.Revision)
+		})
+	}
+}
+
+func TestBuildBundle_TamperedBundleFailsVerification(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	setupSigningKey(t, "RS256")
+	assert.NoError(t, BuildBundle())
+	content := tamperedBundle(t)
+
+	_, err := readVerifiedBundle(t, content)
+	assert.Error(t, err)
+	keys, err := loadSigningKeys()
+	assert.NoError(t, err)
+	assert.ErrorContains(t, verifyBundle(content, keys), "digest mismatch")
+	assert.NoError(t, verifyBundle([]byte("not a bundle"), keys), "Expected a bundle that cannot be read not to be a verification failure")
+}
+
+func TestBuildBundle_UnsignedBundleFailsVerification(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	assert.NoError(t, BuildBundle())
+	content, err := os.ReadFile(consts.BundleTarGzFile)
+	assert.NoError(t, err)
+
+	setupSigningKey(t, "HS256")
+	_, err = readVerifiedBundle(t, content)
+	assert.Error(t, err)
+	keys, err := loadSigningKeys()
+	assert.NoError(t, err)
+	assert.Error(t, verifyBundle(content, keys), "Expected the unsigned bundle to be recognised as failing verification")
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	setupSigningKey(t, "RS256")
+
+	keys, err := loadSigningKeys()
+	assert.NoError(t, err)
+	assert.Contains(t, keys.verificationKey, "BEGIN PUBLIC KEY")
+
+	cfg.BundleVerifyKey = filepath.Join(t.TempDir(), "verification.pem")
+	assert.NoError(t, os.WriteFile(cfg.BundleVerifyKey, []byte("configured key"), 0644))
+	keys, err = loadSigningKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, "configured key", keys.verificationKey)
+
+	cfg.BundleSigningAlg = "none"
+	_, err = loadSigningKeys()
+	assert.Error(t, err)
+
+	cfg.BundleSigningKey = ""
+	keys, err = loadSigningKeys()
+	assert.NoError(t, err)
+	assert.Nil(t, keys, "Expected no keys when signing is not configured")
+}
+
+func TestRecordVerification(t *testing.T) {
+	metrics.BundleVerificationFailureCount = 0
+	defer recordVerification("", nil)
+	source := "https://localhost:8282/opa/bundles/bundle.tar.gz"
+
+	recordVerification(source, nil)
+	assert.NoError(t, CheckVerification())
+
+	recordVerification(source, errors.New("digest mismatch"))
+	assert.ErrorContains(t, CheckVerification(), "digest mismatch")
+	assert.Equal(t, int64(1), metrics.BundleVerificationFailureCount)
+
+	recordVerification(source, errors.New("digest mismatch"))
+	assert.Equal(t, int64(1), metrics.BundleVerificationFailureCount, "Expected a repeated failure to be counted once")
+
+	recordVerification(source, nil)
+	assert.NoError(t, CheckVerification(), "Expected a verified bundle to clear the failure")
+
+	recordVerification(source, errors.New("digest mismatch"))
+	assert.Error(t, CheckVerification())
+	assert.Equal(t, int64(2), metrics.BundleVerificationFailureCount)
+}
+
+// returns the bundle file with a changed policy, keeping the original signature
+func tamperedBundle(t *testing.T) []byte {
+	content, err := os.ReadFile(consts.BundleTarGzFile)
+	assert.NoError(t, err)
+	b, err := readVerifiedBundle(t, content)
+	assert.NoError(t, err)
+
+	b.Modules[0].Raw = []byte("package example\n\nallow := false\n")
+	var tampered bytes.Buffer
+	assert.NoError(t, bundle.NewWriter(&tampered).Write(b))
+	return tampered.Bytes()
+}
+
+// the OPA SDK activates the signed bundle and refuses it once it is tampered with on the way,
+// which is recognised from the downloaded bundle while the bundle file still verifies
+func TestSignedBundle_VerifiedByOPA(t *testing.T) {
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	setupSigningKey(t, "ES256")
+	assert.NoError(t, BuildBundle())
+	metrics.BundleVerificationFailureCount = 0
+	defer recordVerification("", nil)
+
+	original, err := os.ReadFile(consts.BundleTarGzFile)
+	assert.NoError(t, err)
+	var servedMu sync.Mutex
+	served := original
+	server := httptest.NewServer(Authenticate(func(res http.ResponseWriter, req *http.Request) {
+		servedMu.Lock()
+		defer servedMu.Unlock()
+		res.Write(served)
+	}))
+	defer server.Close()
+	serve := func(content []byte) {
+		servedMu.Lock()
+		defer servedMu.Unlock()
+		served = content
+	}
+
+	config := prepareConfig(t, `{
+  "services": [{"name": "opa-bundle-server", "url": "`+server.URL+consts.BundleServerPath+`"}],
+  "bundles": {"opabundle": {"service": "opa-bundle-server", "resource": "bundle.tar.gz", "trigger": "manual"}}
+}`)
+	opa, err := sdk.New(context.Background(), sdk.Options{Config: config, V1Compatible: true, Plugins: SDKPlugins(), Ready: make(chan struct{})})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer opa.Stop(context.Background())
+	plugin := opa.Plugin(bundleplugin.Name).(*bundleplugin.Plugin)
+	decide := func() interface{} {
+		result, err := opa.Decision(context.Background(), sdk.DecisionOptions{Path: "/example/allow"})
+		assert.NoError(t, err)
+		return result.Result
+	}
+
+	assert.NoError(t, plugin.Trigger(context.Background()))
+	assert.Equal(t, true, decide())
+	assert.NoError(t, CheckVerification())
+
+	serve(tamperedBundle(t))
+	assert.NoError(t, plugin.Trigger(context.Background()))
+	assert.NoError(t, plugin.Trigger(context.Background()))
+	assert.ErrorContains(t, CheckVerification(), "digest mismatch")
+	assert.Equal(t, int64(1), metrics.BundleVerificationFailureCount)
+	assert.Equal(t, true, decide(), "Expected the tampered bundle not to be activated")
+
+	serve(original)
+	assert.NoError(t, plugin.Trigger(context.Background()))
+	assert.NoError(t, CheckVerification(), "Expected the verified bundle to clear the failure")
+}
@@ -0,0 +1,109 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package bundleserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/log"
+	"strings"
+)
+
+// Prepares the OPA SDK config for the bundle server: services pointing at the bundle endpoint
+// get the bundle token and use HTTPS when the server has TLS, and when bundles are signed,
+// their bundles are verified with the signing key.
+func PrepareSDKConfig(config io.Reader) (io.Reader, error) {
+	decoder := json.NewDecoder(config)
+	decoder.UseNumber()
+	var parsed map[string]interface{}
+	if err := decoder.Decode(&parsed); err == io.EOF {
+		return bytes.NewReader(nil), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error parsing OPA SDK config: %w", err)
+	}
+
+	services := bundleServices(parsed)
+	injectCredentials(parsed, services)
+	if err := injectTLS(services); err != nil {
+		return nil, err
+	}
+	if err := injectVerification(parsed, services); err != nil {
+		return nil, err
+	}
+
+	result, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding OPA SDK config: %w", err)
+	}
+	return bytes.NewReader(result), nil
+}
+
+// Returns the services of an OPA SDK config that point at the bundle endpoint by name.
+func bundleServices(config map[string]interface{}) map[string]map[string]interface{} {
+	services := map[string]map[string]interface{}{}
+	switch configured := config["services"].(type) {
+	case []interface{}:
+		for _, service := range configured {
+			fields, _ := service.(map[string]interface{})
+			if name, _ := fields["name"].(string); isBundleService(fields) {
+				services[name] = fields
+			}
+		}
+	case map[string]interface{}:
+		for name, service := range configured {
+			fields, _ := service.(map[string]interface{})
+			if isBundleService(fields) {
+				services[name] = fields
+			}
+		}
+	}
+	return services
+}
+
+func isBundleService(fields map[string]interface{}) bool {
+	url, _ := fields["url"].(string)
+	return strings.HasSuffix(strings.TrimSuffix(url, "/"), consts.BundleServerPath)
+}
+
+// Switches plain HTTP bundle services to HTTPS while the server has TLS. Services without their
+// own TLS config trust the CA in BUNDLE_TLS_CA_FILE, which must have issued the server certificate.
+func injectTLS(services map[string]map[string]interface{}) error {
+	if cfg.AllowPlainHTTP {
+		return nil
+	}
+	for name, fields := range services {
+		url, _ := fields["url"].(string)
+		if !strings.HasPrefix(url, "http://") {
+			continue
+		}
+		fields["url"] = "https://" + strings.TrimPrefix(url, "http://")
+		if _, exists := fields["tls"]; !exists {
+			if cfg.BundleCaFile == "" {
+				return fmt.Errorf("OPA SDK service %s uses HTTPS, set BUNDLE_TLS_CA_FILE or its tls.ca_cert to the CA of the server certificate", name)
+			}
+			fields["tls"] = map[string]interface{}{"ca_cert": cfg.BundleCaFile}
+		}
+		log.Debugf("OPA SDK service %s uses HTTPS", name)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package bundleserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"strings"
+	"testing"
+	"time"
+
+	bundleplugin "github.com/open-policy-agent/opa/plugins/bundle"
+	"github.com/open-policy-agent/opa/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareSDKConfig_Verification(t *testing.T) {
+	setupSigningKey(t, "HS256")
+	config := `{
+  "services": [
+    {"name": "opa-bundle-server", "url": "http://localhost:8282/opa/bundles"},
+    {"name": "elsewhere", "url": "https://bundles.example.com"}
+  ],
+  "bundles": {
+    "opabundle": {"service": "opa-bundle-server", "resource": "bundle.tar.gz"},
+    "fromdisk": {"resource": "file://` + consts.BundleTarGzFile + `"},
+    "ownsigning": {"service": "opa-bundle-server", "signing": {"keyid": "other"}},
+    "external": {"service": "elsewhere"}
+  }
+}`
+
+	parsed := decodeConfig(t, prepareConfig(t, config))
+
+	assert.Equal(t, map[string]interface{}{"algorithm": "HS256", "key": "a-shared-secret-of-sufficient-length"},
+		parsed["keys"].(map[string]interface{})["test-key"])
+	bundles := parsed["bundles"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"keyid": "test-key"}, bundles["opabundle"].(map[string]interface{})["signing"])
+	assert.Equal(t, map[string]interface{}{"keyid": "test-key"}, bundles["fromdisk"].(map[string]interface{})["signing"])
+	assert.Equal(t, map[string]interface{}{"keyid": "other"}, bundles["ownsigning"].(map[string]interface{})["signing"])
+	assert.NotContains(t, bundles["external"], "signing")
+}
+
+func TestPrepareSDKConfig_EmptyAndInvalid(t *testing.T) {
+	injected, err := PrepareSDKConfig(strings.NewReader(""))
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(injected)
+	assert.Empty(t, content)
+
+	_, err = PrepareSDKConfig(strings.NewReader("{not json"))
+	assert.Error(t, err)
+}
+
+func TestPrepareSDKConfig_TLS(t *testing.T) {
+	allowPlainHTTP, caFile := cfg.AllowPlainHTTP, cfg.BundleCaFile
+	defer func() { cfg.AllowPlainHTTP, cfg.BundleCaFile = allowPlainHTTP, caFile }()
+	config := `{"services": [
+    {"name": "opa-bundle-server", "url": "http://localhost:8282/opa/bundles"},
+    {"name": "own-tls", "url": "http://localhost:8282/opa/bundles", "tls": {"ca_cert": "/etc/ca.crt"}},
+    {"name": "elsewhere", "url": "http://bundles.example.com"}
+  ]}`
+	services := func() []interface{} {
+		prepared, err := PrepareSDKConfig(strings.NewReader(config))
+		assert.NoError(t, err)
+		var parsed map[string]interface{}
+		assert.NoError(t, json.NewDecoder(prepared).Decode(&parsed))
+		return parsed["services"].([]interface{})
+	}
+
+	cfg.AllowPlainHTTP = true
+	prepared := services()
+	assert.Equal(t, "http://localhost:8282/opa/bundles", prepared[0].(map[string]interface{})["url"])
+	assert.NotContains(t, prepared[0], "tls")
+
+	cfg.AllowPlainHTTP = false
+	cfg.BundleCaFile = "/etc/tls/ca.crt"
+	prepared = services()
+	assert.Equal(t, "https://localhost:8282/opa/bundles", prepared[0].(map[string]interface{})["url"])
+	assert.Equal(t, map[string]interface{}{"ca_cert": "/etc/tls/ca.crt"}, prepared[0].(map[string]interface{})["tls"])
+	assert.Equal(t, map[string]interface{}{"ca_cert": "/etc/ca.crt"}, prepared[1].(map[string]interface{})["tls"])
+	assert.Equal(t, "http://bundles.example.com", prepared[2].(map[string]interface{})["url"])
+
+	cfg.BundleCaFile = ""
+	_, err := PrepareSDKConfig(strings.NewReader(config))
+	assert.ErrorContains(t, err, "BUNDLE_TLS_CA_FILE")
+}
+
+// writes a CA certificate to the file and returns it with its key
+func writeCA(t *testing.T, caFile string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "bundle ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	return ca, key
+}
+
+// issues a server certificate for 127.0.0.1 signed by the CA
+func issueServerCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "opa-pdp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// the OPA SDK downloads the bundle from a server whose certificate is issued by BUNDLE_TLS_CA_FILE
+func TestPrepareSDKConfig_CASignedServerCertificate(t *testing.T) {
+	allowPlainHTTP, caFile, signingKey := cfg.AllowPlainHTTP, cfg.BundleCaFile, cfg.BundleSigningKey
+	defer func() {
+		cfg.AllowPlainHTTP, cfg.BundleCaFile, cfg.BundleSigningKey = allowPlainHTTP, caFile, signingKey
+	}()
+	setupBundleDirs(t, "package example\n\nallow := true\n", "")
+	cfg.BundleSigningKey = ""
+	assert.NoError(t, BuildBundle())
+
+	cfg.AllowPlainHTTP = false
+	cfg.BundleCaFile = filepath.Join(t.TempDir(), "ca.crt")
+	ca, caKey := writeCA(t, cfg.BundleCaFile)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(GetBundle))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{issueServerCertificate(t, ca, caKey)}}
+	server.StartTLS()
+	defer server.Close()
+
+	config, err := PrepareSDKConfig(strings.NewReader(`{
+  "services": [{"name": "opa-bundle-server", "url": "` + strings.Replace(server.URL, "https://", "http://", 1) + consts.BundleServerPath + `"}],
+  "bundles": {"opabundle": {"service": "opa-bundle-server", "resource": "bundle.tar.gz", "trigger": "manual"}}
+}`))
+	assert.NoError(t, err)
+	opa, err := sdk.New(context.Background(), sdk.Options{Config: config, V1Compatible: true, Plugins: SDKPlugins(), Ready: make(chan struct{})})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer opa.Stop(context.Background())
+
+	assert.NoError(t, opa.Plugin(bundleplugin.Name).(*bundleplugin.Plugin).Trigger(context.Background()))
+	result, err := opa.Decision(context.Background(), sdk.DecisionOptions{Path: "/example/allow"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, result.Result)
+}
@@ -0,0 +1,237 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The credentials package checks the Basic auth credentials of PDP API callers. Users are read
+// from a credentials file holding bcrypt or argon2id password hashes, which is reloaded when it
+// changes. Without a file the single API_USER and API_PASSWORD credential is used.
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// user is an entry of the credentials file
+type user struct {
+	hash  string
+	roles []string
+}
+
+// userFile is the loaded credentials file
+type userFile struct {
+	modTime time.Time
+	size    int64
+	users   map[string]user
+	// the password hashes credentials were verified against, by their keyed digest, so that
+	// repeated requests do not pay for a bcrypt or argon2id verification each
+	verified map[[sha256.Size]byte]string
+}
+
+var (
+	mu        sync.Mutex
+	loaded    *userFile
+	digestKey = randomBytes()
+	dummyOnce sync.Once
+	dummyHash []byte
+)
+
+// Loads the credentials file, failing when it cannot be read or holds invalid entries. Without a
+// credentials file it warns when Basic auth still accepts the built-in default password.
+func Initialize() error {
+	if cfg.CredentialsFile == "" {
+		if usesDefaultPassword() {
+			log.Warnf("SECURITY WARNING: the API is protected by the built-in default password, set API_PASSWORD or API_CREDENTIALS_FILE, or disable Basic auth with ALLOW_BASIC_AUTH=false")
+		}
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	_, err := currentUsers()
+	return err
+}
+
+// returns true when Basic auth accepts the built-in default password
+func usesDefaultPassword() bool {
+	return cfg.CredentialsFile == "" && cfg.AllowBasicAuth && cfg.Password.Value() == consts.DefaultApiPassword
+}
+
+// Checks the credentials and returns the roles of the user, those the credentials file grants it
+// or consts.ApiUserRole for the API_USER credential. Passwords are compared in constant time.
+func Authenticate(username, password string) ([]string, bool) {
+	if cfg.CredentialsFile == "" {
+		// an API_PASSWORD_FILE that could not be read leaves the password empty
+		if cfg.Password == "" {
+			return nil, false
+		}
+		userMatches := subtle.ConstantTimeCompare(digest(username), digest(cfg.Username))
+		passwordMatches := subtle.ConstantTimeCompare(digest(password), digest(cfg.Password.Value()))
+		if userMatches&passwordMatches != 1 {
+			return nil, false
+		}
+		return []string{consts.ApiUserRole}, true
+	}
+
+	mu.Lock()
+	file, err := currentUsers()
+	if err != nil {
+		mu.Unlock()
+		log.Errorf("Credentials file %s could not be loaded: %v", cfg.CredentialsFile, err)
+		return nil, false
+	}
+	entry, known := file.users[username]
+	key := credentialsDigest(username, password)
+	cachedHash, cached := file.verified[key]
+	mu.Unlock()
+
+	if !known {
+		// verify against a dummy hash, so unknown users take as long as known ones
+		dummyOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword(randomBytes()[:16], bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, false
+	}
+	if cached && subtle.ConstantTimeCompare([]byte(cachedHash), []byte(entry.hash)) == 1 {
+		return entry.roles, true
+	}
+	if !verifyHash(entry.hash, password) {
+		return nil, false
+	}
+
+	mu.Lock()
+	if len(file.verified) >= consts.CredentialsCacheSize {
+		file.verified = make(map[[sha256.Size]byte]string)
+	}
+	file.verified[key] = entry.hash
+	mu.Unlock()
+	return entry.roles, true
+}
+
+// Returns the loaded credentials file, reloaded when its modification time or size changed.
+// A file that fails to reload is logged and the previous users are kept until it changes again.
+// Must be called with mu held.
+func currentUsers() (*userFile, error) {
+	info, err := os.Stat(cfg.CredentialsFile)
+	if err != nil {
+		if loaded != nil {
+			return loaded, nil
+		}
+		return nil, err
+	}
+	if loaded != nil && info.ModTime().Equal(loaded.modTime) && info.Size() == loaded.size {
+		return loaded, nil
+	}
+
+	users, err := loadUsers(cfg.CredentialsFile)
+	if err != nil {
+		if loaded == nil {
+			return nil, err
+		}
+		log.Warnf("Credentials file %s could not be reloaded, keeping the previous users: %v", cfg.CredentialsFile, err)
+		loaded.modTime, loaded.size = info.ModTime(), info.Size()
+		return loaded, nil
+	}
+	log.Infof("Loaded %d users from credentials file %s", len(users), cfg.CredentialsFile)
+	loaded = &userFile{
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		users:    users,
+		verified: make(map[[sha256.Size]byte]string),
+	}
+	return loaded, nil
+}
+
+// Reads the credentials file. Each line holds "user:hash" or "user:hash:role,role", blank lines
+// and lines starting with # are ignored. The first two fields are those of an htpasswd file
+// written with htpasswd -B.
+func loadUsers(path string) (map[string]user, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string]user)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, fmt.Errorf("line %d: expected user:hash", lineNumber)
+		}
+		if _, duplicate := users[fields[0]]; duplicate {
+			return nil, fmt.Errorf("line %d: duplicate user %s", lineNumber, fields[0])
+		}
+		if err := validateHash(fields[1]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		entry := user{hash: fields[1]}
+		if len(fields) == 3 {
+			for _, role := range strings.Split(fields[2], ",") {
+				if role = strings.TrimSpace(role); role != "" {
+					entry.roles = append(entry.roles, role)
+				}
+			}
+		}
+		users[fields[0]] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func digest(value string) []byte {
+	sum := sha256.Sum256([]byte(value))
+	return sum[:]
+}
+
+// returns the digest of the credentials keyed with a per-process key, so the cache holds no
+// digest of a password that could be looked up
+func credentialsDigest(username, password string) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, digestKey)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	var key [sha256.Size]byte
+	copy(key[:], mac.Sum(nil))
+	return key
+}
+
+func randomBytes() []byte {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		panic(err)
+	}
+	return value
+}
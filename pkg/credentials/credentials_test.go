@@ -0,0 +1,178 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// configures the credentials file with the content and returns its path
+func setupCredentialsFile(t *testing.T, content string) string {
+	credentialsFile := cfg.CredentialsFile
+	t.Cleanup(func() {
+		cfg.CredentialsFile = credentialsFile
+		loaded = nil
+	})
+	cfg.CredentialsFile = filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, os.WriteFile(cfg.CredentialsFile, []byte(content), 0600))
+	loaded = nil
+	return cfg.CredentialsFile
+}
+
+// rewrites the credentials file with a modification time the reload detects
+func rewriteCredentialsFile(t *testing.T, path, content string) {
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	modTime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestAuthenticate_EnvCredential(t *testing.T) {
+	username, password := cfg.Username, cfg.Password
+	defer func() { cfg.Username, cfg.Password = username, password }()
+	cfg.Username, cfg.Password = "policyadmin", "secret"
+
+	roles, ok := Authenticate("policyadmin", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, []string{consts.ApiUserRole}, roles)
+	for _, credentials := range [][2]string{{"policyadmin", "secre"}, {"policyadmin", "secret "}, {"admin", "secret"}, {"", ""}} {
+		_, ok := Authenticate(credentials[0], credentials[1])
+		assert.False(t, ok, credentials)
+	}
+
+	cfg.Password = ""
+	_, ok = Authenticate("policyadmin", "")
+	assert.False(t, ok, "an empty password is never accepted")
+}
+
+func TestAuthenticate_CredentialsFile(t *testing.T) {
+	setupCredentialsFile(t, `# API users
+pep:`+bcryptHash(t, "pep-secret")+`:decision:abac.
+
+admin:`+argon2idHashOf("admin-secret")+`
+monitor:`+bcryptHash(t, "monitor-secret")+`:monitor, decision
+`)
+	assert.NoError(t, Initialize())
+
+	roles, ok := Authenticate("pep", "pep-secret")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"decision:abac."}, roles)
+	roles, ok = Authenticate("admin", "admin-secret")
+	assert.True(t, ok)
+	assert.Nil(t, roles, "a user without roles in the file holds none")
+	roles, ok = Authenticate("monitor", "monitor-secret")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"monitor", "decision"}, roles)
+
+	_, ok = Authenticate("pep", "admin-secret")
+	assert.False(t, ok)
+	_, ok = Authenticate("unknown", "pep-secret")
+	assert.False(t, ok)
+
+	// the API_USER credential is replaced by the file
+	_, ok = Authenticate(cfg.Username, cfg.Password.Value())
+	assert.False(t, ok)
+}
+
+func TestAuthenticate_VerifiedCache(t *testing.T) {
+	path := setupCredentialsFile(t, "pep:"+bcryptHash(t, "secret")+"\n")
+
+	_, ok := Authenticate("pep", "secret")
+	assert.True(t, ok)
+	assert.Len(t, loaded.verified, 1)
+	_, ok = Authenticate("pep", "secret")
+	assert.True(t, ok)
+	_, ok = Authenticate("pep", "wrong")
+	assert.False(t, ok)
+	assert.Len(t, loaded.verified, 1, "only verified credentials are cached")
+
+	// a changed password invalidates the cached verification
+	rewriteCredentialsFile(t, path, "pep:"+bcryptHash(t, "rotated")+"\n")
+	_, ok = Authenticate("pep", "secret")
+	assert.False(t, ok)
+	_, ok = Authenticate("pep", "rotated")
+	assert.True(t, ok)
+}
+
+func TestAuthenticate_Reload(t *testing.T) {
+	path := setupCredentialsFile(t, "pep:"+bcryptHash(t, "secret")+"\n")
+	_, ok := Authenticate("pep", "secret")
+	assert.True(t, ok)
+
+	rewriteCredentialsFile(t, path, "pep:"+bcryptHash(t, "secret")+"\nother:"+bcryptHash(t, "other-secret")+"\n")
+	_, ok = Authenticate("other", "other-secret")
+	assert.True(t, ok)
+
+	// an invalid file keeps the previous users
+	rewriteCredentialsFile(t, path, "pep:plaintext\n")
+	_, ok = Authenticate("other", "other-secret")
+	assert.True(t, ok)
+
+	// as does a removed file
+	assert.NoError(t, os.Remove(path))
+	_, ok = Authenticate("pep", "secret")
+	assert.True(t, ok)
+}
+
+func TestInitialize_InvalidFile(t *testing.T) {
+	tests := map[string]string{
+		"plain text password": "pep:secret\n",
+		"missing hash":        "pep\n",
+		"missing user":        ":" + argon2idHashOf("secret") + "\n",
+		"duplicate user":      "pep:" + argon2idHashOf("a") + "\npep:" + argon2idHashOf("b") + "\n",
+	}
+	for name, content := range tests {
+		setupCredentialsFile(t, content)
+		assert.Error(t, Initialize(), name)
+		_, ok := Authenticate("pep", "secret")
+		assert.False(t, ok, name)
+	}
+
+	setupCredentialsFile(t, "")
+	cfg.CredentialsFile = filepath.Join(t.TempDir(), "missing")
+	assert.Error(t, Initialize())
+}
+
+func TestUsesDefaultPassword(t *testing.T) {
+	password, credentialsFile, allowBasicAuth := cfg.Password, cfg.CredentialsFile, cfg.AllowBasicAuth
+	defer func() {
+		cfg.Password, cfg.CredentialsFile, cfg.AllowBasicAuth = password, credentialsFile, allowBasicAuth
+	}()
+	cfg.Password, cfg.CredentialsFile, cfg.AllowBasicAuth = cfg.Secret(consts.DefaultApiPassword), "", true
+
+	assert.True(t, usesDefaultPassword())
+	// the default password is only warned about, existing deployments keep starting
+	assert.NoError(t, Initialize())
+
+	cfg.AllowBasicAuth = false
+	assert.False(t, usesDefaultPassword())
+	cfg.AllowBasicAuth = true
+	cfg.CredentialsFile = "/app/config/credentials"
+	assert.False(t, usesDefaultPassword())
+	cfg.CredentialsFile = ""
+	cfg.Password = "changed"
+	assert.False(t, usesDefaultPassword())
+}
@@ -0,0 +1,120 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package credentials
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// the bcrypt hash prefixes, $2y$ is written by htpasswd -B
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// argon2idHash is a parsed "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>" hash
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// Checks that the hash is a bcrypt or argon2id hash, plain text passwords are refused.
+func validateHash(hash string) error {
+	if isBcrypt(hash) {
+		_, err := bcrypt.Cost([]byte(hash))
+		return err
+	}
+	if strings.HasPrefix(hash, "$argon2id$") {
+		_, err := parseArgon2id(hash)
+		return err
+	}
+	return fmt.Errorf("unsupported password hash, use bcrypt or argon2id")
+}
+
+// Returns true when the password matches the hash. Both bcrypt and argon2id compare in constant time.
+func verifyHash(hash, password string) bool {
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	parsed, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), parsed.salt, parsed.time, parsed.memory, parsed.threads, uint32(len(parsed.key)))
+	return subtle.ConstantTimeCompare(key, parsed.key) == 1
+}
+
+func isBcrypt(hash string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parses an argon2id hash in the PHC string format, as written by the argon2 CLI with -id -e
+func parseArgon2id(hash string) (*argon2idHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, fmt.Errorf("invalid argon2id hash")
+	}
+	if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return nil, fmt.Errorf("unsupported argon2id version %s", parts[2])
+	}
+	parsed := &argon2idHash{}
+	for _, param := range strings.Split(parts[3], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		number, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argon2id parameter %s", param)
+		}
+		switch name {
+		case "m":
+			parsed.memory = uint32(number)
+		case "t":
+			parsed.time = uint32(number)
+		case "p":
+			if number > 255 {
+				return nil, fmt.Errorf("invalid argon2id parameter %s", param)
+			}
+			parsed.threads = uint8(number)
+		default:
+			return nil, fmt.Errorf("unknown argon2id parameter %s", param)
+		}
+	}
+	if parsed.memory == 0 || parsed.time == 0 || parsed.threads == 0 {
+		return nil, fmt.Errorf("argon2id hash lacks the m, t or p parameter")
+	}
+	var err error
+	if parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(parsed.key) == 0 {
+		return nil, fmt.Errorf("invalid argon2id key")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,71 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package credentials
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bcryptHash(t *testing.T, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return string(hash)
+}
+
+func argon2idHashOf(password string) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, 1, 8*1024, 1, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=8192,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestVerifyHash(t *testing.T) {
+	for _, hash := range []string{bcryptHash(t, "secret"), argon2idHashOf("secret")} {
+		assert.NoError(t, validateHash(hash))
+		assert.True(t, verifyHash(hash, "secret"), hash)
+		assert.False(t, verifyHash(hash, "Secret"), hash)
+		assert.False(t, verifyHash(hash, ""), hash)
+	}
+}
+
+func TestValidateHash_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"plain text":        "secret",
+		"md5 htpasswd":      "$apr1$salt$hash",
+		"truncated bcrypt":  "$2y$10$abc",
+		"argon2i":           "$argon2i$v=19$m=8192,t=1,p=1$c2FsdA$a2V5",
+		"argon2 version":    "$argon2id$v=16$m=8192,t=1,p=1$c2FsdA$a2V5",
+		"missing parameter": "$argon2id$v=19$m=8192,t=1$c2FsdA$a2V5",
+		"unknown parameter": "$argon2id$v=19$m=8192,t=1,p=1,x=2$c2FsdA$a2V5",
+		"too many threads":  "$argon2id$v=19$m=8192,t=1,p=256$c2FsdA$a2V5",
+		"invalid salt":      "$argon2id$v=19$m=8192,t=1,p=1$!!$a2V5",
+		"missing key":       "$argon2id$v=19$m=8192,t=1,p=1$c2FsdA$",
+	}
+	for name, hash := range tests {
+		assert.Error(t, validateHash(hash), name)
+		assert.False(t, verifyHash(hash, "secret"), name)
+	}
+}
@@ -0,0 +1,133 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The deadletter package keeps the PAP messages the PDP could not process, e.g. because they
+// are not valid JSON or fail validation. Each message is counted in the metrics and written
+// with its original payload, headers and the error reason to a dead-letter topic or file, so
+// broken PAP deployments can be diagnosed afterwards.
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// Letter is a message the PDP could not process.
+type Letter struct {
+	Payload     string            `json:"payload"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Reason      string            `json:"reason"`
+	TimestampMs int64             `json:"timestampMs"`
+}
+
+// Sink stores dead letters.
+type Sink interface {
+	Write(letter Letter) error
+}
+
+var (
+	sink   Sink // the configured sink, letters are only counted and logged when nil
+	sinkMu sync.RWMutex
+)
+
+// Sets the sink dead letters are written to, nil disables writing them.
+func SetSink(s Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sink = s
+}
+
+// Counts a message the PDP could not process and writes it to the configured sink.
+func Publish(payload []byte, headers map[string]string, reason string) {
+	metrics.IncrementDeadLetterCount()
+	log.Warnf("Dead-lettering PAP message: %s", reason)
+
+	sinkMu.RLock()
+	s := sink
+	sinkMu.RUnlock()
+	if s == nil {
+		return
+	}
+	letter := Letter{
+		Payload:     string(payload),
+		Headers:     headers,
+		Reason:      reason,
+		TimestampMs: time.Now().UnixMilli(),
+	}
+	if err := s.Write(letter); err != nil {
+		log.Errorf("Failed to write dead letter: %v", err)
+	}
+}
+
+// FileSink appends dead letters as JSON lines to a local file.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Creates a sink appending to the file at path, the file is created on the first letter.
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Appends the letter to the file.
+func (f *FileSink) Write(letter Letter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// TopicSender sends a message to a topic.
+type TopicSender interface {
+	SendTo(topic string, message []byte) error
+}
+
+// TopicSink publishes dead letters as JSON to a topic.
+type TopicSink struct {
+	sender TopicSender
+	topic  string
+}
+
+// Creates a sink publishing to topic with sender.
+func NewTopicSink(sender TopicSender, topic string) *TopicSink {
+	return &TopicSink{sender: sender, topic: topic}
+}
+
+// Publishes the letter to the topic.
+func (t *TopicSink) Write(letter Letter) error {
+	message, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return t.sender.SendTo(t.topic, message)
+}
@@ -0,0 +1,122 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package deadletter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/pkg/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	letters []Letter
+	err     error
+}
+
+func (r *recordingSink) Write(letter Letter) error {
+	r.letters = append(r.letters, letter)
+	return r.err
+}
+
+type recordingSender struct {
+	topic   string
+	message []byte
+}
+
+func (r *recordingSender) SendTo(topic string, message []byte) error {
+	r.topic, r.message = topic, message
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	defer SetSink(nil)
+	sink := &recordingSink{}
+	SetSink(sink)
+	before := *metrics.DeadLetterCountRef()
+
+	Publish([]byte(`{"messageName":`), map[string]string{"key": "value"}, "unexpected end of JSON input")
+
+	assert.Equal(t, before+1, *metrics.DeadLetterCountRef())
+	if assert.Len(t, sink.letters, 1) {
+		assert.Equal(t, `{"messageName":`, sink.letters[0].Payload)
+		assert.Equal(t, "value", sink.letters[0].Headers["key"])
+		assert.Equal(t, "unexpected end of JSON input", sink.letters[0].Reason)
+		assert.NotZero(t, sink.letters[0].TimestampMs)
+	}
+}
+
+func TestPublish_WithoutSinkOrFailingSink(t *testing.T) {
+	defer SetSink(nil)
+	before := *metrics.DeadLetterCountRef()
+
+	SetSink(nil)
+	Publish([]byte("payload"), nil, "reason")
+	SetSink(&recordingSink{err: errors.New("write failed")})
+	Publish([]byte("payload"), nil, "reason")
+
+	assert.Equal(t, before+2, *metrics.DeadLetterCountRef(), "Letters should be counted even when they cannot be written")
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	sink := NewFileSink(path)
+
+	assert.NoError(t, sink.Write(Letter{Payload: "first", Reason: "bad"}))
+	assert.NoError(t, sink.Write(Letter{Payload: "second", Reason: "worse"}))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	var letters []Letter
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var letter Letter
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &letter))
+		letters = append(letters, letter)
+	}
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, "first", letters[0].Payload)
+		assert.Equal(t, "worse", letters[1].Reason)
+	}
+}
+
+func TestFileSink_Error(t *testing.T) {
+	sink := NewFileSink(filepath.Join(t.TempDir(), "missing", "dead-letters.jsonl"))
+	assert.Error(t, sink.Write(Letter{Payload: "payload"}))
+}
+
+func TestTopicSink(t *testing.T) {
+	sender := &recordingSender{}
+	sink := NewTopicSink(sender, "policy-pdp-pap-dlq")
+
+	assert.NoError(t, sink.Write(Letter{Payload: "payload", Reason: "reason"}))
+
+	assert.Equal(t, "policy-pdp-pap-dlq", sender.topic)
+	var letter Letter
+	assert.NoError(t, json.Unmarshal(sender.message, &letter))
+	assert.Equal(t, "payload", letter.Payload)
+	assert.Equal(t, "reason", letter.Reason)
+}
@@ -0,0 +1,129 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package decision
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/identity"
+	"policy-opa-pdp/pkg/ratelimit"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// admissionControl limits the decisions served, with token buckets for all clients together and
+// for each client, and a semaphore bounding the decisions evaluated at the same time.
+type admissionControl struct {
+	global   *rate.Limiter             // nil when the global rate is unlimited
+	clients  *ratelimit.ClientLimiters // nil when the rate per client is unlimited
+	inFlight chan struct{}             // nil when in-flight decisions are unlimited
+}
+
+var (
+	admissionMu sync.Mutex
+	admission   *admissionControl
+)
+
+// builds the admission control from the configuration
+func newAdmissionControl() *admissionControl {
+	control := &admissionControl{}
+	if cfg.DecisionRate > 0 {
+		control.global = rate.NewLimiter(rate.Limit(cfg.DecisionRate), burstOf(cfg.DecisionBurst, cfg.DecisionRate))
+	}
+	if cfg.ClientRate > 0 {
+		control.clients = ratelimit.NewClientLimiters(rate.Limit(cfg.ClientRate), burstOf(cfg.ClientBurst, cfg.ClientRate))
+	}
+	if cfg.MaxInFlight > 0 {
+		control.inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return control
+}
+
+// the burst of a token bucket defaults to its rate
+func burstOf(burst, rate int) int {
+	if burst > 0 {
+		return burst
+	}
+	return rate
+}
+
+// Admits a decision request or returns why it is refused and the seconds the client should wait
+// before retrying. An admitted request must call release once its decision is made.
+func admit(req *http.Request) (release func(), retryAfter int, rejection string) {
+	now := time.Now()
+	admissionMu.Lock()
+	if admission == nil {
+		admission = newAdmissionControl()
+	}
+	control := admission
+
+	var reservations []*rate.Reservation
+	var wait time.Duration
+	if control.clients != nil {
+		client := clientKey(req)
+		limiter := control.clients.Limiter(client, now)
+		reservation := limiter.ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+		if delay := reservation.DelayFrom(now); delay > wait {
+			wait, rejection = delay, fmt.Sprintf("rate limit of %v decisions per second exceeded by %s", limiter.Limit(), client)
+		}
+	}
+	if control.global != nil {
+		reservation := control.global.ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+		if delay := reservation.DelayFrom(now); delay > wait {
+			wait, rejection = delay, fmt.Sprintf("rate limit of %v decisions per second exceeded", control.global.Limit())
+		}
+	}
+	admissionMu.Unlock()
+
+	// tokens of refused requests are handed back, so refusals do not delay later requests
+	cancel := func() {
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+	}
+	if wait > 0 {
+		cancel()
+		return nil, int(math.Ceil(wait.Seconds())), rejection
+	}
+	if control.inFlight == nil {
+		return func() {}, 0, ""
+	}
+	select {
+	case control.inFlight <- struct{}{}:
+		return func() { <-control.inFlight }, 0, ""
+	default:
+		cancel()
+		return nil, consts.InFlightRetryAfterSeconds, fmt.Sprintf("maximum of %d decisions in flight reached", cap(control.inFlight))
+	}
+}
+
+// identifies the client of a request by its authenticated identity, or by its address
+func clientKey(req *http.Request) string {
+	if caller, ok := identity.FromContext(req.Context()); ok {
+		return caller.String()
+	}
+	return ratelimit.ClientAddress(req)
+}
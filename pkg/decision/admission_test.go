@@ -0,0 +1,182 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package decision
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/identity"
+	"policy-opa-pdp/pkg/metrics"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpstate"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// configures the admission control limits and restores them after the test
+func setupAdmission(t *testing.T, decisionRate, decisionBurst, clientRate, clientBurst, maxInFlight int) {
+	saved := []int{cfg.DecisionRate, cfg.DecisionBurst, cfg.ClientRate, cfg.ClientBurst, cfg.MaxInFlight}
+	t.Cleanup(func() {
+		cfg.DecisionRate, cfg.DecisionBurst, cfg.ClientRate, cfg.ClientBurst, cfg.MaxInFlight = saved[0], saved[1], saved[2], saved[3], saved[4]
+		admission = nil
+	})
+	cfg.DecisionRate, cfg.DecisionBurst, cfg.ClientRate, cfg.ClientBurst, cfg.MaxInFlight = decisionRate, decisionBurst, clientRate, clientBurst, maxInFlight
+	admission = nil
+}
+
+func decisionRequestFrom(caller string) *http.Request {
+	return requestFrom(caller, http.MethodPost, `{"policyName": "example/allow"}`)
+}
+
+func requestFrom(caller, method, body string) *http.Request {
+	req := httptest.NewRequest(method, "/policy/pdpo/v1/decision", strings.NewReader(body))
+	return req.WithContext(identity.NewContext(req.Context(), identity.Identity{Name: caller, Method: identity.MethodBasic}))
+}
+
+func TestAdmit_Unlimited(t *testing.T) {
+	setupAdmission(t, 0, 0, 0, 0, 0)
+	for i := 0; i < 100; i++ {
+		release, _, rejection := admit(decisionRequestFrom("pep"))
+		assert.NotNil(t, release)
+		assert.Empty(t, rejection)
+	}
+}
+
+func TestAdmit_GlobalRate(t *testing.T) {
+	setupAdmission(t, 1, 2, 0, 0, 0)
+
+	for _, caller := range []string{"pep-1", "pep-2"} {
+		release, _, _ := admit(decisionRequestFrom(caller))
+		assert.NotNil(t, release, "the burst is admitted")
+	}
+	release, retryAfter, rejection := admit(decisionRequestFrom("pep-3"))
+	assert.Nil(t, release)
+	assert.Equal(t, 1, retryAfter)
+	assert.Contains(t, rejection, "rate limit of 1 decisions per second exceeded")
+}
+
+func TestAdmit_ClientRate(t *testing.T) {
+	setupAdmission(t, 3, 3, 1, 0, 0)
+
+	release, _, _ := admit(decisionRequestFrom("pep-1"))
+	assert.NotNil(t, release)
+	for i := 0; i < 3; i++ {
+		release, retryAfter, rejection := admit(decisionRequestFrom("pep-1"))
+		assert.Nil(t, release)
+		assert.Equal(t, 1, retryAfter)
+		assert.Contains(t, rejection, "basic:pep-1")
+	}
+
+	// the refused requests of pep-1 did not use up the global rate
+	for _, caller := range []string{"pep-2", "pep-3"} {
+		release, _, _ := admit(decisionRequestFrom(caller))
+		assert.NotNil(t, release, caller)
+	}
+}
+
+func TestAdmit_ClientAddress(t *testing.T) {
+	setupAdmission(t, 0, 0, 1, 1, 0)
+
+	req := httptest.NewRequest(http.MethodPost, "/policy/pdpo/v1/decision", nil)
+	req.RemoteAddr = "10.0.0.1:40000"
+	release, _, _ := admit(req)
+	assert.NotNil(t, release)
+
+	// another connection of the same host shares its limit
+	req.RemoteAddr = "10.0.0.1:40001"
+	release, _, rejection := admit(req)
+	assert.Nil(t, release)
+	assert.Contains(t, rejection, "10.0.0.1")
+
+	req.RemoteAddr = "10.0.0.2:40000"
+	release, _, _ = admit(req)
+	assert.NotNil(t, release)
+}
+
+func TestAdmit_MaxInFlight(t *testing.T) {
+	setupAdmission(t, 0, 0, 0, 0, 2)
+
+	first, _, _ := admit(decisionRequestFrom("pep"))
+	second, _, _ := admit(decisionRequestFrom("pep"))
+	assert.NotNil(t, first)
+	assert.NotNil(t, second)
+
+	release, retryAfter, rejection := admit(decisionRequestFrom("pep"))
+	assert.Nil(t, release)
+	assert.Equal(t, 1, retryAfter)
+	assert.Equal(t, "maximum of 2 decisions in flight reached", rejection)
+
+	first()
+	release, _, _ = admit(decisionRequestFrom("pep"))
+	assert.NotNil(t, release, "a finished decision frees its slot")
+}
+
+func TestOpaDecision_TooManyRequests(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Active
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	setupAdmission(t, 0, 0, 0, 0, 1)
+	release, _, _ := admit(decisionRequestFrom("pep"))
+	defer release()
+	metrics.RateLimitedCount = 0
+	metrics.EvaluationErrorCount = 0
+
+	rec := httptest.NewRecorder()
+	OpaDecision(rec, decisionRequestFrom("pep"))
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "TOO_MANY_REQUESTS")
+	assert.Contains(t, rec.Body.String(), "maximum of 1 decisions in flight reached")
+	assert.Equal(t, int64(1), metrics.RateLimitedCount)
+	assert.Zero(t, metrics.EvaluationErrorCount, "a refused decision is not an evaluation error")
+}
+
+// requests refused for their method or content do not use up the limits of the client
+func TestOpaDecision_InvalidRequestsAreNotAdmitted(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Active
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	setupAdmission(t, 0, 0, 1, 1, 0)
+
+	for _, tt := range []struct {
+		method, body string
+		statusCode   int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "{", http.StatusBadRequest},
+		{http.MethodPost, `{"input": {}}`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		OpaDecision(rec, requestFrom("pep", tt.method, tt.body))
+		assert.Equal(t, tt.statusCode, rec.Code, "%s %s", tt.method, tt.body)
+	}
+
+	release, _, rejection := admit(decisionRequestFrom("pep"))
+	assert.NotNil(t, release)
+	assert.Empty(t, rejection)
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -24,16 +24,22 @@ package decision
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/identity"
 	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/metrics"
 	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/model/oapicodegen"
 	"policy-opa-pdp/pkg/opasdk"
 	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
 	"policy-opa-pdp/pkg/utils"
+	"strconv"
 	"strings"
+	"time"
         "fmt"
 	"github.com/google/uuid"
 	openapi_types "github.com/oapi-codegen/runtime/types"
@@ -44,7 +50,10 @@ import (
 var httpToResponseCode = map[int]oapicodegen.ErrorResponseResponseCode{
 	400: oapicodegen.BADREQUEST,
 	401: oapicodegen.UNAUTHORIZED,
+	429: oapicodegen.TOOMANYREQUESTS,
 	500: oapicodegen.INTERNALSERVERERROR,
+	503: oapicodegen.SERVICEUNAVAILABLE,
+	504: oapicodegen.REQUESTTIMEOUT,
 }
 
 // Gets responsecode from map
@@ -127,17 +136,15 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 	for key, value := range res.Header() {
 		log.Debugf("%s: %s", key, value)
 	}
-	// Check if the system is in an active state
-
-	if pdpstate.GetCurrentState() != model.Active {
-		msg := " System Is In PASSIVE State so Unable To Handle Decision wait until it becomes ACTIVE"
-		errorMsg := " System Is In PASSIVE State so error Handling the request"
-		decisionExc := createDecisionExceptionResponse(http.StatusInternalServerError, msg, []string{errorMsg}, "")
-		metrics.IncrementTotalErrorCount()
-		writeErrorJSONResponse(res, http.StatusInternalServerError, msg, *decisionExc)
-	        return
+	// Check if the system is in a state that serves decisions
+	pdpState := pdpstate.GetCurrentState()
+	if pdpState != model.Active && pdpState != model.Test && pdpState != model.Safe {
+		writeStateUnavailableResponse(res, pdpState)
+		return
+	}
+	if pdpState == model.Test {
+		res.Header().Set(consts.TestDecisionHeader, "true")
 	}
-	ctx := context.Background()
 
 	// Check if the request method is POST
 	if req.Method != http.MethodPost {
@@ -149,6 +156,15 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	timeout, err := decisionTimeout(req)
+	if err != nil {
+		decisionExc := createDecisionExceptionResponse(http.StatusBadRequest, "Invalid decision timeout",
+			[]string{err.Error()}, "")
+		metrics.IncrementTotalErrorCount()
+		writeErrorJSONResponse(res, http.StatusBadRequest, err.Error(), *decisionExc)
+		return
+	}
+
 	var decisionReq oapicodegen.OPADecisionRequest
 
 	// Decode the request body into a DecisionRequest struct
@@ -170,6 +186,32 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Refuse the decision when the rate or in-flight limits are reached, invalid requests are
+	// answered above without using up the limits
+	release, retryAfter, rejection := admit(req)
+	if release == nil {
+		writeTooManyRequestsResponse(res, retryAfter, rejection)
+		return
+	}
+	defer release()
+	// The evaluation is stopped when the caller disconnects or the timeout expires
+	ctx := req.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// In SAFE state either the fallback policy is evaluated or the default decision is served
+	if pdpState == model.Safe {
+		if cfg.SafeModePolicy == "" {
+			writeSafeModeDecision(res, *decisionReq.PolicyName)
+			return
+		}
+		log.Infof("PDP in SAFE state, evaluating fallback policy %s instead of %s", cfg.SafeModePolicy, *decisionReq.PolicyName)
+		decisionReq.PolicyName = &cfg.SafeModePolicy
+	}
+
 	// Get the OPA singleton instance
 	opa, err := opasdk.GetOPASingletonInstance()
 	if err != nil {
@@ -186,6 +228,13 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 	options := sdk.DecisionOptions{Path: *decisionReq.PolicyName, Input: decisionReq.Input}
 
 	decision, decision_err := opa.Decision(ctx, options)
+	if decision_err != nil && ctx.Err() != nil {
+		writeEvaluationStoppedResponse(res, ctx.Err(), *decisionReq.PolicyName, timeout)
+		return
+	}
+	if caller, ok := identity.FromContext(req.Context()); ok && decision != nil {
+		log.Infof("Decision %s on %s requested by %s", decision.ID, *decisionReq.PolicyName, caller)
+	}
 
 	jsonOutput, err := json.MarshalIndent(decision, "", "  ")
 	if err != nil {
@@ -193,6 +242,9 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 	log.Debugf("RAW opa Decision output:\n%s\n", string(jsonOutput))
+	if pdpState == model.Test {
+		log.Infof("TEST state decision for policy %s:\n%s", *decisionReq.PolicyName, string(jsonOutput))
+	}
 
 	// Check for errors in the OPA decision
 	if decision_err != nil {
@@ -205,6 +257,7 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 		} else {
 			decisionExc := createDecisionExceptionResponse(http.StatusBadRequest, "Error from OPA while making decision",
 				[]string{decision_err.Error()}, *decisionReq.PolicyName)
+			metrics.IncrementEvaluationErrorCount()
 			metrics.IncrementTotalErrorCount()
 			writeErrorJSONResponse(res, http.StatusBadRequest, decision_err.Error(), *decisionExc)
 			return
@@ -319,6 +372,85 @@ func OpaDecision(res http.ResponseWriter, req *http.Request) {
 
 }
 
+// rejects decisions while the PDP is not serving them, PASSIVE clients are asked to retry later
+func writeStateUnavailableResponse(res http.ResponseWriter, pdpState model.PdpState) {
+	msg := fmt.Sprintf(" System Is In %s State so Unable To Handle Decision wait until it becomes ACTIVE", pdpState)
+	errorMsg := fmt.Sprintf(" System Is In %s State so error Handling the request", pdpState)
+	if pdpState == model.Passive {
+		res.Header().Set("Retry-After", strconv.Itoa(consts.PassiveRetryAfterSeconds))
+	}
+	decisionExc := createDecisionExceptionResponse(http.StatusServiceUnavailable, msg, []string{errorMsg}, "")
+	metrics.IncrementTotalErrorCount()
+	writeErrorJSONResponse(res, http.StatusServiceUnavailable, msg, *decisionExc)
+}
+
+// Returns the time the decision may take, the configured timeout lowered by the timeout header
+// of the caller. Callers cannot raise the configured timeout, 0 means no timeout.
+func decisionTimeout(req *http.Request) (time.Duration, error) {
+	timeout := time.Duration(cfg.DecisionTimeout) * time.Millisecond
+	header := req.Header.Get(consts.DecisionTimeoutHeader)
+	if header == "" {
+		return timeout, nil
+	}
+	requestedMs, err := strconv.Atoi(header)
+	if err != nil || requestedMs <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number of milliseconds, got %q", consts.DecisionTimeoutHeader, header)
+	}
+	requested := time.Duration(requestedMs) * time.Millisecond
+	if timeout > 0 && timeout < requested {
+		return timeout, nil
+	}
+	return requested, nil
+}
+
+// answers a decision whose evaluation was stopped, a timed out one with 504. Nothing is written
+// to a caller that disconnected. Timeouts are counted per deployed policy the decision path is
+// evaluated in, those of other paths under metrics.UnknownPolicy.
+func writeEvaluationStoppedResponse(res http.ResponseWriter, ctxErr error, policyName string, timeout time.Duration) {
+	if !errors.Is(ctxErr, context.DeadlineExceeded) {
+		log.Debugf("Decision on %s cancelled, the caller disconnected", policyName)
+		return
+	}
+	msg := fmt.Sprintf("Decision not made within %s", timeout)
+	log.Warnf("Decision on %s timed out after %s", policyName, timeout)
+	decisionExc := createDecisionExceptionResponse(http.StatusGatewayTimeout, msg, []string{ctxErr.Error()}, policyName)
+	if deployed, ok := policyregistry.PolicyOfDecisionPath(policyName); ok {
+		metrics.ObserveDecisionTimeout(deployed)
+	} else {
+		metrics.ObserveDecisionTimeout(metrics.UnknownPolicy)
+	}
+	metrics.IncrementEvaluationErrorCount()
+	metrics.IncrementTotalErrorCount()
+	writeErrorJSONResponse(res, http.StatusGatewayTimeout, msg, *decisionExc)
+}
+
+// rejects decisions refused by the admission control, the client is asked to retry later
+func writeTooManyRequestsResponse(res http.ResponseWriter, retryAfter int, rejection string) {
+	msg := "Too many decision requests"
+	log.Debugf("Decision refused: %s", rejection)
+	res.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	decisionExc := createDecisionExceptionResponse(http.StatusTooManyRequests, msg, []string{rejection}, "")
+	metrics.IncrementRateLimitedCount()
+	metrics.IncrementTotalErrorCount()
+	writeErrorJSONResponse(res, http.StatusTooManyRequests, msg, *decisionExc)
+}
+
+// serves the configured default decision while the PDP is in SAFE state, cfg.Validate refuses
+// other decisions than PERMIT, DENY and NOTAPPLICABLE at startup
+func writeSafeModeDecision(res http.ResponseWriter, policyName string) {
+	decision := oapicodegen.OPADecisionResponseDecision(cfg.SafeModeDecision)
+	switch decision {
+	case oapicodegen.PERMIT:
+		metrics.IncrementPermitDecisionsCount()
+	case oapicodegen.DENY:
+		metrics.IncrementDenyDecisionsCount()
+	case oapicodegen.NOTAPPLICABLE:
+		// no policy was queried, so this is not a query failure
+	}
+	decisionRes := createSuccessDecisionResponse("PDP In SAFE State, Default Decision Served", string(decision), policyName, nil)
+	writeOpaJSONResponse(res, http.StatusOK, *decisionRes)
+}
+
 // Function to apply policy filter to decision result
 func applyPolicyFilter(result map[string]interface{}, filters []string) interface{} {
 
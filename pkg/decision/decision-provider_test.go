@@ -29,13 +29,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/metrics"
 	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/model/oapicodegen"
 	opasdk "policy-opa-pdp/pkg/opasdk"
 	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -138,8 +142,10 @@ func TestOpaDecision_PassiveState(t *testing.T) {
 
 	OpaDecision(rec, req)
 
-	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
 	assert.Contains(t, rec.Body.String(), " System Is In PASSIVE State")
+	assert.Contains(t, rec.Body.String(), "SERVICE_UNAVAILABLE")
 }
 
 // New
@@ -403,7 +409,7 @@ func Test_passive_system_state(t *testing.T) {
 
 	OpaDecision(res, req)
 
-	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
 	assert.Contains(t, res.Body.String(), "System Is In PASSIVE State")
 }
 
@@ -791,3 +797,237 @@ func TestWriteErrorJSONResponse_EncodingFailure(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
 }
+
+func TestOpaDecision_TerminatedState(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Terminated
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", nil)
+	rec := httptest.NewRecorder()
+
+	OpaDecision(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Empty(t, rec.Header().Get("Retry-After"), "A terminated PDP should not ask clients to retry")
+}
+
+func TestOpaDecision_TestState(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Test
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+
+	patch := monkey.PatchInstanceMethod(
+		reflect.TypeOf(&sdk.OPA{}), "Decision",
+		func(_ *sdk.OPA, _ context.Context, _ sdk.DecisionOptions) (*sdk.DecisionResult, error) {
+			return &sdk.DecisionResult{Result: true}, nil
+		},
+	)
+	defer patch.Unpatch()
+
+	body := map[string]interface{}{"policyName": "s3", "input": map[string]interface{}{"content": "content"}}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBuffer(jsonBody))
+	rec := httptest.NewRecorder()
+
+	OpaDecision(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(consts.TestDecisionHeader))
+	assert.Contains(t, rec.Body.String(), "PERMIT")
+}
+
+func TestOpaDecision_SafeState_DefaultDecision(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Safe
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	originalPolicy, originalDecision := cfg.SafeModePolicy, cfg.SafeModeDecision
+	cfg.SafeModePolicy, cfg.SafeModeDecision = "", "DENY"
+	defer func() { cfg.SafeModePolicy, cfg.SafeModeDecision = originalPolicy, originalDecision }()
+
+	body := map[string]interface{}{"policyName": "s3", "input": map[string]interface{}{"content": "content"}}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBuffer(jsonBody))
+	rec := httptest.NewRecorder()
+
+	OpaDecision(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "DENY")
+	assert.Contains(t, rec.Body.String(), "SAFE State")
+}
+
+func TestOpaDecision_SafeState_NotApplicableIsNotAQueryFailure(t *testing.T) {
+	originalDecision := cfg.SafeModeDecision
+	cfg.SafeModeDecision = "NOTAPPLICABLE"
+	defer func() { cfg.SafeModeDecision = originalDecision }()
+	queryFailures := *metrics.TotalQueryFailureCountRef()
+
+	rec := httptest.NewRecorder()
+	writeSafeModeDecision(rec, "s3")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "NOTAPPLICABLE")
+	assert.Equal(t, queryFailures, *metrics.TotalQueryFailureCountRef())
+}
+
+func TestOpaDecision_SafeState_FallbackPolicy(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Safe
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	originalPolicy := cfg.SafeModePolicy
+	cfg.SafeModePolicy = "fallback/allow"
+	defer func() { cfg.SafeModePolicy = originalPolicy }()
+
+	var evaluatedPath string
+	patch := monkey.PatchInstanceMethod(
+		reflect.TypeOf(&sdk.OPA{}), "Decision",
+		func(_ *sdk.OPA, _ context.Context, options sdk.DecisionOptions) (*sdk.DecisionResult, error) {
+			evaluatedPath = options.Path
+			return &sdk.DecisionResult{Result: false}, nil
+		},
+	)
+	defer patch.Unpatch()
+
+	body := map[string]interface{}{"policyName": "s3", "input": map[string]interface{}{"content": "content"}}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBuffer(jsonBody))
+	rec := httptest.NewRecorder()
+
+	OpaDecision(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "fallback/allow", evaluatedPath)
+	assert.Contains(t, rec.Body.String(), "DENY")
+}
+
+func TestDecisionTimeout(t *testing.T) {
+	decisionTimeoutMs := cfg.DecisionTimeout
+	defer func() { cfg.DecisionTimeout = decisionTimeoutMs }()
+
+	tests := []struct {
+		configuredMs int
+		header       string
+		timeout      time.Duration
+		valid        bool
+	}{
+		{5000, "", 5 * time.Second, true},
+		{5000, "200", 200 * time.Millisecond, true},
+		{5000, "60000", 5 * time.Second, true},
+		{0, "", 0, true},
+		{0, "200", 200 * time.Millisecond, true},
+		{5000, "0", 0, false},
+		{5000, "-1", 0, false},
+		{5000, "1s", 0, false},
+	}
+	for _, tt := range tests {
+		cfg.DecisionTimeout = tt.configuredMs
+		req := httptest.NewRequest(http.MethodPost, "/opa/decision", nil)
+		if tt.header != "" {
+			req.Header.Set(consts.DecisionTimeoutHeader, tt.header)
+		}
+		timeout, err := decisionTimeout(req)
+		assert.Equal(t, tt.valid, err == nil, "configured %d, header %q", tt.configuredMs, tt.header)
+		assert.Equal(t, tt.timeout, timeout, "configured %d, header %q", tt.configuredMs, tt.header)
+	}
+}
+
+// patches the OPA decision to run until its context is done
+func patchBlockingDecision() func() {
+	instancePatch := monkey.Patch(opasdk.GetOPASingletonInstance, func() (*sdk.OPA, error) {
+		return &sdk.OPA{}, nil
+	})
+	decisionPatch := monkey.PatchInstanceMethod(
+		reflect.TypeOf(&sdk.OPA{}), "Decision",
+		func(_ *sdk.OPA, ctx context.Context, _ sdk.DecisionOptions) (*sdk.DecisionResult, error) {
+			<-ctx.Done()
+			return nil, errors.New("eval_cancel_error: caller cancelled query execution")
+		},
+	)
+	return func() {
+		decisionPatch.Unpatch()
+		instancePatch.Unpatch()
+	}
+}
+
+func TestOpaDecision_Timeout(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Active
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	unpatch := patchBlockingDecision()
+	defer unpatch()
+	metrics.ResetDecisionTimeoutStatistics()
+	defer metrics.ResetDecisionTimeoutStatistics()
+	policyregistry.Clear()
+	defer policyregistry.Clear()
+	policyregistry.Apply("opaGroup", []string{"abac.policy:1.0.0"}, nil)
+	metrics.EvaluationErrorCount = 0
+
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBufferString(`{"policyName": "abac/policy/allow", "input": {}}`))
+	req.Header.Set(consts.DecisionTimeoutHeader, "50")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	OpaDecision(rec, req)
+
+	assert.Less(t, time.Since(start), time.Second, "the caller's timeout stops the evaluation")
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Contains(t, rec.Body.String(), "REQUEST_TIMEOUT")
+	assert.Contains(t, rec.Body.String(), "Decision not made within 50ms")
+	assert.Equal(t, []metrics.DecisionTimeoutStatistics{{PolicyName: "abac.policy", TimeoutCount: 1}}, metrics.GetDecisionTimeoutStatistics())
+	assert.Equal(t, int64(1), metrics.EvaluationErrorCount, "a timed out decision is an evaluation error")
+
+	// the path of a policy that is not deployed is not used as a label
+	req = httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBufferString(`{"policyName": "no/such/policy/allow", "input": {}}`))
+	req.Header.Set(consts.DecisionTimeoutHeader, "50")
+	OpaDecision(httptest.NewRecorder(), req)
+	assert.Equal(t, []metrics.DecisionTimeoutStatistics{
+		{PolicyName: "abac.policy", TimeoutCount: 1},
+		{PolicyName: metrics.UnknownPolicy, TimeoutCount: 1},
+	}, metrics.GetDecisionTimeoutStatistics())
+}
+
+func TestOpaDecision_CallerDisconnected(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Active
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+	unpatch := patchBlockingDecision()
+	defer unpatch()
+	metrics.ResetDecisionTimeoutStatistics()
+	defer metrics.ResetDecisionTimeoutStatistics()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBufferString(`{"policyName": "abac.policy", "input": {}}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	OpaDecision(rec, req)
+
+	assert.Empty(t, rec.Body.String(), "nothing is written to a disconnected caller")
+	assert.Empty(t, metrics.GetDecisionTimeoutStatistics(), "a disconnect is not a timeout")
+}
+
+func TestOpaDecision_InvalidTimeoutHeader(t *testing.T) {
+	originalGetState := pdpstate.GetCurrentState
+	pdpstate.GetCurrentState = func() model.PdpState {
+		return model.Active
+	}
+	defer func() { pdpstate.GetCurrentState = originalGetState }()
+
+	req := httptest.NewRequest(http.MethodPost, "/opa/decision", bytes.NewBufferString(`{"policyName": "abac.policy", "input": {}}`))
+	req.Header.Set(consts.DecisionTimeoutHeader, "soon")
+	rec := httptest.NewRecorder()
+	OpaDecision(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "X-Decision-Timeout must be a positive number of milliseconds")
+}
@@ -0,0 +1,84 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The identity package carries the authenticated caller of the PDP API through the request
+// context, so authorization rules and decision logs can refer to it.
+package identity
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+)
+
+// How a caller authenticated.
+const (
+	MethodMTLS  = "mtls"
+	MethodBasic = "basic"
+	MethodJWT   = "jwt"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	Name    string   // the SPIFFE id or other SAN of a client certificate, its subject common name, the token subject or the Basic auth user
+	Subject string   // the subject of the client certificate or the issuer of the token, empty for Basic auth
+	Method  string   // MethodMTLS, MethodJWT or MethodBasic
+	Roles   []string // the roles granted by the token, the credentials file or the API_USER credential, empty for client certificates
+}
+
+type contextKey struct{}
+
+func (i Identity) String() string {
+	return i.Method + ":" + i.Name
+}
+
+// Returns the identity of a verified client certificate. The first URI SAN, such as a SPIFFE id,
+// is preferred over DNS and email SANs, the subject common name is used when there is no SAN.
+func FromCertificate(cert *x509.Certificate) Identity {
+	id := Identity{Subject: cert.Subject.String(), Method: MethodMTLS}
+	switch {
+	case len(cert.URIs) > 0:
+		id.Name = cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		id.Name = cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		id.Name = cert.EmailAddresses[0]
+	default:
+		id.Name = cert.Subject.CommonName
+	}
+	return id
+}
+
+// Returns the identity of the client certificate of a request, when one was presented and verified.
+func FromRequest(req *http.Request) (Identity, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return Identity{}, false
+	}
+	return FromCertificate(req.TLS.VerifiedChains[0][0]), true
+}
+
+// Returns a copy of the context carrying the identity.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Returns the identity carried by the context, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,80 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromCertificate(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/policy/sa/pep")
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		expected string
+	}{
+		{"uri san", &x509.Certificate{URIs: []*url.URL{spiffeID}, DNSNames: []string{"pep.example.org"}}, "spiffe://example.org/ns/policy/sa/pep"},
+		{"dns san", &x509.Certificate{DNSNames: []string{"pep.example.org"}, EmailAddresses: []string{"pep@example.org"}}, "pep.example.org"},
+		{"email san", &x509.Certificate{EmailAddresses: []string{"pep@example.org"}}, "pep@example.org"},
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: "pep", Organization: []string{"Example"}}}, "pep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := FromCertificate(tt.cert)
+			assert.Equal(t, tt.expected, id.Name)
+			assert.Equal(t, MethodMTLS, id.Method)
+			assert.Equal(t, tt.cert.Subject.String(), id.Subject)
+		})
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	_, ok := FromRequest(req)
+	assert.False(t, ok, "Expected no identity without TLS")
+
+	req.TLS = &tls.ConnectionState{}
+	_, ok = FromRequest(req)
+	assert.False(t, ok, "Expected no identity without a verified client certificate")
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "pep"}}}}
+	id, ok := FromRequest(req)
+	assert.True(t, ok)
+	assert.Equal(t, "mtls:pep", id.String())
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewContext(context.Background(), Identity{Name: "policyadmin", Method: MethodBasic})
+	id, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "basic:policyadmin", id.String())
+}
@@ -0,0 +1,139 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package jwtauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// the largest JWKS accepted from a URL
+const maxJwksSize = 1 << 20
+
+// keySet is a loaded JWKS
+type keySet struct {
+	jwks     string          // the JWKS document, as passed to io.jwt.decode_verify
+	kids     map[string]bool // the key ids of the JWKS
+	loadedAt time.Time
+}
+
+var (
+	keysMu       sync.Mutex
+	keys         *keySet
+	keysReloads  singleflight.Group
+	loadJwksFunc = loadJwks
+	jwksClient   = &http.Client{Timeout: time.Duration(consts.JwksFetchTimeoutSeconds) * time.Second}
+)
+
+// Returns the cached JWKS. It is reloaded once it is older than consts.JwksCacheSeconds, or when
+// it does not hold the key id of a token, which happens after the identity provider rotated its keys.
+// Reloads triggered by unknown key ids are limited to one per consts.JwksMinRefreshSeconds, and a
+// JWKS that fails to reload is used until the next attempt. The JWKS is fetched outside keysMu by
+// a single caller at a time, an expired JWKS is served while it is reloaded in the background.
+func currentKeys(kid string) (*keySet, error) {
+	keysMu.Lock()
+	cached := keys
+	keysMu.Unlock()
+
+	if cached != nil {
+		age := time.Since(cached.loadedAt)
+		unknownKid := kid != "" && !cached.kids[kid] && age >= time.Duration(consts.JwksMinRefreshSeconds)*time.Second
+		if !unknownKid {
+			if age >= time.Duration(consts.JwksCacheSeconds)*time.Second {
+				keysReloads.DoChan(cfg.JwtJwks, reloadKeys)
+			}
+			return cached, nil
+		}
+	}
+
+	reloaded, err, _ := keysReloads.Do(cfg.JwtJwks, reloadKeys)
+	if err != nil {
+		return nil, err
+	}
+	return reloaded.(*keySet), nil
+}
+
+// reloads the JWKS, keeping the cached keys for another period when it cannot be loaded
+func reloadKeys() (interface{}, error) {
+	loaded, err := loadKeySet(cfg.JwtJwks)
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if err != nil {
+		if keys == nil {
+			return nil, err
+		}
+		log.Warnf("JWKS could not be reloaded from %s, using the cached keys: %v", cfg.JwtJwks, err)
+		loaded = &keySet{jwks: keys.jwks, kids: keys.kids, loadedAt: time.Now()}
+	}
+	keys = loaded
+	return keys, nil
+}
+
+// loads and parses the JWKS
+func loadKeySet(source string) (*keySet, error) {
+	raw, err := loadJwksFunc(source)
+	if err != nil {
+		return nil, err
+	}
+	var document struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	if len(document.Keys) == 0 {
+		return nil, fmt.Errorf("JWKS holds no keys")
+	}
+	loaded := &keySet{jwks: string(raw), kids: make(map[string]bool), loadedAt: time.Now()}
+	for _, key := range document.Keys {
+		loaded.kids[key.Kid] = true
+	}
+	log.Debugf("Loaded %d keys from JWKS %s", len(document.Keys), source)
+	return loaded, nil
+}
+
+// reads the JWKS from an http(s) URL or a file
+func loadJwks(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	res, err := jwksClient.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS returned %s", res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, maxJwksSize))
+}
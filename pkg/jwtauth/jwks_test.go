@@ -0,0 +1,133 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package jwtauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"policy-opa-pdp/cfg"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentKeys_Rotation(t *testing.T) {
+	oldKey := newSigningKey(t, "key-1")
+	jwksFile := setupJwks(t, jwksOf(t, oldKey))
+	loads := 0
+	loadJwksFunc = func(source string) ([]byte, error) {
+		loads++
+		return loadJwks(source)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := Authenticate(context.Background(), oldKey.sign(t, validClaims()))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, loads, "the JWKS is cached")
+
+	// the identity provider publishes a new key and signs with it
+	newKey := newSigningKey(t, "key-2")
+	assert.NoError(t, os.WriteFile(jwksFile, jwksOf(t, oldKey, newKey), 0644))
+
+	// an unknown key id does not reload the JWKS again right after it was loaded
+	_, err := Authenticate(context.Background(), newKey.sign(t, validClaims()))
+	assert.Error(t, err)
+	assert.Equal(t, 1, loads)
+
+	keys.loadedAt = keys.loadedAt.Add(-time.Minute)
+	caller, err := Authenticate(context.Background(), newKey.sign(t, validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, "pep-service", caller.Name)
+	assert.Equal(t, 2, loads)
+
+	// the cached keys stay in use while the JWKS is reloaded and when it cannot be reloaded
+	assert.NoError(t, os.Remove(jwksFile))
+	keys.loadedAt = keys.loadedAt.Add(-time.Hour)
+	_, err = Authenticate(context.Background(), oldKey.sign(t, validClaims()))
+	assert.NoError(t, err)
+}
+
+// a slow JWKS fetch does not block callers served from the cache, and callers waiting for a
+// reload share a single fetch
+func TestCurrentKeys_ReloadOutsideLock(t *testing.T) {
+	key := newSigningKey(t, "key-1")
+	setupJwks(t, jwksOf(t, key))
+	_, err := currentKeys("key-1")
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	var loads atomic.Int32
+	loadJwksFunc = func(source string) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return loadJwks(source)
+	}
+	keysMu.Lock()
+	keys = &keySet{jwks: keys.jwks, kids: keys.kids, loadedAt: keys.loadedAt.Add(-time.Hour)}
+	keysMu.Unlock()
+
+	// the expired JWKS is served while it is reloaded
+	cached, err := currentKeys("key-1")
+	assert.NoError(t, err)
+	assert.True(t, cached.kids["key-1"])
+
+	var waiting sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		waiting.Add(1)
+		go func() {
+			defer waiting.Done()
+			reloaded, err := currentKeys("key-2")
+			assert.NoError(t, err)
+			assert.NotSame(t, cached, reloaded)
+		}()
+	}
+	_, err = currentKeys("key-1")
+	assert.NoError(t, err, "Expected the cached JWKS while the reload is blocked")
+
+	close(release)
+	waiting.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestLoadJwks_URL(t *testing.T) {
+	key := newSigningKey(t, "key-1")
+	setupJwks(t, nil)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		res.Write(jwksOf(t, key))
+	}))
+	defer server.Close()
+	cfg.JwtJwks = server.URL + "/.well-known/jwks.json"
+
+	_, err := Authenticate(context.Background(), key.sign(t, validClaims()))
+	assert.NoError(t, err)
+
+	cfg.JwtJwks = server.URL + "/missing"
+	server.Config.Handler = http.NotFoundHandler()
+	keys = nil
+	_, err = Authenticate(context.Background(), key.sign(t, validClaims()))
+	assert.ErrorContains(t, err, "404")
+}
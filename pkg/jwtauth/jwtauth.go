@@ -0,0 +1,169 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The jwtauth package authenticates callers of the PDP API with JWT bearer tokens. Tokens are
+// verified against the JWKS of the identity provider, which is cached and refreshed when a token
+// is signed with a key it does not know yet.
+package jwtauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/identity"
+	"policy-opa-pdp/pkg/log"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// verifies the signature, issuer, audience, expiry and not-before time of the token
+const verifyQuery = `[valid, header, payload] := io.jwt.decode_verify(input.token, input.constraints)`
+
+var (
+	errInvalidToken = errors.New("token is invalid, expired or not signed by a trusted key")
+	prepareOnce     sync.Once
+	preparedQuery   rego.PreparedEvalQuery
+	prepareErr      error
+)
+
+// Returns true when bearer tokens are accepted, i.e. a JWKS is configured.
+func Enabled() bool {
+	return cfg.JwtJwks != ""
+}
+
+// Checks the bearer token configuration and loads the JWKS, so misconfigurations surface at
+// startup. A JWKS that cannot be loaded yet is only logged, it is fetched again on the first token.
+func Initialize() error {
+	if !Enabled() {
+		return nil
+	}
+	if cfg.JwtIssuer == "" || cfg.JwtAudience == "" {
+		return fmt.Errorf("JWT_ISSUER and JWT_AUDIENCE must be set when JWT_JWKS is configured")
+	}
+	if _, err := currentKeys(""); err != nil {
+		log.Warnf("JWKS could not be loaded from %s: %v", cfg.JwtJwks, err)
+	}
+	return nil
+}
+
+// Verifies the bearer token and returns the caller it identifies. The token must be signed by a
+// key of the JWKS, carry the configured issuer and audience, and must not be expired.
+func Authenticate(ctx context.Context, token string) (identity.Identity, error) {
+	keys, err := currentKeys(tokenKeyId(token))
+	if err != nil {
+		return identity.Identity{}, err
+	}
+	payload, err := verify(ctx, token, keys.jwks)
+	if err != nil {
+		return identity.Identity{}, err
+	}
+	if _, ok := payload["exp"]; !ok {
+		return identity.Identity{}, fmt.Errorf("token has no expiry")
+	}
+	subject, _ := payload["sub"].(string)
+	if subject == "" {
+		return identity.Identity{}, fmt.Errorf("token has no subject")
+	}
+	return identity.Identity{
+		Name:    subject,
+		Subject: cfg.JwtIssuer,
+		Method:  identity.MethodJWT,
+		Roles:   claimRoles(payload, cfg.JwtRolesClaim),
+	}, nil
+}
+
+// evaluates io.jwt.decode_verify and returns the claims of a valid token
+func verify(ctx context.Context, token string, jwks string) (map[string]interface{}, error) {
+	prepareOnce.Do(func() {
+		preparedQuery, prepareErr = rego.New(rego.Query(verifyQuery)).PrepareForEval(context.Background())
+	})
+	if prepareErr != nil {
+		return nil, prepareErr
+	}
+	input := map[string]interface{}{
+		"token": token,
+		"constraints": map[string]interface{}{
+			"cert": jwks,
+			"iss":  cfg.JwtIssuer,
+			"aud":  cfg.JwtAudience,
+		},
+	}
+	results, err := preparedQuery.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, err
+	}
+	// a malformed token makes the builtin fail, which leaves the query undefined
+	if len(results) == 0 {
+		return nil, errInvalidToken
+	}
+	if valid, _ := results[0].Bindings["valid"].(bool); !valid {
+		return nil, errInvalidToken
+	}
+	payload, ok := results[0].Bindings["payload"].(map[string]interface{})
+	if !ok {
+		return nil, errInvalidToken
+	}
+	return payload, nil
+}
+
+// returns the key id of the token header, empty when the header cannot be decoded
+func tokenKeyId(token string) string {
+	encodedHeader, _, _ := strings.Cut(token, ".")
+	rawHeader, err := base64.RawURLEncoding.DecodeString(encodedHeader)
+	if err != nil {
+		return ""
+	}
+	var header struct {
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return ""
+	}
+	return header.Kid
+}
+
+// Returns the roles held by the claim, which may be nested like "realm_access.roles". The claim
+// is either a list of roles or a space separated string, as the OAuth scope claim.
+func claimRoles(claims map[string]interface{}, claimPath string) []string {
+	var value interface{} = claims
+	for _, name := range strings.Split(claimPath, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	var roles []string
+	switch value := value.(type) {
+	case string:
+		roles = strings.Fields(value)
+	case []interface{}:
+		for _, role := range value {
+			if role, ok := role.(string); ok && role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
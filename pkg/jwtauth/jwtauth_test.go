@@ -0,0 +1,195 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/identity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// signingKey is an RSA key of the test identity provider
+type signingKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return signingKey{kid: kid, key: key}
+}
+
+// returns the JWKS publishing the keys
+func jwksOf(t *testing.T, keys ...signingKey) []byte {
+	var jwks []map[string]string
+	for _, k := range keys {
+		jwks = append(jwks, map[string]string{
+			"kty": "RSA",
+			"kid": k.kid,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+		})
+	}
+	raw, err := json.Marshal(map[string]interface{}{"keys": jwks})
+	assert.NoError(t, err)
+	return raw
+}
+
+// returns an RS256 token with the claims
+func (k signingKey) sign(t *testing.T, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": k.kid})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":          "https://idp.example.org",
+		"aud":          "opa-pdp",
+		"sub":          "pep-service",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"realm_access": map[string]interface{}{"roles": []string{"decision", "statistics"}},
+	}
+}
+
+// configures the JWKS file and returns its path, the key cache is reset
+func setupJwks(t *testing.T, jwks []byte) string {
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksFile, jwks, 0644))
+
+	jwtJwks, jwtIssuer, jwtAudience, jwtRolesClaim := cfg.JwtJwks, cfg.JwtIssuer, cfg.JwtAudience, cfg.JwtRolesClaim
+	loadJwks := loadJwksFunc
+	t.Cleanup(func() {
+		// waits for a reload still running in the background
+		keysReloads.Do(cfg.JwtJwks, func() (interface{}, error) { return nil, nil })
+		cfg.JwtJwks, cfg.JwtIssuer, cfg.JwtAudience, cfg.JwtRolesClaim = jwtJwks, jwtIssuer, jwtAudience, jwtRolesClaim
+		loadJwksFunc = loadJwks
+		keys = nil
+	})
+	cfg.JwtJwks = jwksFile
+	cfg.JwtIssuer = "https://idp.example.org"
+	cfg.JwtAudience = "opa-pdp"
+	cfg.JwtRolesClaim = "realm_access.roles"
+	keys = nil
+	return jwksFile
+}
+
+func TestAuthenticate(t *testing.T) {
+	key := newSigningKey(t, "key-1")
+	setupJwks(t, jwksOf(t, key))
+
+	caller, err := Authenticate(context.Background(), key.sign(t, validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, identity.Identity{
+		Name:    "pep-service",
+		Subject: "https://idp.example.org",
+		Method:  identity.MethodJWT,
+		Roles:   []string{"decision", "statistics"},
+	}, caller)
+}
+
+func TestAuthenticate_Invalid(t *testing.T) {
+	key := newSigningKey(t, "key-1")
+	setupJwks(t, jwksOf(t, key))
+	untrusted := newSigningKey(t, "key-1")
+
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"untrusted key", untrusted.sign(t, validClaims())},
+		{"wrong issuer", key.sign(t, with("iss", "https://other.example.org"))},
+		{"wrong audience", key.sign(t, with("aud", "other"))},
+		{"expired", key.sign(t, with("exp", time.Now().Add(-time.Minute).Unix()))},
+		{"not yet valid", key.sign(t, with("nbf", time.Now().Add(time.Hour).Unix()))},
+		{"no expiry", key.sign(t, with("exp", nil))},
+		{"no subject", key.sign(t, with("sub", nil))},
+		{"malformed", "not-a-token"},
+	}
+	for _, tt := range tests {
+		_, err := Authenticate(context.Background(), tt.token)
+		assert.Error(t, err, tt.name)
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	key := newSigningKey(t, "key-1")
+	setupJwks(t, jwksOf(t, key))
+	assert.NoError(t, Initialize())
+	assert.NotNil(t, keys)
+
+	cfg.JwtAudience = ""
+	assert.ErrorContains(t, Initialize(), "JWT_AUDIENCE")
+
+	// a JWKS that cannot be loaded yet does not prevent the startup
+	cfg.JwtAudience = "opa-pdp"
+	cfg.JwtJwks = filepath.Join(t.TempDir(), "missing.json")
+	keys = nil
+	assert.NoError(t, Initialize())
+
+	cfg.JwtJwks = ""
+	assert.False(t, Enabled())
+	assert.NoError(t, Initialize())
+}
+
+func TestClaimRoles(t *testing.T) {
+	claims := map[string]interface{}{
+		"roles":        []interface{}{"admin", "", 42},
+		"scope":        "decision statistics",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"decision"}},
+	}
+	assert.Equal(t, []string{"admin"}, claimRoles(claims, "roles"))
+	assert.Equal(t, []string{"decision", "statistics"}, claimRoles(claims, "scope"))
+	assert.Equal(t, []string{"decision"}, claimRoles(claims, "realm_access.roles"))
+	assert.Nil(t, claimRoles(claims, "resource_access.opa-pdp.roles"))
+	assert.Nil(t, claimRoles(claims, "scope.roles"))
+}
@@ -0,0 +1,51 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// will process the health check message from pap and send the pdp status response.
+package handler
+
+import (
+	"encoding/json"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdphealth"
+)
+
+// Handles messages of type PDP_HEALTH_CHECK sent from the Policy Administration Point (PAP).
+// It evaluates the health of the PDP components and reports it back in a PDP_STATUS.
+func PdpHealthCheckMessageHandler(message []byte, p publisher.PdpStatusSender) error {
+
+	var pdpHealthCheck model.PdpHealthCheck
+	err := json.Unmarshal(message, &pdpHealthCheck)
+	if err != nil {
+		log.Debugf("Failed to UnMarshal Messages: %v\n", err)
+		return malformed(err)
+	}
+
+	log.Debugf("PDP_HEALTH_CHECK Message received: %s", string(message))
+
+	report := pdphealth.Evaluate()
+	err = publisher.SendHealthCheckResponse(p, &pdpHealthCheck, report)
+	if err != nil {
+		log.Debugf("Failed to Send Health Check Response Message: %v\n", err)
+		return err
+	}
+	log.Infof("PDP_STATUS With Health Check Message Sent Successfully, PDP is %s", report.Status)
+	return nil
+}
@@ -0,0 +1,75 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package handler
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdphealth"
+	"testing"
+)
+
+func TestPdpHealthCheckMessageHandler_Healthy(t *testing.T) {
+	pdphealth.Reset()
+	defer pdphealth.Reset()
+	pdphealth.Register("opa", func() error { return nil })
+
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.Healthy == model.Healthy && *pdpStatus.PdpResponse.ResponseTo == "hc-1"
+	})).Return(nil)
+
+	err := PdpHealthCheckMessageHandler([]byte(`{"messageName":"PDP_HEALTH_CHECK","requestId":"hc-1"}`), mockSender)
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+func TestPdpHealthCheckMessageHandler_NotHealthy(t *testing.T) {
+	pdphealth.Reset()
+	defer pdphealth.Reset()
+	pdphealth.Register("bundle", func() error { return errors.New("bundle missing") })
+
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.Healthy == model.NotHealthy
+	})).Return(nil)
+
+	err := PdpHealthCheckMessageHandler([]byte(`{"messageName":"PDP_HEALTH_CHECK","requestId":"hc-2"}`), mockSender)
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+func TestPdpHealthCheckMessageHandler_InvalidJSON(t *testing.T) {
+	mockSender := new(MockPdpStatusSender)
+
+	err := PdpHealthCheckMessageHandler([]byte(`{"requestId":}`), mockSender)
+	assert.Error(t, err)
+	mockSender.AssertNotCalled(t, "SendPdpStatus", mock.Anything)
+}
+
+func TestPdpHealthCheckMessageHandler_SendFailure(t *testing.T) {
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(errors.New("send failed"))
+
+	err := PdpHealthCheckMessageHandler([]byte(`{"messageName":"PDP_HEALTH_CHECK","requestId":"hc-3"}`), mockSender)
+	assert.Error(t, err)
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -25,11 +25,14 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"policy-opa-pdp/consts"
-	"policy-opa-pdp/pkg/kafkacomm"
+	"policy-opa-pdp/pkg/deadletter"
 	"policy-opa-pdp/pkg/kafkacomm/publisher"
 	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/transport"
 	"sync"
 )
 
@@ -52,6 +55,14 @@ func IsShutdown() bool {
 	return shutdownFlag
 }
 
+// the handlers of the PAP message types, the messages of each type are processed by their own pipeline
+var messageHandlers = map[string]func(message []byte, p publisher.PdpStatusSender) error{
+	"PDP_UPDATE":       PdpUpdateMessageHandler,
+	"PDP_STATE_CHANGE": PdpStateChangeMessageHandler,
+	"PDP_HEALTH_CHECK": PdpHealthCheckMessageHandler,
+	"PDP_TOPIC_CHECK":  PdpTopicCheckMessageHandler,
+}
+
 type OpaPdpMessage struct {
 	Name        string `json:"name"`        // Name of the PDP (optional for broadcast messages).
 	MessageType string `json:"MessageName"` // Type of the message (e.g., PDP_UPDATE, PDP_STATE_CHANGE, etc.)
@@ -74,14 +85,15 @@ func checkIfMessageIsForOpaPdp(message OpaPdpMessage) bool {
 		return false
 	}
 
-	if pdpattributes.PdpSubgroup == "" {
-		// this PDP has no assignment yet, thus should ignore broadcast messages
-		//log.Infof(" pdpstate PDP subgroup is empty")
+	if !pdpattributes.IsPdpGroupMember(message.PdpGroup) {
+		//log.Infof(" message pdp group is not one of the pdp groups")
 		return false
 	}
 
-	if message.PdpGroup != consts.PdpGroup {
-		//log.Infof(" message pdp group is not equal to cons pdp group")
+	pdpSubgroup := pdpattributes.GetPdpGroupSubgroup(message.PdpGroup)
+	if pdpSubgroup == "" {
+		// this PDP has no assignment in the group yet, thus should ignore broadcast messages
+		//log.Infof(" pdp subgroup of the message pdp group is empty")
 		return false
 	}
 
@@ -91,71 +103,94 @@ func checkIfMessageIsForOpaPdp(message OpaPdpMessage) bool {
 		return true
 	}
 
-	return message.PdpSubgroup == pdpattributes.PdpSubgroup
+	return message.PdpSubgroup == pdpSubgroup
 }
 
-// Handles incoming Kafka messages, validates their relevance to the current PDP,
-// and dispatches them for further processing based on their type.
-func PdpMessageHandler(ctx context.Context, kc *kafkacomm.KafkaConsumer, topic string, p publisher.PdpStatusSender) error {
+// Handles incoming PAP messages received through the transport. A poll goroutine queues them
+// in the pipeline of their type, which checks their relevance to the current PDP and processes
+// them in order. Broadcasts are checked once the PDP_UPDATE messages received before them are
+// processed. Polling waits while a queue is full. On ctx.Done() polling stops and the handler
+// returns once the queued messages are processed.
+func PdpMessageHandler(ctx context.Context, t transport.Transport, p publisher.PdpStatusSender) error {
 
 	log.Debug("Starting PDP Message Listener.....")
-	var stopConsuming bool
-	for !stopConsuming {
-		select {
-		case <-ctx.Done():
-			log.Debug("Stopping PDP Listener.....")
-			return nil
-			stopConsuming = true ///Loop Exits
-		default:
-			message, err := kafkacomm.ReadKafkaMessages(kc)
-			if err != nil {
-				continue
-			}
-			log.Debugf("[IN|KAFKA|%s]\n%s", topic, string(message))
-
-			if message != nil {
-
-				var opaPdpMessage OpaPdpMessage
-
-				err = json.Unmarshal(message, &opaPdpMessage)
-				if err != nil {
-					log.Warnf("Failed to UnMarshal Messages: %v\n", err)
-					continue
-				}
-
-				if !checkIfMessageIsForOpaPdp(opaPdpMessage) {
-
-					log.Warnf("Not a valid Opa Pdp Message")
-					continue
-				}
-
-				switch opaPdpMessage.MessageType {
+	barrier := newUpdateBarrier()
+	pipelines := make(map[string]*messagePipeline, len(messageHandlers))
+	for messageName, handle := range messageHandlers {
+		pipeline := newMessagePipeline(messageName, handle, consts.MessageQueueSize)
+		pipeline.accept = barrier.accept
+		if messageName == "PDP_UPDATE" {
+			pipeline.processed = barrier.updateProcessed
+		}
+		pipelines[messageName] = pipeline
+	}
 
-				case "PDP_UPDATE":
-					err = PdpUpdateMessageHandler(message, p)
-					if err != nil {
-						log.Warnf("Error processing Update Message: %v", err)
-					}
+	var workers sync.WaitGroup
+	for _, pipeline := range pipelines {
+		workers.Add(1)
+		go func(pipeline *messagePipeline) {
+			defer workers.Done()
+			pipeline.run(p)
+		}(pipeline)
+	}
 
-				case "PDP_STATE_CHANGE":
-					err = PdpStateChangeMessageHandler(message, p)
-					if err != nil {
-						log.Warnf("Error processing Update Message: %v", err)
-					}
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		pollMessages(ctx, t, pipelines, barrier)
+	}()
+	<-polled
 
-				case "PDP_STATUS":
-					log.Debugf("discarding event of type PDP_STATUS")
-					continue
-				default:
-					log.Errorf("This is not a valid Message Type: %s", opaPdpMessage.MessageType)
-					continue
+	for _, pipeline := range pipelines {
+		pipeline.close()
+	}
+	workers.Wait()
+	log.Debug("PDP Listener stopped.....")
+	return nil
+}
 
-				}
+// Receives messages until the context is done or the transport is closed and queues them
+// in the pipeline of their type.
+func pollMessages(ctx context.Context, t transport.Transport, pipelines map[string]*messagePipeline, barrier *updateBarrier) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Stopping PDP Listener.....")
+			return
+		default:
+		}
 
-			}
+		received, err := t.Receive(ctx)
+		if errors.Is(err, transport.ErrClosed) {
+			log.Debug("Transport closed, stopping PDP Listener.....")
+			return
+		}
+		if err != nil || received == nil {
+			continue
+		}
+		var opaPdpMessage OpaPdpMessage
+		err = json.Unmarshal(received.Value, &opaPdpMessage)
+		if err != nil {
+			log.Warnf("Failed to UnMarshal Messages: %v\n", err)
+			deadletter.Publish(received.Value, received.Headers, fmt.Sprintf("invalid PAP message: %v", err))
+			continue
 		}
 
+		if opaPdpMessage.MessageType == "PDP_STATUS" {
+			log.Debugf("discarding event of type PDP_STATUS")
+			continue
+		}
+		pipeline, ok := pipelines[opaPdpMessage.MessageType]
+		if !ok {
+			log.Errorf("This is not a valid Message Type: %s", opaPdpMessage.MessageType)
+			continue
+		}
+		queued := &queuedMessage{Message: received, target: opaPdpMessage, updatesBefore: barrier.updatesReceived()}
+		if !pipeline.enqueue(ctx, queued) {
+			return
+		}
+		if opaPdpMessage.MessageType == "PDP_UPDATE" {
+			barrier.updateReceived()
+		}
 	}
-	return nil
-
 }
@@ -21,6 +21,7 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/stretchr/testify/assert"
@@ -28,7 +29,11 @@ import (
 	"policy-opa-pdp/consts"
 	"policy-opa-pdp/pkg/kafkacomm"
 	"policy-opa-pdp/pkg/kafkacomm/mocks"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/transport"
 	"testing"
 	"time"
 )
@@ -118,7 +123,7 @@ func TestCheckIfMessageIsForOpaPdp_Check_PdpGroup(t *testing.T) {
 	opapdpMessage.PdpGroup = "opaGroup"
 	opapdpMessage.PdpSubgroup = "opa"
 
-	pdpattributes.PdpSubgroup = "opa"
+	pdpattributes.SetPdpSubgroup("opa")
 	assert.True(t, checkIfMessageIsForOpaPdp(opapdpMessage), "Its a valid Opa Pdp Message")
 
 }
@@ -157,7 +162,7 @@ func TestCheckIfMessageIsForOpaPdp_Check_PdpSubgroup(t *testing.T) {
 	opapdpMessage.PdpGroup = "opaGroup"
 	opapdpMessage.PdpSubgroup = "opa"
 
-	pdpattributes.PdpSubgroup = "opa"
+	pdpattributes.SetPdpSubgroup("opa")
 	assert.True(t, checkIfMessageIsForOpaPdp(opapdpMessage), "It's a valid Opa Pdp Message")
 
 }
@@ -177,7 +182,7 @@ func TestCheckIfMessageIsForOpaPdp_Check_IncorrectPdpSubgroup(t *testing.T) {
 	opapdpMessage.PdpGroup = "opaGroup"
 	opapdpMessage.PdpSubgroup = "o"
 
-	pdpattributes.PdpSubgroup = "opa"
+	pdpattributes.SetPdpSubgroup("opa")
 	assert.False(t, checkIfMessageIsForOpaPdp(opapdpMessage), "Not a valid Opa Pdp Message")
 
 }
@@ -199,7 +204,7 @@ func TestCheckIfMessageIsForOpaPdp_ValidBroadcastMessage(t *testing.T) {
 	opapdpMessage.PdpGroup = "opaGroup"
 	opapdpMessage.PdpSubgroup = ""
 
-	pdpattributes.PdpSubgroup = "opa"
+	pdpattributes.SetPdpSubgroup("opa")
 	consts.PdpGroup = "opaGroup"
 
 	assert.True(t, checkIfMessageIsForOpaPdp(opapdpMessage), "Valid broadcast message should pass the check")
@@ -217,6 +222,25 @@ func TestCheckIfMessageIsForOpaPdp_InvalidGroupMismatch(t *testing.T) {
 	assert.False(t, checkIfMessageIsForOpaPdp(opapdpMessage), "Message with mismatched PdpGroup should fail")
 }
 
+func TestCheckIfMessageIsForOpaPdp_AdditionalGroup(t *testing.T) {
+	original := pdpattributes.GetPdpGroups()
+	defer pdpattributes.SetPdpGroups(original)
+	pdpattributes.SetPdpGroups([]string{"opaGroup", "tenantGroup"})
+	pdpattributes.SetPdpGroupSubgroup("opaGroup", "opa")
+
+	message := OpaPdpMessage{MessageType: "PDP_UPDATE", PdpGroup: "tenantGroup"}
+	assert.False(t, checkIfMessageIsForOpaPdp(message), "Broadcast to a group without assignment should be ignored")
+
+	pdpattributes.SetPdpGroupSubgroup("tenantGroup", "opa-tenant")
+	assert.True(t, checkIfMessageIsForOpaPdp(message), "Broadcast to an additional group should pass the check")
+
+	message.PdpSubgroup = "opa-tenant"
+	assert.True(t, checkIfMessageIsForOpaPdp(message))
+
+	message.PdpSubgroup = "opa"
+	assert.False(t, checkIfMessageIsForOpaPdp(message), "Subgroup of another group should not match")
+}
+
 // Test SetShutdownFlag and IsShutdown
 func TestSetAndCheckShutdownFlag(t *testing.T) {
 	assert.False(t, IsShutdown(), "Shutdown flag should be false initially")
@@ -262,7 +286,7 @@ func TestPdpMessageHandler_ValidPDPUpdate(t *testing.T) {
 
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(nil)
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing PDP_UPDATE message")
@@ -270,6 +294,40 @@ func TestPdpMessageHandler_ValidPDPUpdate(t *testing.T) {
 	})
 }
 
+func TestPdpMessageHandler_ValidPdpHealthAndTopicCheck(t *testing.T) {
+	for _, messageName := range []string{"PDP_HEALTH_CHECK", "PDP_TOPIC_CHECK"} {
+		t.Run("Process "+messageName+" Message", func(t *testing.T) {
+			pdpattributes.SetPdpGroupSubgroup("opaGroup", "opa")
+			message := `{
+                "source":"pap-c17b4dbc-3278-483a-ace9-98f3157245c0",
+                "messageName":"` + messageName + `",
+                "requestId":"9a5b0b6e-4c2f-4a43-9d3b-3f1f4c1b2e11",
+                "timestampMs":1730722305297,
+                "name":"",
+                "pdpGroup":"opaGroup",
+                "pdpSubgroup":"opa"
+                 }`
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+			defer cancel()
+
+			mockConsumer := new(mocks.KafkaConsumerInterface)
+			mockConsumer.On("ReadMessage", mock.Anything).Return(&kafka.Message{Value: []byte(message)}, nil)
+			mockKafkaConsumer := &kafkacomm.KafkaConsumer{
+				Consumer: mockConsumer,
+			}
+
+			mockPublisher := new(MockPdpStatusSender)
+			mockPublisher.On("SendPdpStatus", mock.Anything).Return(nil)
+
+			err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
+
+			assert.NoError(t, err)
+			mockPublisher.AssertCalled(t, "SendPdpStatus", mock.Anything)
+		})
+	}
+}
+
 func TestPdpMessageHandler_ValidPdpStateChange(t *testing.T) {
 	t.Run("Process PDP STATE CHANGE Message Handler", func(t *testing.T) {
 		message := `{
@@ -307,7 +365,7 @@ func TestPdpMessageHandler_ValidPdpStateChange(t *testing.T) {
 
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(nil)
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing PDP STATE CHANGE message")
@@ -352,7 +410,7 @@ func TestPdpMessageHandler_DiscardPdpStatus(t *testing.T) {
 
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(nil)
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing PDP_UPDATE message")
@@ -397,7 +455,7 @@ func TestPdpMessageHandler_InvalidMessage(t *testing.T) {
 
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(nil)
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing INVALID PDP message")
@@ -441,7 +499,7 @@ func TestPdpMessageHandler_ContextCancelled(t *testing.T) {
 
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(nil)
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error while testing context cancelled")
@@ -484,7 +542,7 @@ func TestPdpMessageHandler_InvalidOPAPdpmessage(t *testing.T) {
 		mockPublisher := new(MockPdpStatusSender)
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(errors.New("Jsonunmarshal Error"))
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing PDP_UPDATE message")
@@ -527,7 +585,7 @@ func TestPdpMessageHandler_InvalidOPAPdpStateChangemessage(t *testing.T) {
 		mockPublisher := new(MockPdpStatusSender)
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(errors.New("Jsonunmarshal Error"))
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing Invalid OPA PDP STATE CHANGE message")
@@ -570,10 +628,104 @@ func TestPdpMessageHandler_jsonunmarshallOPAPdpStateChangemessage(t *testing.T)
 		mockPublisher := new(MockPdpStatusSender)
 		mockPublisher.On("SendPdpStatus", mock.Anything).Return(errors.New("Jsonunmarshal Error"))
 
-		err := PdpMessageHandler(ctx, mockKafkaConsumer, "test-topic", mockPublisher)
+		err := PdpMessageHandler(ctx, kafkacomm.NewKafkaTransport(mockKafkaConsumer, nil, "test-topic"), mockPublisher)
 
 		assert.NoError(t, err)
 		assert.Nil(t, err, "Expected no error processing Invalid OPA PDP State Change message")
 
 	})
 }
+
+// receives the next PDP_STATUS the PDP sent on the in-memory transport
+func receivePdpStatus(t *testing.T, tr *transport.InMemory) map[string]interface{} {
+	t.Helper()
+	select {
+	case message := <-tr.Sent():
+		var status map[string]interface{}
+		assert.NoError(t, json.Unmarshal(message, &status))
+		return status
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for PDP_STATUS")
+		return nil
+	}
+}
+
+func TestPdpMessageHandler_InMemoryProtocol(t *testing.T) {
+	processedRequests.clear()
+	assert.NoError(t, pdpstate.TransitionTo(model.Passive))
+	tr := transport.NewInMemory(10)
+	sender := &publisher.RealPdpStatusSender{Transport: tr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- PdpMessageHandler(ctx, tr, sender) }()
+
+	// the PDP registers with PAP
+	assert.NoError(t, publisher.SendPdpPapRegistration(sender))
+	registration := receivePdpStatus(t, tr)
+	assert.Equal(t, "PDP_STATUS", registration["messageName"])
+	assert.Equal(t, "PASSIVE", registration["state"])
+
+	// PAP assigns the PDP to a subgroup
+	update := `{
+		"source":"pap-c17b4dbc-3278-483a-ace9-98f3157245c0",
+		"pdpHeartbeatIntervalMs":120000,
+		"policiesToBeDeployed":[],
+		"policiesToBeUndeployed":[],
+		"messageName":"PDP_UPDATE",
+		"requestId":"5d8a1c38-8d52-4c2b-9a0d-0c8e6f3b2a11",
+		"timestampMs":1730722305297,
+		"name":"` + pdpattributes.PdpName + `",
+		"pdpGroup":"opaGroup",
+		"pdpSubgroup":"opa"
+	}`
+	assert.NoError(t, tr.Publish(ctx, []byte(update), nil))
+	updateStatus := receivePdpStatus(t, tr)
+	assert.Equal(t, "opa", updateStatus["pdpSubgroup"])
+	if response, ok := updateStatus["response"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Equal(t, "5d8a1c38-8d52-4c2b-9a0d-0c8e6f3b2a11", response["responseTo"])
+		assert.Equal(t, "SUCCESS", response["responseStatus"])
+	}
+
+	// PAP activates the PDP
+	stateChange := `{
+		"source":"pap-c17b4dbc-3278-483a-ace9-98f3157245c0",
+		"state":"ACTIVE",
+		"messageName":"PDP_STATE_CHANGE",
+		"requestId":"7b2f9e44-1c6d-4e8a-b5f3-2d9c0a7e6b22",
+		"timestampMs":1730722305297,
+		"name":"` + pdpattributes.PdpName + `",
+		"pdpGroup":"opaGroup",
+		"pdpSubgroup":"opa"
+	}`
+	assert.NoError(t, tr.Publish(ctx, []byte(stateChange), nil))
+	stateStatus := receivePdpStatus(t, tr)
+	assert.Equal(t, "ACTIVE", stateStatus["state"])
+	if response, ok := stateStatus["response"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Equal(t, "7b2f9e44-1c6d-4e8a-b5f3-2d9c0a7e6b22", response["responseTo"])
+	}
+	assert.Equal(t, model.Active, pdpstate.GetState())
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("PdpMessageHandler did not stop after the context was cancelled")
+	}
+}
+
+func TestPdpMessageHandler_StopsWhenTransportClosed(t *testing.T) {
+	tr := transport.NewInMemory(1)
+	assert.NoError(t, tr.Close())
+
+	done := make(chan error, 1)
+	go func() { done <- PdpMessageHandler(context.Background(), tr, new(MockPdpStatusSender)) }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("PdpMessageHandler did not stop after the transport was closed")
+	}
+}
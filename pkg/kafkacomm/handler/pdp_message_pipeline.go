@@ -0,0 +1,187 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"policy-opa-pdp/pkg/deadletter"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/metrics"
+	"policy-opa-pdp/pkg/transport"
+	"sync"
+	"time"
+)
+
+// malformedMessageError marks the errors of PAP messages that cannot be processed at all,
+// because they are not valid JSON or fail validation.
+type malformedMessageError struct {
+	err error
+}
+
+func (e *malformedMessageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *malformedMessageError) Unwrap() error {
+	return e.err
+}
+
+// Marks err as caused by a malformed message, the message is dead-lettered.
+func malformed(err error) error {
+	return &malformedMessageError{err: err}
+}
+
+// queuedMessage is a PAP message waiting in a pipeline.
+type queuedMessage struct {
+	*transport.Message
+	target        OpaPdpMessage // the PDP, group and subgroup the message is addressed to
+	updatesBefore uint64        // the PDP_UPDATE messages received before this one
+}
+
+// messagePipeline processes the PAP messages of one type in arrival order, so a slow
+// PDP_UPDATE does not hold back the messages of other types queued behind it.
+type messagePipeline struct {
+	messageName string
+	handle      func(message []byte, p publisher.PdpStatusSender) error
+	queue       chan *queuedMessage
+	accept      func(message *queuedMessage) bool // decides whether to handle a message, nil handles every message
+	processed   func()                            // called after every message taken from the queue, may be nil
+}
+
+// Creates a pipeline for messageName queueing up to size messages.
+func newMessagePipeline(messageName string, handle func([]byte, publisher.PdpStatusSender) error, size int) *messagePipeline {
+	pipeline := &messagePipeline{
+		messageName: messageName,
+		handle:      handle,
+		queue:       make(chan *queuedMessage, size),
+	}
+	pipeline.reportDepth()
+	return pipeline
+}
+
+// Queues a message, waiting while the queue is full. Returns false when the context ended first.
+func (mp *messagePipeline) enqueue(ctx context.Context, message *queuedMessage) bool {
+	select {
+	case mp.queue <- message:
+	default:
+		log.Warnf("%s queue is full, waiting for queued messages to be processed", mp.messageName)
+		select {
+		case mp.queue <- message:
+		case <-ctx.Done():
+			log.Warnf("Discarding %s message queued during shutdown", mp.messageName)
+			return false
+		}
+	}
+	mp.reportDepth()
+	return true
+}
+
+// Processes the queued messages in order until the queue is closed, malformed messages are
+// dead-lettered. Messages queued before the close are still processed.
+func (mp *messagePipeline) run(p publisher.PdpStatusSender) {
+	for message := range mp.queue {
+		mp.reportDepth()
+		mp.process(message, p)
+		if mp.processed != nil {
+			mp.processed()
+		}
+	}
+}
+
+func (mp *messagePipeline) process(message *queuedMessage, p publisher.PdpStatusSender) {
+	if mp.accept != nil && !mp.accept(message) {
+		return
+	}
+	start := time.Now()
+	if err := mp.handle(message.Value, p); err != nil {
+		log.Warnf("Error processing %s Message: %v", mp.messageName, err)
+		var malformedErr *malformedMessageError
+		if errors.As(err, &malformedErr) {
+			deadletter.Publish(message.Value, message.Headers, fmt.Sprintf("invalid %s message: %v", mp.messageName, err))
+		}
+	}
+	metrics.ObserveMessageLatency(mp.messageName, time.Since(start))
+}
+
+// Stops accepting messages, run returns once the queued ones are processed.
+func (mp *messagePipeline) close() {
+	close(mp.queue)
+}
+
+func (mp *messagePipeline) reportDepth() {
+	metrics.SetMessageQueueDepth(mp.messageName, len(mp.queue), cap(mp.queue))
+}
+
+// updateBarrier holds back the messages broadcast to a group or subgroup until the PDP_UPDATE
+// messages received before them are processed, as those decide the subgroup of this PDP and
+// with it whether the broadcast is meant for this PDP.
+type updateBarrier struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	received  uint64
+	processed uint64
+}
+
+func newUpdateBarrier() *updateBarrier {
+	b := &updateBarrier{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Returns the number of PDP_UPDATE messages received so far.
+func (b *updateBarrier) updatesReceived() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.received
+}
+
+// Records a PDP_UPDATE message queued for processing.
+func (b *updateBarrier) updateReceived() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.received++
+}
+
+// Records a PDP_UPDATE message taken from the queue and processed, or discarded.
+func (b *updateBarrier) updateProcessed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.processed++
+	b.cond.Broadcast()
+}
+
+// Reports whether the message is meant for this PDP. Messages addressed to the PDP by name are
+// checked right away, broadcasts once the PDP_UPDATE messages received before them are processed.
+func (b *updateBarrier) accept(message *queuedMessage) bool {
+	if message.target.Name == "" {
+		b.mu.Lock()
+		for b.processed < message.updatesBefore {
+			b.cond.Wait()
+		}
+		b.mu.Unlock()
+	}
+	if !checkIfMessageIsForOpaPdp(message.target) {
+		log.Warnf("Not a valid Opa Pdp Message")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"policy-opa-pdp/pkg/deadletter"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/metrics"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/transport"
+	"sync"
+	"testing"
+	"time"
+)
+
+// returns the statistics of the queue of messageName
+func queueStatistics(messageName string) metrics.MessageQueueStatistics {
+	for _, queue := range metrics.GetMessageQueueStatistics() {
+		if queue.MessageName == messageName {
+			return queue
+		}
+	}
+	return metrics.MessageQueueStatistics{}
+}
+
+func TestMessagePipeline_ProcessesInOrder(t *testing.T) {
+	metrics.ResetMessageQueueStatistics()
+	defer metrics.ResetMessageQueueStatistics()
+
+	var processed []string
+	pipeline := newMessagePipeline("TEST_ORDER", func(message []byte, p publisher.PdpStatusSender) error {
+		processed = append(processed, string(message))
+		return nil
+	}, 5)
+
+	for _, message := range []string{"1", "2", "3"} {
+		assert.True(t, pipeline.enqueue(context.Background(), &queuedMessage{Message: &transport.Message{Value: []byte(message)}}))
+	}
+	assert.Equal(t, 3, queueStatistics("TEST_ORDER").Depth)
+
+	pipeline.close()
+	pipeline.run(new(MockPdpStatusSender))
+
+	assert.Equal(t, []string{"1", "2", "3"}, processed)
+	stats := queueStatistics("TEST_ORDER")
+	assert.Equal(t, 0, stats.Depth)
+	assert.Equal(t, 5, stats.Capacity)
+	assert.Equal(t, int64(3), stats.ProcessedCount)
+}
+
+func TestMessagePipeline_EnqueueWaitsWhileFull(t *testing.T) {
+	pipeline := newMessagePipeline("TEST_FULL", func([]byte, publisher.PdpStatusSender) error { return nil }, 1)
+	assert.True(t, pipeline.enqueue(context.Background(), &queuedMessage{Message: &transport.Message{Value: []byte("1")}}))
+
+	enqueued := make(chan bool, 1)
+	go func() {
+		enqueued <- pipeline.enqueue(context.Background(), &queuedMessage{Message: &transport.Message{Value: []byte("2")}})
+	}()
+	select {
+	case <-enqueued:
+		t.Fatal("Expected enqueue to wait while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, "1", string((<-pipeline.queue).Value))
+	assert.True(t, <-enqueued)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, pipeline.enqueue(ctx, &queuedMessage{Message: &transport.Message{Value: []byte("3")}}), "Expected enqueue to give up when the context is done")
+}
+
+func TestPdpMessageHandler_SlowUpdateDoesNotBlockStateChange(t *testing.T) {
+	original := messageHandlers
+	defer func() { messageHandlers = original }()
+
+	release := make(chan struct{})
+	stateChanged := make(chan struct{})
+	var updates sync.WaitGroup
+	updates.Add(1)
+	messageHandlers = map[string]func([]byte, publisher.PdpStatusSender) error{
+		"PDP_UPDATE": func([]byte, publisher.PdpStatusSender) error {
+			defer updates.Done()
+			<-release
+			return nil
+		},
+		"PDP_STATE_CHANGE": func([]byte, publisher.PdpStatusSender) error {
+			close(stateChanged)
+			return nil
+		},
+	}
+
+	tr := transport.NewInMemory(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- PdpMessageHandler(ctx, tr, new(MockPdpStatusSender)) }()
+
+	name := pdpattributes.PdpName
+	assert.NoError(t, tr.Publish(ctx, []byte(`{"messageName":"PDP_UPDATE","name":"`+name+`"}`), nil))
+	assert.NoError(t, tr.Publish(ctx, []byte(`{"messageName":"PDP_STATE_CHANGE","name":"`+name+`"}`), nil))
+
+	select {
+	case <-stateChanged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("PDP_STATE_CHANGE was held back by the PDP_UPDATE in progress")
+	}
+
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Expected the handler to wait for the PDP_UPDATE in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("PdpMessageHandler did not stop after the context was cancelled")
+	}
+	updates.Wait()
+}
+
+func TestPdpMessageHandler_BroadcastWaitsForEarlierUpdates(t *testing.T) {
+	original := messageHandlers
+	defer func() { messageHandlers = original }()
+	group := pdpattributes.GetPdpGroup()
+	originalSubgroup := pdpattributes.GetPdpGroupSubgroup(group)
+	pdpattributes.SetPdpGroupSubgroup(group, "")
+	defer pdpattributes.SetPdpGroupSubgroup(group, originalSubgroup)
+
+	release := make(chan struct{})
+	stateChanged := make(chan struct{})
+	messageHandlers = map[string]func([]byte, publisher.PdpStatusSender) error{
+		"PDP_UPDATE": func([]byte, publisher.PdpStatusSender) error {
+			<-release
+			pdpattributes.SetPdpGroupSubgroup(group, "opa")
+			return nil
+		},
+		"PDP_STATE_CHANGE": func([]byte, publisher.PdpStatusSender) error {
+			close(stateChanged)
+			return nil
+		},
+	}
+
+	tr := transport.NewInMemory(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- PdpMessageHandler(ctx, tr, new(MockPdpStatusSender)) }()
+
+	name := pdpattributes.PdpName
+	assert.NoError(t, tr.Publish(ctx, []byte(`{"messageName":"PDP_UPDATE","name":"`+name+`"}`), nil))
+	assert.NoError(t, tr.Publish(ctx, []byte(`{"messageName":"PDP_STATE_CHANGE","pdpGroup":"`+group+`","pdpSubgroup":"opa"}`), nil))
+
+	select {
+	case <-stateChanged:
+		t.Fatal("Expected the broadcast to wait for the PDP_UPDATE in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-stateChanged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("PDP_STATE_CHANGE to the subgroup assigned by the earlier PDP_UPDATE was dropped")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestUpdateBarrier_Accept(t *testing.T) {
+	group := pdpattributes.GetPdpGroup()
+	originalSubgroup := pdpattributes.GetPdpGroupSubgroup(group)
+	pdpattributes.SetPdpGroupSubgroup(group, "opa")
+	defer pdpattributes.SetPdpGroupSubgroup(group, originalSubgroup)
+
+	barrier := newUpdateBarrier()
+	assert.True(t, barrier.accept(&queuedMessage{target: OpaPdpMessage{Name: pdpattributes.PdpName}}))
+	assert.False(t, barrier.accept(&queuedMessage{target: OpaPdpMessage{Name: "opa-other"}}))
+	assert.True(t, barrier.accept(&queuedMessage{target: OpaPdpMessage{PdpGroup: group, PdpSubgroup: "opa"}}))
+	assert.False(t, barrier.accept(&queuedMessage{target: OpaPdpMessage{PdpGroup: group, PdpSubgroup: "other"}}))
+
+	barrier.updateReceived()
+	accepted := make(chan bool, 1)
+	go func() {
+		accepted <- barrier.accept(&queuedMessage{target: OpaPdpMessage{PdpGroup: group}, updatesBefore: barrier.updatesReceived()})
+	}()
+	select {
+	case <-accepted:
+		t.Fatal("Expected the broadcast to wait for the PDP_UPDATE received before it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	barrier.updateProcessed()
+	assert.True(t, <-accepted)
+}
+
+type recordingDeadLetterSink struct {
+	mu      sync.Mutex
+	letters []deadletter.Letter
+}
+
+func (r *recordingDeadLetterSink) Write(letter deadletter.Letter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.letters = append(r.letters, letter)
+	return nil
+}
+
+func (r *recordingDeadLetterSink) Letters() []deadletter.Letter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]deadletter.Letter(nil), r.letters...)
+}
+
+func TestMessagePipeline_DeadLettersMalformedMessages(t *testing.T) {
+	sink := &recordingDeadLetterSink{}
+	deadletter.SetSink(sink)
+	defer deadletter.SetSink(nil)
+
+	pipeline := newMessagePipeline("PDP_UPDATE", PdpUpdateMessageHandler, 5)
+	headers := map[string]string{"source": "pap"}
+	pipeline.enqueue(context.Background(), &queuedMessage{Message: &transport.Message{Value: []byte(`{"messageName":"PDP_UPDATE","name":"opa-1"}`), Headers: headers}})
+	pipeline.enqueue(context.Background(), &queuedMessage{Message: &transport.Message{Value: []byte(`{"messageName":"PDP_UPDATE","pdpHeartbeatIntervalMs":"often"}`)}})
+	pipeline.close()
+	pipeline.run(new(MockPdpStatusSender))
+
+	letters := sink.Letters()
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, `{"messageName":"PDP_UPDATE","name":"opa-1"}`, letters[0].Payload)
+		assert.Equal(t, headers, letters[0].Headers)
+		assert.Contains(t, letters[0].Reason, "failed on the 'required' tag")
+		assert.Contains(t, letters[1].Reason, "cannot unmarshal")
+	}
+}
+
+func TestMessagePipeline_DoesNotDeadLetterProcessingFailures(t *testing.T) {
+	sink := &recordingDeadLetterSink{}
+	deadletter.SetSink(sink)
+	defer deadletter.SetSink(nil)
+
+	pipeline := newMessagePipeline("TEST_FAILURE", func([]byte, publisher.PdpStatusSender) error {
+		return errors.New("send failed")
+	}, 1)
+	pipeline.enqueue(context.Background(), &queuedMessage{Message: &transport.Message{Value: []byte("{}")}})
+	pipeline.close()
+	pipeline.run(new(MockPdpStatusSender))
+
+	assert.Empty(t, sink.Letters())
+}
+
+func TestPdpMessageHandler_DeadLettersInvalidJson(t *testing.T) {
+	sink := &recordingDeadLetterSink{}
+	deadletter.SetSink(sink)
+	defer deadletter.SetSink(nil)
+	before := *metrics.DeadLetterCountRef()
+
+	tr := transport.NewInMemory(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- PdpMessageHandler(ctx, tr, new(MockPdpStatusSender)) }()
+
+	assert.NoError(t, tr.Publish(ctx, []byte(`{"messageName":`), map[string]string{"key": "value"}))
+	assert.Eventually(t, func() bool { return len(sink.Letters()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	letter := sink.Letters()[0]
+	assert.Equal(t, `{"messageName":`, letter.Payload)
+	assert.Equal(t, "value", letter.Headers["key"])
+	assert.Contains(t, letter.Reason, "invalid PAP message")
+	assert.Equal(t, before+1, *metrics.DeadLetterCountRef())
+}
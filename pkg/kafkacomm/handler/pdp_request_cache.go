@@ -0,0 +1,126 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// keeps track of the PAP requests already processed by this PDP so that retried
+// PDP_UPDATE and PDP_STATE_CHANGE messages are answered without being applied twice.
+package handler
+
+import (
+	"container/list"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"sync"
+)
+
+// holds the PDP_STATUS sent in response to a processed request
+type processedRequest struct {
+	requestId string
+	response  model.PdpStatus
+}
+
+// bounded cache of processed request ids, the oldest entry is evicted first
+type processedRequestCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+var processedRequests = newProcessedRequestCache(consts.ProcessedRequestCacheSize)
+
+func newProcessedRequestCache(capacity int) *processedRequestCache {
+	return &processedRequestCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// returns the response cached for the request id, if any
+func (c *processedRequestCache) get(requestId string) (model.PdpStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[requestId]
+	if !ok {
+		return model.PdpStatus{}, false
+	}
+	return elem.Value.(*processedRequest).response, true
+}
+
+// stores the response for the request id, evicting the oldest entry when full
+func (c *processedRequestCache) put(requestId string, response model.PdpStatus) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[requestId]; ok {
+		elem.Value.(*processedRequest).response = response
+		return
+	}
+	for c.order.Len() >= c.capacity {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*processedRequest).requestId)
+	}
+	c.entries[requestId] = c.order.PushBack(&processedRequest{requestId: requestId, response: response})
+}
+
+// removes every cached entry
+func (c *processedRequestCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// responseRecorder forwards PDP_STATUS messages to the wrapped sender and remembers
+// the last one so it can be cached against the request it answers.
+type responseRecorder struct {
+	sender   publisher.PdpStatusSender
+	response *model.PdpStatus
+}
+
+func (r *responseRecorder) SendPdpStatus(pdpStatus model.PdpStatus) error {
+	r.response = &pdpStatus
+	return r.sender.SendPdpStatus(pdpStatus)
+}
+
+// Re-sends the cached response when the request id was already processed.
+// Returns true if the request is a duplicate and must not be applied again.
+func resendProcessedResponse(requestId string, p publisher.PdpStatusSender) (bool, error) {
+	if requestId == "" {
+		return false, nil
+	}
+	response, ok := processedRequests.get(requestId)
+	if !ok {
+		return false, nil
+	}
+	log.Infof("Request %s was already processed, re-sending cached PDP_STATUS response", requestId)
+	return true, p.SendPdpStatus(response)
+}
+
+// Caches the response recorded while processing the request id.
+func recordProcessedResponse(requestId string, recorder *responseRecorder) {
+	if requestId == "" || recorder.response == nil {
+		return
+	}
+	processedRequests.put(requestId, *recorder.response)
+}
@@ -0,0 +1,119 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"policy-opa-pdp/pkg/kafkacomm/publisher/mocks"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"testing"
+)
+
+func TestProcessedRequestCache_EvictsOldest(t *testing.T) {
+	cache := newProcessedRequestCache(2)
+	cache.put("req-1", model.PdpStatus{Description: "first"})
+	cache.put("req-2", model.PdpStatus{Description: "second"})
+	cache.put("req-3", model.PdpStatus{Description: "third"})
+
+	_, ok := cache.get("req-1")
+	assert.False(t, ok, "Oldest entry should have been evicted")
+
+	response, ok := cache.get("req-3")
+	assert.True(t, ok)
+	assert.Equal(t, "third", response.Description)
+}
+
+func TestProcessedRequestCache_ZeroCapacity(t *testing.T) {
+	cache := newProcessedRequestCache(0)
+	cache.put("req-1", model.PdpStatus{})
+
+	_, ok := cache.get("req-1")
+	assert.False(t, ok, "Cache with zero capacity should not store entries")
+}
+
+/*
+PdpUpdateMessageHandler_DuplicateRequest
+Description: Test by sending the same PDP_UPDATE twice
+Input: valid input message sent twice with the same requestId
+Expected Output: the second message re-sends the cached response without updating the PDP attributes.
+*/
+func TestPdpUpdateMessageHandler_DuplicateRequest(t *testing.T) {
+	processedRequests.clear()
+	defer processedRequests.clear()
+
+	messageString := `{
+		"source":"pap-c17b4dbc-3278-483a-ace9-98f3157245c0",
+		"pdpHeartbeatIntervalMs":120000,
+		"policiesToBeDeployed":[],
+		"policiesToBeUndeployed":[],
+		"messageName":"PDP_UPDATE",
+		"requestId":"6c1f2d4e-8d36-4b4e-9a0e-1f5b0c0f2a11",
+		"timestampMs":1730722305297,
+		"name":"opa-21cabb3e-f652-4ca6-b498-a77e62fcd059",
+		"pdpGroup":"opaGroup",
+		"pdpSubgroup":"opa"
+	         }`
+
+	var sent []model.PdpStatus
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.Get(0).(model.PdpStatus))
+	}).Return(nil)
+
+	err := PdpUpdateMessageHandler([]byte(messageString), mockSender)
+	assert.NoError(t, err)
+
+	pdpattributes.SetPdpSubgroup("changed")
+	defer pdpattributes.SetPdpSubgroup("opa")
+
+	err = PdpUpdateMessageHandler([]byte(messageString), mockSender)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "changed", pdpattributes.GetPdpSubgroup(), "Duplicate request must not be applied again")
+	assert.Len(t, sent, 2)
+	assert.Equal(t, sent[0], sent[1], "Duplicate request should get the cached response")
+}
+
+/*
+PdpStateChangeMessageHandler_DuplicateRequest
+Description: Test by sending the same PDP_STATE_CHANGE twice
+Input: valid state change message sent twice with the same requestId
+Expected Output: the cached response is sent for the duplicate.
+*/
+func TestPdpStateChangeMessageHandler_DuplicateRequest(t *testing.T) {
+	processedRequests.clear()
+	defer processedRequests.clear()
+
+	message := []byte(`{"state":"ACTIVE","requestId":"0d5f3a57-3b4a-4a34-8a4f-6b7d8d1c2e90"}`)
+
+	var sent []model.PdpStatus
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.Get(0).(model.PdpStatus))
+	}).Return(nil)
+
+	assert.NoError(t, PdpStateChangeMessageHandler(message, mockSender))
+	assert.NoError(t, PdpStateChangeMessageHandler(message, mockSender))
+
+	assert.Len(t, sent, 2)
+	assert.Equal(t, sent[0], sent[1], "Duplicate request should get the cached response")
+}
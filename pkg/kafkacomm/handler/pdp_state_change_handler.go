@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -36,18 +36,31 @@ func PdpStateChangeMessageHandler(message []byte, p publisher.PdpStatusSender) e
 	err := json.Unmarshal(message, &pdpStateChange)
 	if err != nil {
 		log.Debugf("Failed to UnMarshal Messages: %v\n", err)
-		return err
+		return malformed(err)
 	}
 
 	log.Debugf("PDP STATE CHANGE message received: %s", string(message))
 
-	if pdpStateChange.State != "" {
-		pdpstate.SetState(pdpStateChange.State)
+	if duplicate, err := resendProcessedResponse(pdpStateChange.RequestId, p); duplicate {
+		return err
+	}
 
+	recorder := &responseRecorder{sender: p}
+	previousState := pdpstate.GetState()
+	stateErr := pdpstate.SetState(pdpStateChange.State)
+	if stateErr != nil {
+		log.Warnf("Rejecting state change to %q: %v", pdpStateChange.State, stateErr)
+		err = publisher.SendStateChangeFailureResponse(recorder, &pdpStateChange, stateErr.Error())
+		recordProcessedResponse(pdpStateChange.RequestId, recorder)
+		if err != nil {
+			log.Debugf("Failed to Send State Change Failure Response Message: %v\n", err)
+		}
+		return stateErr
 	}
 
-	log.Debugf("State change from PASSIVE To : %s", pdpstate.GetState())
-	err = publisher.SendStateChangeResponse(p, &pdpStateChange)
+	log.Debugf("State change from %s To : %s", previousState, pdpstate.GetState())
+	err = publisher.SendStateChangeResponse(recorder, &pdpStateChange)
+	recordProcessedResponse(pdpStateChange.RequestId, recorder)
 	if err != nil {
 		log.Debugf("Failed to Send State Change Response Message: %v\n", err)
 		return err
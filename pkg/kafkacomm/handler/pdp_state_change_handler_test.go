@@ -112,3 +112,20 @@ func TestPdpStateChangeMessageHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPdpStateChangeMessageHandler_InvalidState(t *testing.T) {
+	processedRequests.clear()
+	defer processedRequests.clear()
+
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(status model.PdpStatus) bool {
+		return status.PdpResponse != nil && *status.PdpResponse.ResponseStatus == model.Failure
+	})).Return(nil)
+
+	stateBefore := pdpstate.GetState()
+	err := PdpStateChangeMessageHandler([]byte(`{"state":"BOGUS","requestId":"c5c0d0de-54a0-4b0f-9a64-3f0a2a1e7d61"}`), mockSender)
+
+	assert.Error(t, err, "Expected an error for an unknown state")
+	assert.Equal(t, stateBefore, pdpstate.GetState(), "State must not change for an unknown state")
+	mockSender.AssertExpectations(t)
+}
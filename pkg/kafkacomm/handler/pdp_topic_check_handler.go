@@ -0,0 +1,49 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// will process the topic check message from pap and send the pdp status response.
+package handler
+
+import (
+	"encoding/json"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+)
+
+// Handles messages of type PDP_TOPIC_CHECK sent from the Policy Administration Point (PAP).
+// It answers with a PDP_STATUS referencing the request so PAP can confirm the topic round-trip.
+func PdpTopicCheckMessageHandler(message []byte, p publisher.PdpStatusSender) error {
+
+	var pdpTopicCheck model.PdpTopicCheck
+	err := json.Unmarshal(message, &pdpTopicCheck)
+	if err != nil {
+		log.Debugf("Failed to UnMarshal Messages: %v\n", err)
+		return malformed(err)
+	}
+
+	log.Debugf("PDP_TOPIC_CHECK Message received: %s", string(message))
+
+	err = publisher.SendTopicCheckResponse(p, &pdpTopicCheck)
+	if err != nil {
+		log.Debugf("Failed to Send Topic Check Response Message: %v\n", err)
+		return err
+	}
+	log.Infof("PDP_STATUS With Topic Check Message Sent Successfully")
+	return nil
+}
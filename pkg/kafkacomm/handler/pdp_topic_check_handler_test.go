@@ -0,0 +1,57 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package handler
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"policy-opa-pdp/pkg/model"
+	"testing"
+)
+
+func TestPdpTopicCheckMessageHandler_Success(t *testing.T) {
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.MessageType == model.PDP_STATUS &&
+			*pdpStatus.PdpResponse.ResponseTo == "tc-1" &&
+			*pdpStatus.PdpResponse.ResponseStatus == model.Success
+	})).Return(nil)
+
+	err := PdpTopicCheckMessageHandler([]byte(`{"messageName":"PDP_TOPIC_CHECK","requestId":"tc-1"}`), mockSender)
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+func TestPdpTopicCheckMessageHandler_InvalidJSON(t *testing.T) {
+	mockSender := new(MockPdpStatusSender)
+
+	err := PdpTopicCheckMessageHandler([]byte(`{"requestId":}`), mockSender)
+	assert.Error(t, err)
+	mockSender.AssertNotCalled(t, "SendPdpStatus", mock.Anything)
+}
+
+func TestPdpTopicCheckMessageHandler_SendFailure(t *testing.T) {
+	mockSender := new(MockPdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(errors.New("send failed"))
+
+	err := PdpTopicCheckMessageHandler([]byte(`{"messageName":"PDP_TOPIC_CHECK","requestId":"tc-2"}`), mockSender)
+	assert.Error(t, err)
+}
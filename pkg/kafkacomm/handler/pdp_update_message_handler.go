@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -24,8 +24,11 @@ import (
 	"github.com/go-playground/validator/v10"
 	"policy-opa-pdp/pkg/kafkacomm/publisher"
 	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/metrics"
 	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/persistence"
+	"policy-opa-pdp/pkg/policyregistry"
 )
 
 // Handles messages of type PDP_UPDATE sent from the Policy Administration Point (PAP).
@@ -36,7 +39,7 @@ func PdpUpdateMessageHandler(message []byte, p publisher.PdpStatusSender) error
 	err := json.Unmarshal(message, &pdpUpdate)
 	if err != nil {
 		log.Debugf("Failed to UnMarshal Messages: %v\n", err)
-		return err
+		return malformed(err)
 	}
 	//Initialize Validator and validate Struct after unmarshalling
 	validate := validator.New()
@@ -46,20 +49,50 @@ func PdpUpdateMessageHandler(message []byte, p publisher.PdpStatusSender) error
 		for _, err := range err.(validator.ValidationErrors) {
 			log.Infof("Field %s failed on the %s tag\n", err.Field(), err.Tag())
 		}
-		return err
+		return malformed(err)
 	}
 
 	log.Debugf("PDP_UPDATE Message received: %s", string(message))
 
-	pdpattributes.SetPdpSubgroup(pdpUpdate.PdpSubgroup)
+	if duplicate, err := resendProcessedResponse(pdpUpdate.RequestId, p); duplicate {
+		return err
+	}
+
+	pdpattributes.SetPdpGroupSubgroup(pdpUpdate.PdpGroup, pdpUpdate.PdpSubgroup)
 	pdpattributes.SetPdpHeartbeatInterval(pdpUpdate.PdpHeartbeatIntervalMs)
+	result := policyregistry.Apply(pdpUpdate.PdpGroup, pdpUpdate.PoliciesToBeDeloyed, pdpUpdate.PoliciesToBeUndeployed)
+	recordPolicyDeployments(result)
+	if err := persistence.SavePdpUpdate(&pdpUpdate, policyregistry.ListGroup(pdpUpdate.PdpGroup)); err != nil {
+		log.Warnf("Failed to persist PDP_UPDATE: %v", err)
+	}
 
-	err = publisher.SendPdpUpdateResponse(p, &pdpUpdate)
+	recorder := &responseRecorder{sender: p}
+	err = publisher.SendPdpUpdateResponse(recorder, &pdpUpdate)
+	recordProcessedResponse(pdpUpdate.RequestId, recorder)
 	if err != nil {
 		log.Debugf("Failed to Send Update Response Message: %v\n", err)
 		return err
 	}
 	log.Infof("PDP_STATUS Message Sent Successfully")
-	go publisher.StartHeartbeatIntervalTimer(pdpattributes.PdpHeartbeatInterval, p)
+	publisher.UpdateHeartbeatInterval(pdpattributes.GetPdpHeartbeatInterval())
 	return nil
 }
+
+// counts the outcome of every policy deployed and undeployed by a PDP_UPDATE
+func recordPolicyDeployments(result policyregistry.ApplyResult) {
+	if result.DeployFailed > 0 || result.UndeployFailed > 0 {
+		log.Warnf("PDP_UPDATE failed to deploy %d and undeploy %d policies", result.DeployFailed, result.UndeployFailed)
+	}
+	for i := 0; i < result.Deployed; i++ {
+		metrics.IncrementDeploySuccessCount()
+	}
+	for i := 0; i < result.DeployFailed; i++ {
+		metrics.IncrementDeployFailureCount()
+	}
+	for i := 0; i < result.Undeployed; i++ {
+		metrics.IncrementUndeploySuccessCount()
+	}
+	for i := 0; i < result.UndeployFailed; i++ {
+		metrics.IncrementUndeployFailureCount()
+	}
+}
@@ -1,5 +1,5 @@
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -23,6 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"policy-opa-pdp/pkg/kafkacomm/publisher/mocks"
+	"policy-opa-pdp/pkg/metrics"
 	"testing"
 )
 
@@ -194,3 +195,38 @@ func TestPdpUpdateMessageHandler_Invalid_Starttimeinterval(t *testing.T) {
 	assert.Error(t, err)
 
 }
+
+/*
+PdpUpdateMessageHandler_Counts_Deployments
+Description: Test that the outcome of every deployed and undeployed policy is counted in the statistics
+Input: valid input message deploying two policies and undeploying one of them and one that is not deployed
+Expected Output: Deploy and undeploy success and failure counters are incremented.
+*/
+func TestPdpUpdateMessageHandler_Counts_Deployments(t *testing.T) {
+	processedRequests.clear()
+	defer processedRequests.clear()
+	deployed, deployFailed := *metrics.DeploySuccessCountRef(), *metrics.DeployFailureCountRef()
+	undeployed, undeployFailed := *metrics.UndeploySuccessCountRef(), *metrics.UndeployFailureCountRef()
+
+	messageString := `{
+		"source":"pap-c17b4dbc-3278-483a-ace9-98f3157245c0",
+		"pdpHeartbeatIntervalMs":120000,
+		"policiesToBeDeployed":["zone:1.0.0", "account:1.0.0"],
+		"policiesToBeUndeployed":[{"name":"account","version":"1.0.0"},{"name":"role","version":"1.0.0"}],
+		"messageName":"PDP_UPDATE",
+		"requestId":"5e2b8f1d-3a4c-4d7e-9f60-2b1c8a7d9e34",
+		"timestampMs":1730722305297,
+		"name":"opa-21cabb3e-f652-4ca6-b498-a77e62fcd059",
+		"pdpGroup":"opaGroup",
+		"pdpSubgroup":"opa"
+	         }`
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(errors.New("Error in Sending PDP Update Response"))
+
+	PdpUpdateMessageHandler([]byte(messageString), mockSender)
+	assert.Equal(t, deployed+2, *metrics.DeploySuccessCountRef())
+	assert.Equal(t, deployFailed, *metrics.DeployFailureCountRef())
+	assert.Equal(t, undeployed+1, *metrics.UndeploySuccessCountRef())
+	assert.Equal(t, undeployFailed+1, *metrics.UndeployFailureCountRef(), "A policy that is not deployed cannot be undeployed")
+}
@@ -78,7 +78,7 @@ func NewKafkaConsumer() (*KafkaConsumer, error) {
 		topic := cfg.Topic
 		useSASL := cfg.UseSASLForKAFKA
 		username := cfg.KAFKA_USERNAME
-		password := cfg.KAFKA_PASSWORD
+		password := cfg.KAFKA_PASSWORD.Value()
 
 		// Add Kafka connection properties
 		configMap := &kafka.ConfigMap{
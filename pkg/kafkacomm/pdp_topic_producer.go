@@ -55,7 +55,7 @@ func GetKafkaProducer(bootstrapServers, topic string) (*KafkaProducer, error) {
 		brokers := cfg.BootstrapServer
 		useSASL := cfg.UseSASLForKAFKA
 		username := cfg.KAFKA_USERNAME
-		password := cfg.KAFKA_PASSWORD
+		password := cfg.KAFKA_PASSWORD.Value()
 
 		// Add Kafka Connection Properties ....
 		configMap := &kafka.ConfigMap{
@@ -0,0 +1,106 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package kafkacomm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/transport"
+	"time"
+)
+
+// the time a Receive call waits for a Kafka message
+const kafkaPollTimeout = 100 * time.Millisecond
+
+// KafkaTransport exchanges PAP messages over a Kafka topic.
+type KafkaTransport struct {
+	consumer *KafkaConsumer
+	producer KafkaProducerInterface
+	topic    string
+}
+
+// NewKafkaTransport creates a transport reading with the consumer and writing with the producer to topic.
+func NewKafkaTransport(consumer *KafkaConsumer, producer KafkaProducerInterface, topic string) *KafkaTransport {
+	return &KafkaTransport{consumer: consumer, producer: producer, topic: topic}
+}
+
+// Receive polls the subscribed topic for the next message, returning nil when the poll timed out.
+// The poll is bounded by the poll timeout, so the context is left to the caller's loop.
+func (t *KafkaTransport) Receive(ctx context.Context) (*transport.Message, error) {
+	if t.consumer == nil || t.consumer.Consumer == nil {
+		return nil, fmt.Errorf("Kafka Consumer is nil so cannot read messages")
+	}
+	msg, err := t.consumer.Consumer.ReadMessage(kafkaPollTimeout)
+	if err != nil {
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrTimedOut {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if msg == nil {
+		return nil, nil
+	}
+	log.Debugf("[IN|KAFKA|%s]\n%s", t.topic, string(msg.Value))
+	headers := make(map[string]string, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	return &transport.Message{Value: msg.Value, Headers: headers}, nil
+}
+
+// Send produces the message to the topic.
+func (t *KafkaTransport) Send(message []byte) error {
+	return t.SendTo(t.topic, message)
+}
+
+// SendTo produces the message to another topic with the same producer, e.g. a dead-letter topic.
+func (t *KafkaTransport) SendTo(topic string, message []byte) error {
+	if t.producer == nil {
+		return fmt.Errorf("Kafka Producer is nil so cannot send messages")
+	}
+	kafkaMessage := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: kafka.PartitionAny,
+		},
+		Value: message,
+	}
+	if err := t.producer.Produce(kafkaMessage, nil); err != nil {
+		return err
+	}
+	log.Debugf("[OUT|KAFKA|%s]\n%s", topic, string(message))
+	return nil
+}
+
+// Close unsubscribes and closes the consumer and closes the producer.
+func (t *KafkaTransport) Close() error {
+	var err error
+	if t.consumer != nil && t.consumer.Consumer != nil {
+		err = t.consumer.Unsubscribe()
+		t.consumer.Close()
+	}
+	if t.producer != nil {
+		t.producer.Close()
+	}
+	return err
+}
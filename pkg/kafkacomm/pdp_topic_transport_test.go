@@ -0,0 +1,105 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package kafkacomm
+
+import (
+	"context"
+	"errors"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"policy-opa-pdp/pkg/kafkacomm/mocks"
+	"testing"
+)
+
+func TestKafkaTransport_Receive(t *testing.T) {
+	mockConsumer := new(mocks.KafkaConsumerInterface)
+	mockConsumer.On("ReadMessage", kafkaPollTimeout).Return(&kafka.Message{
+		Value:   []byte("message"),
+		Headers: []kafka.Header{{Key: "key", Value: []byte("value")}},
+	}, nil)
+	tr := NewKafkaTransport(&KafkaConsumer{Consumer: mockConsumer}, nil, "test-topic")
+
+	message, err := tr.Receive(context.Background())
+	assert.NoError(t, err)
+	if assert.NotNil(t, message) {
+		assert.Equal(t, "message", string(message.Value))
+		assert.Equal(t, "value", message.Headers["key"])
+	}
+}
+
+func TestKafkaTransport_ReceiveTimeout(t *testing.T) {
+	mockConsumer := new(mocks.KafkaConsumerInterface)
+	mockConsumer.On("ReadMessage", kafkaPollTimeout).Return(nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false))
+	tr := NewKafkaTransport(&KafkaConsumer{Consumer: mockConsumer}, nil, "test-topic")
+
+	message, err := tr.Receive(context.Background())
+	assert.NoError(t, err, "A poll timeout should not be reported as an error")
+	assert.Nil(t, message)
+}
+
+func TestKafkaTransport_ReceiveError(t *testing.T) {
+	mockConsumer := new(mocks.KafkaConsumerInterface)
+	mockConsumer.On("ReadMessage", kafkaPollTimeout).Return(nil, errors.New("read error"))
+	tr := NewKafkaTransport(&KafkaConsumer{Consumer: mockConsumer}, nil, "test-topic")
+
+	_, err := tr.Receive(context.Background())
+	assert.EqualError(t, err, "read error")
+
+	_, err = NewKafkaTransport(nil, nil, "test-topic").Receive(context.Background())
+	assert.Error(t, err, "Expected an error without a consumer")
+}
+
+func TestKafkaTransport_Send(t *testing.T) {
+	mockProducer := new(mocks.KafkaProducerInterface)
+	mockProducer.On("Produce", mock.MatchedBy(func(message *kafka.Message) bool {
+		return *message.TopicPartition.Topic == "test-topic" && string(message.Value) == "status"
+	}), mock.Anything).Return(nil).Once()
+	tr := NewKafkaTransport(nil, mockProducer, "test-topic")
+
+	assert.NoError(t, tr.Send([]byte("status")))
+	mockProducer.AssertExpectations(t)
+
+	assert.Error(t, NewKafkaTransport(nil, nil, "test-topic").Send([]byte("status")), "Expected an error without a producer")
+}
+
+func TestKafkaTransport_SendTo(t *testing.T) {
+	mockProducer := new(mocks.KafkaProducerInterface)
+	mockProducer.On("Produce", mock.MatchedBy(func(message *kafka.Message) bool {
+		return *message.TopicPartition.Topic == "dead-letter-topic" && string(message.Value) == "letter"
+	}), mock.Anything).Return(nil).Once()
+	tr := NewKafkaTransport(nil, mockProducer, "test-topic")
+
+	assert.NoError(t, tr.SendTo("dead-letter-topic", []byte("letter")))
+	mockProducer.AssertExpectations(t)
+}
+
+func TestKafkaTransport_Close(t *testing.T) {
+	mockConsumer := new(mocks.KafkaConsumerInterface)
+	mockConsumer.On("Unsubscribe").Return(nil)
+	mockConsumer.On("Close").Return(nil)
+	mockProducer := new(mocks.KafkaProducerInterface)
+	mockProducer.On("Close").Return()
+	tr := NewKafkaTransport(&KafkaConsumer{Consumer: mockConsumer}, mockProducer, "test-topic")
+
+	assert.NoError(t, tr.Close())
+	mockConsumer.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+}
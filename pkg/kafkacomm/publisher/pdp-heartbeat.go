@@ -18,77 +18,199 @@
 
 // The publisher package is responsible for managing periodic heartbeat messages for the
 // Open Policy Agent (OPA) Policy Decision Point (PDP) and publishing the PDP's status to relevant channels.
-// It provides functions to initialize, manage, and stop timers for sending heartbeat messages,
-// ensuring the PDP communicates its health and state periodically.
+// The Heartbeater runs a single sender loop that sends a heartbeat every interval, optionally with
+// jitter, until its context is cancelled, ensuring the PDP communicates its health and state periodically.
 package publisher
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
+	"math/rand"
 	"policy-opa-pdp/consts"
 	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/metrics"
 	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdphealth"
 	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
 	"sync"
 	"time"
 )
 
+// Heartbeater sends PDP heartbeats to PAP. At most one sender loop runs at a time, the interval
+// can be changed while it runs and a non-positive interval pauses the heartbeats.
+type Heartbeater struct {
+	sender        PdpStatusSender
+	jitterPercent int                         // the random deviation of each interval, in percent
+	send          func(PdpStatusSender) error // sends a single heartbeat
+	random        func() float64              // returns a number in [0.0, 1.0)
+
+	mu       sync.Mutex
+	interval time.Duration
+	updates  chan time.Duration // wakes the sender loop when the interval changes
+	cancel   context.CancelFunc
+	done     chan struct{} // closed when the sender loop exits
+}
+
 var (
-	ticker          *time.Ticker
-	stopChan        chan bool
-	currentInterval int64
-	mu              sync.Mutex
+	defaultHeartbeater *Heartbeater
+	heartbeaterMu      sync.Mutex
 )
 
-// Initializes a timer that sends periodic heartbeat messages to indicate the health and state of the PDP.
-func StartHeartbeatIntervalTimer(intervalMs int64, s PdpStatusSender) {
+// Creates a Heartbeater sending heartbeats through the sender. Each interval is moved by a random
+// amount of up to jitterPercent of the interval, jitterPercent is limited to the range 0 to 50.
+func NewHeartbeater(sender PdpStatusSender, jitterPercent int) *Heartbeater {
+	if jitterPercent < 0 || jitterPercent > 50 {
+		log.Warnf("Invalid heartbeat jitter %d%%, it must be between 0 and 50", jitterPercent)
+		jitterPercent = min(max(jitterPercent, 0), 50)
+	}
+	return &Heartbeater{
+		sender:        sender,
+		jitterPercent: jitterPercent,
+		send:          sendPDPHeartBeat,
+		random:        rand.Float64,
+		updates:       make(chan time.Duration, 1),
+	}
+}
+
+// Starts the sender loop, which runs until the context is cancelled or Stop is called.
+// Calling Start while the loop is running has no effect.
+func (h *Heartbeater) Start(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done != nil {
+		log.Debug("Heartbeat is already running")
+		return
+	}
+	ctx, h.cancel = context.WithCancel(ctx)
+	h.done = make(chan struct{})
+	go h.run(ctx, h.interval, h.done)
+}
+
+// Changes the heartbeat interval, the next heartbeat is scheduled with the new interval.
+// A non-positive interval pauses the heartbeats until a valid interval is set.
+func (h *Heartbeater) UpdateInterval(intervalMs int64) {
+	interval := time.Duration(intervalMs) * time.Millisecond
 	if intervalMs <= 0 {
 		log.Errorf("Invalid interval provided: %d. Interval must be greater than zero.", intervalMs)
-		ticker = nil
+		interval = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if interval == h.interval {
+		log.Debug("Heartbeat interval unchanged")
 		return
 	}
-	mu.Lock()
-	defer mu.Unlock()
+	h.interval = interval
+	log.Debugf("Heartbeat interval set to %s", interval)
+	// keep only the latest interval for the sender loop
+	select {
+	case <-h.updates:
+	default:
+	}
+	h.updates <- interval
+}
 
-	if ticker != nil && intervalMs == currentInterval {
-		log.Debug("Ticker is already running")
+// Returns the current heartbeat interval.
+func (h *Heartbeater) Interval() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.interval
+}
+
+// Reports whether the sender loop is running.
+func (h *Heartbeater) IsRunning() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.done != nil
+}
+
+// Stops the sender loop and waits for it to exit. The Heartbeater can be started again afterwards.
+func (h *Heartbeater) Stop() {
+	h.mu.Lock()
+	cancel, done := h.cancel, h.done
+	h.mu.Unlock()
+	if cancel == nil {
+		log.Debugf("Heartbeat is not Running")
 		return
 	}
+	cancel()
+	<-done
+}
 
-	if ticker != nil {
-		ticker.Stop()
-	}
-	// StopTicker()
-	currentInterval = intervalMs
-
-	ticker = time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
-	log.Debugf("New Ticker %d", currentInterval)
-	stopChan = make(chan bool)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				sendPDPHeartBeat(s)
-			case <-stopChan:
-				ticker.Stop()
-				return
-			}
+// the sender loop, a nil timer channel blocks forever and pauses the heartbeats
+func (h *Heartbeater) run(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer func() {
+		h.mu.Lock()
+		if h.done == done {
+			h.cancel = nil
+			h.done = nil
 		}
+		h.mu.Unlock()
+		close(done)
 	}()
+
+	var timer *time.Timer
+	var tick <-chan time.Time
+	schedule := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, tick = nil, nil
+		if interval > 0 {
+			timer = time.NewTimer(h.nextDelay(interval))
+			tick = timer.C
+		}
+	}
+	schedule()
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Stopping heartbeat")
+			return
+		case interval = <-h.updates:
+			schedule()
+		case <-tick:
+			h.send(h.sender)
+			schedule()
+		}
+	}
 }
 
-// Creates and sends a heartbeat message with the PDP's current state, health, and attributes
+// returns the interval moved by a random amount of up to the configured jitter
+func (h *Heartbeater) nextDelay(interval time.Duration) time.Duration {
+	if h.jitterPercent == 0 {
+		return interval
+	}
+	maxJitter := float64(interval) * float64(h.jitterPercent) / 100
+	return interval + time.Duration((2*h.random()-1)*maxJitter)
+}
+
+// Creates and sends a heartbeat message with the PDP's current state, health, deployed policies,
+// statistics and attributes
 func sendPDPHeartBeat(s PdpStatusSender) error {
+	pdpGroup := pdpattributes.GetPdpGroup()
+	pdpSubgroup := pdpattributes.GetPdpGroupSubgroup(pdpGroup)
 	pdpStatus := model.PdpStatus{
-		MessageType: model.PDP_STATUS,
-		PdpType:     consts.PdpType,
-		State:       pdpstate.GetState(),
-		Healthy:     model.Healthy,
-		Name:        pdpattributes.PdpName,
-		Description: "Pdp heartbeat",
-		PdpGroup:    consts.PdpGroup,
-		PdpSubgroup: &pdpattributes.PdpSubgroup,
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                pdphealth.Evaluate().Status,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp heartbeat",
+		PdpGroup:               pdpGroup,
+		PdpSubgroup:            &pdpSubgroup,
+		Policies:               policyregistry.ListGroup(pdpGroup),
+		Statistics:             buildPdpStatistics(pdpGroup, pdpSubgroup),
 	}
 	pdpStatus.RequestID = uuid.New().String()
 	pdpStatus.TimestampMs = fmt.Sprintf("%d", time.Now().UnixMilli())
@@ -103,16 +225,60 @@ func sendPDPHeartBeat(s PdpStatusSender) error {
 	}
 }
 
-// Stops the running ticker and terminates the goroutine managing heartbeat messages.
-func StopTicker() {
-	mu.Lock()
-	defer mu.Unlock()
-	if ticker != nil && stopChan != nil {
-		stopChan <- true
-		close(stopChan)
-		ticker = nil
-		stopChan = nil
-	} else {
-		log.Debugf("Ticker is not Running")
+// Builds the statistics reported in heartbeats from a snapshot of the decision and deployment counters.
+func buildPdpStatistics(pdpGroup string, pdpSubgroup string) *model.PdpStatistics {
+	counters := metrics.GetStatistics()
+	executedSuccess := counters.PermitDecisionsCount + counters.DenyDecisionsCount
+	// indeterminate decisions and evaluations that failed, requests refused before evaluation are not executions
+	executedFail := counters.IndeterminantDecisionsCount + counters.EvaluationErrorCount
+	return &model.PdpStatistics{
+		PdpInstanceId:              pdpattributes.PdpName,
+		TimeStamp:                  time.Now().UTC().Format(time.RFC3339Nano),
+		PdpGroupName:               pdpGroup,
+		PdpSubGroupName:            pdpSubgroup,
+		PolicyDeployCount:          counters.DeploySuccessCount + counters.DeployFailureCount,
+		PolicyDeploySuccessCount:   counters.DeploySuccessCount,
+		PolicyDeployFailCount:      counters.DeployFailureCount,
+		PolicyUndeployCount:        counters.UndeploySuccessCount + counters.UndeployFailureCount,
+		PolicyUndeploySuccessCount: counters.UndeploySuccessCount,
+		PolicyUndeployFailCount:    counters.UndeployFailureCount,
+		PolicyExecutedCount:        executedSuccess + executedFail,
+		PolicyExecutedSuccessCount: executedSuccess,
+		PolicyExecutedFailCount:    executedFail,
+	}
+}
+
+// Sets the Heartbeater driven by PAP messages, replacing and stopping the previous one.
+func SetDefaultHeartbeater(h *Heartbeater) {
+	heartbeaterMu.Lock()
+	previous := defaultHeartbeater
+	defaultHeartbeater = h
+	heartbeaterMu.Unlock()
+	if previous != nil && previous != h {
+		previous.Stop()
+	}
+}
+
+// Changes the interval of the default Heartbeater, as requested by PAP in a PDP_UPDATE.
+func UpdateHeartbeatInterval(intervalMs int64) {
+	heartbeaterMu.Lock()
+	h := defaultHeartbeater
+	heartbeaterMu.Unlock()
+	if h == nil {
+		log.Debugf("No heartbeat configured, ignoring interval %d", intervalMs)
+		return
+	}
+	h.UpdateInterval(intervalMs)
+}
+
+// Stops the default Heartbeater, e.g. when PAP terminates the PDP.
+func StopHeartbeat() {
+	heartbeaterMu.Lock()
+	h := defaultHeartbeater
+	heartbeaterMu.Unlock()
+	if h == nil {
+		log.Debugf("Heartbeat is not Running")
+		return
 	}
+	h.Stop()
 }
@@ -20,57 +20,188 @@
 package publisher
 
 import (
+	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"policy-opa-pdp/pkg/kafkacomm/publisher/mocks"
+	"policy-opa-pdp/pkg/metrics"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdphealth"
+	"policy-opa-pdp/pkg/policyregistry"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 	)
 
 
+// returns a Heartbeater whose heartbeats are counted instead of sent
+func newCountingHeartbeater(jitterPercent int) (*Heartbeater, *atomic.Int64) {
+	var sent atomic.Int64
+	h := NewHeartbeater(new(mocks.PdpStatusSender), jitterPercent)
+	h.send = func(PdpStatusSender) error {
+		sent.Add(1)
+		return nil
+	}
+	return h, &sent
+}
+
 /*
-Success Case 1
-TestStartHeartbeatIntervalTimer_ValidInterval
-Description: Test starting the heartbeat interval timer with a valid interval.
-Input: intervalMs = 1000
-Expected Output: The ticker starts with an interval of 1000 milliseconds, and heartbeat messages are sent at this interval.
+TestHeartbeater_SendsUntilContextCancelled
+Description: Test that heartbeats are sent every interval until the context is cancelled.
+Input: interval = 10ms
+Expected Output: Heartbeats are sent while running, the loop exits on cancellation and no more heartbeats are sent.
 */
-func TestStartHeartbeatIntervalTimer_ValidInterval(t *testing.T) {
+func TestHeartbeater_SendsUntilContextCancelled(t *testing.T) {
+	h, sent := newCountingHeartbeater(0)
+	h.UpdateInterval(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	h.Start(ctx)
 
-	intervalMs := int64(1000)
-	mockSender := new(mocks.PdpStatusSender)
-	mockSender.On("SendPdpStatus", mock.Anything).Return(nil)
+	assert.Eventually(t, func() bool { return sent.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	assert.Eventually(t, func() bool { return !h.IsRunning() }, time.Second, time.Millisecond)
+
+	stopped := sent.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, stopped, sent.Load(), "Expected no heartbeats after cancellation")
+}
 
-	StartHeartbeatIntervalTimer(intervalMs, mockSender)
-	mu.Lock()
-	defer mu.Unlock()
-	if ticker == nil {
-		t.Errorf("Expected ticker to be initialized")
+/*
+TestHeartbeater_SingleSenderLoop
+Description: Test that starting a running Heartbeater does not start a second sender loop.
+Input: Start called several times concurrently
+Expected Output: Heartbeats are never sent concurrently.
+*/
+func TestHeartbeater_SingleSenderLoop(t *testing.T) {
+	var active, maxActive, sent atomic.Int64
+	h := NewHeartbeater(new(mocks.PdpStatusSender), 0)
+	h.send = func(PdpStatusSender) error {
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		active.Add(-1)
+		sent.Add(1)
+		return nil
 	}
-	if currentInterval != intervalMs {
-		t.Errorf("Expected currentInterval to be %d, got %d", intervalMs, currentInterval)
+	h.UpdateInterval(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Start(ctx)
+		}()
 	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool { return sent.Load() >= 5 }, time.Second, time.Millisecond)
+	h.Stop()
+	assert.Equal(t, int64(1), maxActive.Load(), "Expected exactly one sender loop")
 }
 
 /*
-Failure Case 1
-TestStartHeartbeatIntervalTimer_InvalidInterval
-Description: Test starting the heartbeat interval timer with an invalid interval.
-Input: intervalMs = -1000
-Expected Output: The function should handle the invalid interval gracefully, possibly by logging an error message and not starting the ticker.
+TestHeartbeater_UpdateInterval
+Description: Test changing the interval of a running Heartbeater.
+Input: no interval, then 10ms, then an invalid interval
+Expected Output: Heartbeats are paused without a valid interval and sent once one is set.
 */
-func TestStartHeartbeatIntervalTimer_InvalidInterval(t *testing.T) {
-	intervalMs := int64(-1000)
-	mockSender := new(mocks.PdpStatusSender)
-	mockSender.On("SendPdpStatus", mock.Anything).Return(nil)
+func TestHeartbeater_UpdateInterval(t *testing.T) {
+	h, sent := newCountingHeartbeater(0)
+	h.Start(context.Background())
+	defer h.Stop()
 
-	StartHeartbeatIntervalTimer(intervalMs, mockSender)
-	mu.Lock()
-	defer mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int64(0), sent.Load(), "Expected no heartbeats without an interval")
 
-	if ticker != nil {
-		t.Log("Expected ticker to be nil for invalid interval")
-	}
+	h.UpdateInterval(10)
+	assert.Equal(t, 10*time.Millisecond, h.Interval())
+	assert.Eventually(t, func() bool { return sent.Load() >= 2 }, time.Second, time.Millisecond)
+
+	h.UpdateInterval(-1000)
+	assert.Equal(t, time.Duration(0), h.Interval())
+	time.Sleep(20 * time.Millisecond)
+	paused := sent.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, paused, sent.Load(), "Expected heartbeats to pause for an invalid interval")
+}
+
+/*
+TestHeartbeater_Restart
+Description: Test that a stopped Heartbeater can be started again.
+Input: Start, Stop, Start
+Expected Output: Heartbeats are sent again after the restart.
+*/
+func TestHeartbeater_Restart(t *testing.T) {
+	h, sent := newCountingHeartbeater(0)
+	h.UpdateInterval(10)
+	h.Start(context.Background())
+	h.Stop()
+	assert.False(t, h.IsRunning())
+
+	h.Start(context.Background())
+	defer h.Stop()
+	assert.True(t, h.IsRunning())
+	assert.Eventually(t, func() bool { return sent.Load() >= 1 }, time.Second, time.Millisecond)
+}
+
+/*
+TestHeartbeater_Jitter
+Description: Test that each interval is moved by up to the configured jitter.
+Input: jitter of 10% with the lowest and highest random values, and invalid jitter values
+Expected Output: The delay stays within 10% of the interval and invalid jitter is limited.
+*/
+func TestHeartbeater_Jitter(t *testing.T) {
+	h, _ := newCountingHeartbeater(10)
+	h.random = func() float64 { return 0 }
+	assert.Equal(t, 900*time.Millisecond, h.nextDelay(time.Second))
+	h.random = func() float64 { return 0.5 }
+	assert.Equal(t, time.Second, h.nextDelay(time.Second))
+	h.random = func() float64 { return 0.99 }
+	assert.InDelta(t, float64(1098*time.Millisecond), float64(h.nextDelay(time.Second)), float64(time.Millisecond))
+
+	noJitter, _ := newCountingHeartbeater(0)
+	assert.Equal(t, time.Second, noJitter.nextDelay(time.Second))
+
+	assert.Equal(t, 50, NewHeartbeater(nil, 80).jitterPercent)
+	assert.Equal(t, 0, NewHeartbeater(nil, -5).jitterPercent)
+}
+
+/*
+TestDefaultHeartbeater
+Description: Test the default Heartbeater driven by PAP messages.
+Input: no default, then a running default that is replaced
+Expected Output: Interval updates are forwarded to the default and replacing it stops the previous one.
+*/
+func TestDefaultHeartbeater(t *testing.T) {
+	SetDefaultHeartbeater(nil)
+	UpdateHeartbeatInterval(1000)
+	StopHeartbeat()
+
+	first, _ := newCountingHeartbeater(0)
+	first.Start(context.Background())
+	SetDefaultHeartbeater(first)
+	UpdateHeartbeatInterval(60000)
+	assert.Equal(t, time.Minute, first.Interval())
+
+	second, _ := newCountingHeartbeater(0)
+	SetDefaultHeartbeater(second)
+	assert.False(t, first.IsRunning(), "Expected the replaced Heartbeater to be stopped")
+
+	second.Start(context.Background())
+	StopHeartbeat()
+	assert.False(t, second.IsRunning())
+	SetDefaultHeartbeater(nil)
 }
 
 /*
@@ -102,65 +233,49 @@ func TestSendPDPHeartBeat_Failure(t *testing.T) {
 }
 
 /*
-TestStopTicker_Success 3
-Description: Test stopping the ticker.
-Input: Ticker is running
-Expected Output: The ticker stops, and the stop channel is closed.
-*/
-func TestStopTicker_Success(t *testing.T) {
-	mockSender := new(mocks.PdpStatusSender)
-	mockSender.On("SendPdpStatus", mock.Anything).Return(nil)
-	StartHeartbeatIntervalTimer(1000, mockSender)
-
-	StopTicker()
-	mu.Lock()
-	defer mu.Unlock()
-	if ticker != nil {
-		t.Errorf("Expected ticker to be nil")
-	}
-}
-
-/*
-TestStopTicker_NotRunning 3
-Description: Test stopping the ticker when it is not running.
-Input: Ticker is not running
-Expected Output: The function should handle this case gracefully, possibly by logging a debug message indicating that the ticker is not running.
+TestSendPDPHeartBeat_Payload
+Description: Test that the heartbeat carries the evaluated health, the deployed policies and statistics.
+Input: A failing component health check, a deployed policy and decision and deployment counters
+Expected Output: The heartbeat reports NOT_HEALTHY, the policy of the primary group and a statistics snapshot.
 */
-func TestStopTicker_NotRunning(t *testing.T) {
-	StopTicker()
-	mu.Lock()
-	defer mu.Unlock()
-}
-
-func TestStartHeartbeatIntervalTimer_TickerAlreadyRunning(t *testing.T) {
-	intervalMs := int64(1000)
+func TestSendPDPHeartBeat_Payload(t *testing.T) {
+	pdphealth.Reset()
+	defer pdphealth.Reset()
+	pdphealth.Register("bundle", func() error { return errors.New("bundle missing") })
+	policyregistry.Clear()
+	defer policyregistry.Clear()
+	policyregistry.Set(pdpattributes.GetPdpGroup(), []model.ToscaConceptIdentifier{{Name: "zone", Version: "1.0.0"}})
+	metrics.PermitDecisionsCount = 3
+	metrics.DenyDecisionsCount = 2
+	metrics.IndeterminantDecisionsCount = 1
+	metrics.EvaluationErrorCount = 2
+	metrics.TotalErrorCount = 7
+	metrics.RateLimitedCount = 3
+	metrics.DeploySuccessCount = 4
+	metrics.DeployFailureCount = 1
+	metrics.UndeploySuccessCount = 2
+	metrics.UndeployFailureCount = 3
 
+	var sent model.PdpStatus
 	mockSender := new(mocks.PdpStatusSender)
-	mockSender.On("SendPdpStatus", mock.Anything).Return(nil)
-
-	// Start the ticker for the first time
-	StartHeartbeatIntervalTimer(intervalMs, mockSender)
-
-	StartHeartbeatIntervalTimer(intervalMs, mockSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(model.PdpStatus)
+	}).Return(nil)
 
-	if currentInterval != intervalMs {
-		t.Errorf("Expected ticker to not restart, currentInterval is %d, expected %d", currentInterval, intervalMs)
+	assert.NoError(t, sendPDPHeartBeat(mockSender))
+	assert.Equal(t, model.NotHealthy, sent.Healthy)
+	assert.Equal(t, []model.ToscaConceptIdentifier{{Name: "zone", Version: "1.0.0"}}, sent.Policies)
+	if assert.NotNil(t, sent.Statistics) {
+		assert.Equal(t, pdpattributes.PdpName, sent.Statistics.PdpInstanceId)
+		assert.Equal(t, pdpattributes.GetPdpGroup(), sent.Statistics.PdpGroupName)
+		assert.Equal(t, int64(8), sent.Statistics.PolicyExecutedCount)
+		assert.Equal(t, int64(5), sent.Statistics.PolicyExecutedSuccessCount)
+		assert.Equal(t, int64(3), sent.Statistics.PolicyExecutedFailCount, "Evaluation errors count as failed executions, refused requests do not")
+		assert.Equal(t, int64(5), sent.Statistics.PolicyDeployCount)
+		assert.Equal(t, int64(4), sent.Statistics.PolicyDeploySuccessCount)
+		assert.Equal(t, int64(1), sent.Statistics.PolicyDeployFailCount)
+		assert.Equal(t, int64(5), sent.Statistics.PolicyUndeployCount)
+		assert.Equal(t, int64(2), sent.Statistics.PolicyUndeploySuccessCount)
+		assert.Equal(t, int64(3), sent.Statistics.PolicyUndeployFailCount)
 	}
-
-	assert.NotNil(t, ticker, "Expected ticker to be running but it is nil")
-}
-
-func TestStartHeartbeatIntervalTimer_TickerAlreadyRunning_Case2(t *testing.T) {
-	intervalMs := int64(1000)
-
-	mockSender := new(mocks.PdpStatusSender)
-	mockSender.On("SendPdpStatus", mock.Anything).Return(nil)
-
-	// Start the ticker for the first time
-	StartHeartbeatIntervalTimer(intervalMs, mockSender)
-
-	// Start it again
-	StartHeartbeatIntervalTimer(int64(201), mockSender)
-
-	assert.NotNil(t, ticker, "Expected ticker to be running but it is nil")
 }
@@ -22,14 +22,15 @@ package publisher
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/google/uuid"
-	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
-	"policy-opa-pdp/pkg/kafkacomm"
 	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdphealth"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
+	"policy-opa-pdp/pkg/transport"
 	"time"
 )
 
@@ -38,15 +39,12 @@ type PdpStatusSender interface {
 }
 
 type RealPdpStatusSender struct {
-	Producer kafkacomm.KafkaProducerInterface
+	Transport transport.Transport
 }
 
-// Sends PdpSTatus Message type to KafkaTopic
+// Sends PdpSTatus Message type to PAP through the transport
 func (s *RealPdpStatusSender) SendPdpStatus(pdpStatus model.PdpStatus) error {
 
-	var topic string
-	//	bootstrapServers := cfg.BootstrapServer
-	topic = cfg.Topic
 	pdpStatus.RequestID = uuid.New().String()
 	pdpStatus.TimestampMs = fmt.Sprintf("%d", time.Now().UnixMilli())
 
@@ -55,46 +53,37 @@ func (s *RealPdpStatusSender) SendPdpStatus(pdpStatus model.PdpStatus) error {
 		log.Warnf("failed to marshal PdpStatus to JSON: %v", err)
 		return err
 	}
-	/*	producer, err := kafkacomm.GetKafkaProducer(bootstrapServers, topic)
-		if err != nil {
-			log.Warnf("Error creating Kafka producer: %v\n", err)
-			return err
-		}*/
-	//	s.Producer = producer
-	log.Debugf("Producer saved in RealPdp StatusSender")
-
-	kafkaMessage := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &topic,
-			Partition: kafka.PartitionAny,
-		},
-		Value: jsonMessage,
+	if s.Transport == nil {
+		return fmt.Errorf("no transport configured to send PdpStatus")
 	}
-	var eventChan chan kafka.Event = nil
-	err = s.Producer.Produce(kafkaMessage, eventChan)
+
+	err = s.Transport.Send(jsonMessage)
 	if err != nil {
 		log.Warnf("Error producing message: %v\n", err)
 		return err
-	} else {
-		log.Debugf("[OUT|KAFKA|%s]\n%s", topic, string(jsonMessage))
 	}
 
 	return nil
 }
 
 // sends the registartion message to topic using SendPdpStatus(pdpStatus)
+// A PDP restored from persistence registers with its restored state, subgroup and policies.
 func SendPdpPapRegistration(s PdpStatusSender) error {
 
 	var pdpStatus = model.PdpStatus{
-		MessageType: model.PDP_STATUS,
-		PdpType:     consts.PdpType,
-		State:       model.Passive,
-		Healthy:     model.Healthy,
-		Policies:    nil,
-		PdpResponse: nil,
-		Name:        pdpattributes.PdpName,
-		Description: "Pdp Status Registration Message",
-		PdpGroup:    consts.PdpGroup,
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                pdphealth.Evaluate().Status,
+		Policies:               policyregistry.ListGroup(pdpattributes.GetPdpGroup()),
+		PdpResponse:            nil,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp Status Registration Message",
+		PdpGroup:               pdpattributes.GetPdpGroup(),
+	}
+	if subgroup := pdpattributes.GetPdpSubgroup(); subgroup != "" {
+		pdpStatus.PdpSubgroup = &subgroup
 	}
 
 	log.Debugf("Sending PDP PAP Registration Message")
@@ -20,15 +20,19 @@
 package publisher
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"time"
 	"github.com/google/uuid"
+	"policy-opa-pdp/pkg/kafkacomm"
 	"policy-opa-pdp/pkg/kafkacomm/publisher/mocks"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/transport"
 	"testing"
 )
 
@@ -88,7 +92,7 @@ func TestSendPdpStatus_Success(t *testing.T) {
 
 	// Create the RealPdpStatusSender with the mocked producer
 	sender := RealPdpStatusSender{
-		Producer: mockProducer,
+		Transport: kafkacomm.NewKafkaTransport(nil, mockProducer, "test-topic"),
 	}
 
 	// Prepare a mock PdpStatus
@@ -116,7 +120,7 @@ func TestSendPdpStatus_Failure(t *testing.T) {
 
 	// Create a RealPdpStatusSender with the mock producer
 	sender := RealPdpStatusSender{
-		Producer: mockProducer,
+		Transport: kafkacomm.NewKafkaTransport(nil, mockProducer, "test-topic"),
 	}
 
 	// Create a mock PdpStatus object
@@ -141,3 +145,37 @@ func TestSendPdpStatus_Failure(t *testing.T) {
 	mockProducer.AssertExpectations(t)
 }
 
+
+func TestSendPdpPapRegistration_WithRestoredSubgroup(t *testing.T) {
+	pdpattributes.SetPdpSubgroup("opa")
+	defer pdpattributes.SetPdpSubgroup("")
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(status model.PdpStatus) bool {
+		return status.PdpSubgroup != nil && *status.PdpSubgroup == "opa"
+	})).Return(nil)
+
+	err := SendPdpPapRegistration(mockSender)
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+func TestSendPdpStatus_InMemoryTransport(t *testing.T) {
+	tr := transport.NewInMemory(1)
+	defer tr.Close()
+	sender := RealPdpStatusSender{Transport: tr}
+
+	err := sender.SendPdpStatus(model.PdpStatus{State: model.Active})
+	assert.NoError(t, err)
+
+	var sent map[string]interface{}
+	assert.NoError(t, json.Unmarshal(<-tr.Sent(), &sent))
+	assert.Equal(t, "ACTIVE", sent["state"])
+	assert.NotEmpty(t, sent["requestId"], "Expected the sender to set a request id")
+	assert.NotEmpty(t, sent["timestampMs"], "Expected the sender to set a timestamp")
+}
+
+func TestSendPdpStatus_NoTransport(t *testing.T) {
+	sender := RealPdpStatusSender{}
+	assert.Error(t, sender.SendPdpStatus(model.PdpStatus{}), "Expected an error without a transport")
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -18,7 +18,8 @@
 //
 
 // responsible for sending PDP_STATUS messages in response to specific events
-// such as updates (PDP_UPDATE) or state changes (PDP_STATE_CHANGE). These responses provide details
+// such as updates (PDP_UPDATE), state changes (PDP_STATE_CHANGE), health checks (PDP_HEALTH_CHECK)
+// and topic checks (PDP_TOPIC_CHECK). These responses provide details
 // about the current state, health, and attributes of the Policy Decision Point (PDP).
 package publisher
 
@@ -28,7 +29,10 @@ import (
 	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/model"
 	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdphealth"
 	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,17 +44,19 @@ func SendPdpUpdateResponse(s PdpStatusSender, pdpUpdate *model.PdpUpdate) error
 
 	responseStatus := model.Success
 	responseMessage := "PDP Update was Successful"
+	pdpGroup, pdpSubgroup := statusGroup(pdpUpdate.PdpGroup)
 
 	pdpStatus := model.PdpStatus{
-		MessageType: model.PDP_STATUS,
-		PdpType:     consts.PdpType,
-		State:       pdpstate.State,
-		Healthy:     model.Healthy,
-		Name:        pdpattributes.PdpName,
-		Description: "Pdp Status Response Message For Pdp Update",
-		PdpGroup:    consts.PdpGroup,
-		PdpSubgroup: &pdpattributes.PdpSubgroup,
-		// Policies: [],
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                pdphealth.Evaluate().Status,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp Status Response Message For Pdp Update",
+		PdpGroup:               pdpGroup,
+		PdpSubgroup:            &pdpSubgroup,
+		Policies:               policyregistry.ListGroup(pdpGroup),
 		PdpResponse: &model.PdpResponseDetails{
 			ResponseTo:      &pdpUpdate.RequestId,
 			ResponseStatus:  &responseStatus,
@@ -77,16 +83,18 @@ func SendPdpUpdateResponse(s PdpStatusSender, pdpUpdate *model.PdpUpdate) error
 func SendStateChangeResponse(s PdpStatusSender, pdpStateChange *model.PdpStateChange) error {
 
 	responseStatus := model.Success
-	responseMessage := "PDP State Changed From PASSIVE TO Active"
+	responseMessage := fmt.Sprintf("PDP State Changed To %s", pdpstate.GetState())
+	pdpGroup, pdpSubgroup := statusGroup(pdpStateChange.PdpGroup)
 	pdpStatus := model.PdpStatus{
-		MessageType: model.PDP_STATUS,
-		PdpType:     consts.PdpType,
-		State:       pdpstate.GetState(),
-		Healthy:     model.Healthy,
-		Name:        pdpattributes.PdpName,
-		Description: "Pdp Status Response Message to Pdp State Change",
-		PdpGroup:    consts.PdpGroup,
-		PdpSubgroup: &pdpattributes.PdpSubgroup,
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                pdphealth.Evaluate().Status,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp Status Response Message to Pdp State Change",
+		PdpGroup:               pdpGroup,
+		PdpSubgroup:            &pdpSubgroup,
 		// Policies: [],
 		PdpResponse: &model.PdpResponseDetails{
 			ResponseTo:      &pdpStateChange.RequestId,
@@ -108,3 +116,134 @@ func SendStateChangeResponse(s PdpStatusSender, pdpStateChange *model.PdpStateCh
 
 	return nil
 }
+
+// Sends a PDP_STATUS message to indicate that a state change requested by PAP was rejected.
+// The reported state is the one the PDP remains in.
+func SendStateChangeFailureResponse(s PdpStatusSender, pdpStateChange *model.PdpStateChange, reason string) error {
+
+	responseStatus := model.Failure
+	responseMessage := "PDP State Change Failed: " + reason
+	pdpGroup, pdpSubgroup := statusGroup(pdpStateChange.PdpGroup)
+	pdpStatus := model.PdpStatus{
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                pdphealth.Evaluate().Status,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp Status Response Message to Pdp State Change",
+		PdpGroup:               pdpGroup,
+		PdpSubgroup:            &pdpSubgroup,
+		PdpResponse: &model.PdpResponseDetails{
+			ResponseTo:      &pdpStateChange.RequestId,
+			ResponseStatus:  &responseStatus,
+			ResponseMessage: &responseMessage,
+		},
+	}
+
+	pdpStatus.RequestID = uuid.New().String()
+	pdpStatus.TimestampMs = fmt.Sprintf("%d", time.Now().UnixMilli())
+
+	log.Infof("Sending PDP Status With State Change failure response")
+
+	err := s.SendPdpStatus(pdpStatus)
+	if err != nil {
+		log.Warnf("Failed to send PDP State Change failure Message : %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Sends a PDP_STATUS message answering a PDP_HEALTH_CHECK with the health reported by the PDP components.
+func SendHealthCheckResponse(s PdpStatusSender, pdpHealthCheck *model.PdpHealthCheck, report pdphealth.Report) error {
+
+	responseStatus := model.Success
+	responseMessage := fmt.Sprintf("PDP Health Check: %s", report.Status)
+	if failed := report.FailedComponents(); len(failed) > 0 {
+		reasons := make([]string, 0, len(failed))
+		for _, name := range failed {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", name, report.Failures[name]))
+		}
+		responseMessage += " (" + strings.Join(reasons, "; ") + ")"
+	}
+	pdpGroup, pdpSubgroup := statusGroup(pdpHealthCheck.PdpGroup)
+	pdpStatus := model.PdpStatus{
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                report.Status,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp Status Response Message For Pdp Health Check",
+		PdpGroup:               pdpGroup,
+		PdpSubgroup:            &pdpSubgroup,
+		Policies:               policyregistry.ListGroup(pdpGroup),
+		PdpResponse: &model.PdpResponseDetails{
+			ResponseTo:      &pdpHealthCheck.RequestId,
+			ResponseStatus:  &responseStatus,
+			ResponseMessage: &responseMessage,
+		},
+	}
+
+	pdpStatus.RequestID = uuid.New().String()
+	pdpStatus.TimestampMs = fmt.Sprintf("%d", time.Now().UnixMilli())
+
+	log.Infof("Sending PDP Status With Health Check response")
+
+	err := s.SendPdpStatus(pdpStatus)
+	if err != nil {
+		log.Warnf("Failed to send PDP Health Check Message : %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Sends a PDP_STATUS message answering a PDP_TOPIC_CHECK, so that PAP sees the request come back
+// over the topic. The check itself is not re-published as this PDP consumes the same topic.
+func SendTopicCheckResponse(s PdpStatusSender, pdpTopicCheck *model.PdpTopicCheck) error {
+
+	responseStatus := model.Success
+	responseMessage := "PDP Topic Check Successful"
+	pdpGroup, pdpSubgroup := statusGroup(pdpTopicCheck.PdpGroup)
+	pdpStatus := model.PdpStatus{
+		MessageType:            model.PDP_STATUS,
+		PdpType:                consts.PdpType,
+		State:                  pdpstate.GetState(),
+		Healthy:                pdphealth.Evaluate().Status,
+		Name:                   pdpattributes.PdpName,
+		DeploymentInstanceInfo: pdpattributes.DeploymentInstanceInfo,
+		Description:            "Pdp Status Response Message For Pdp Topic Check",
+		PdpGroup:               pdpGroup,
+		PdpSubgroup:            &pdpSubgroup,
+		PdpResponse: &model.PdpResponseDetails{
+			ResponseTo:      &pdpTopicCheck.RequestId,
+			ResponseStatus:  &responseStatus,
+			ResponseMessage: &responseMessage,
+		},
+	}
+
+	pdpStatus.RequestID = uuid.New().String()
+	pdpStatus.TimestampMs = fmt.Sprintf("%d", time.Now().UnixMilli())
+
+	log.Infof("Sending PDP Status With Topic Check response")
+
+	err := s.SendPdpStatus(pdpStatus)
+	if err != nil {
+		log.Warnf("Failed to send PDP Topic Check Message : %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Returns the group a PDP_STATUS answering a message for the group reports, together with the
+// subgroup assigned in it. Messages addressed to the PDP by name may omit the group, the primary
+// group is reported for them.
+func statusGroup(group string) (string, string) {
+	if group == "" {
+		group = pdpattributes.GetPdpGroup()
+	}
+	return group, pdpattributes.GetPdpGroupSubgroup(group)
+}
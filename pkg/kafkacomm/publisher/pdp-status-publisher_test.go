@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -25,6 +25,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"policy-opa-pdp/pkg/kafkacomm/publisher/mocks"
 	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdphealth"
+	"policy-opa-pdp/pkg/policyregistry"
 	"testing"
 )
 
@@ -82,3 +85,143 @@ func TestSendStateChangeResponse_Failure(t *testing.T) {
 	mockSender.AssertCalled(t, "SendPdpStatus", mock.Anything)
 
 }
+
+// TestSendStateChangeFailureResponse_Success tests that a rejected state change is reported as FAILURE
+func TestSendStateChangeFailureResponse_Success(t *testing.T) {
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(status model.PdpStatus) bool {
+		return status.PdpResponse != nil && *status.PdpResponse.ResponseStatus == model.Failure &&
+			*status.PdpResponse.ResponseTo == "test-state-change-id"
+	})).Return(nil)
+
+	pdpStateChange := &model.PdpStateChange{RequestId: "test-state-change-id"}
+
+	err := SendStateChangeFailureResponse(mockSender, pdpStateChange, "Unknown PdpState: BOGUS")
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendStateChangeFailureResponse_Failure tests SendStateChangeFailureResponse when SendPdpStatus fails
+func TestSendStateChangeFailureResponse_Failure(t *testing.T) {
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(errors.New("mock send error"))
+
+	pdpStateChange := &model.PdpStateChange{RequestId: "test-state-change-id"}
+
+	err := SendStateChangeFailureResponse(mockSender, pdpStateChange, "invalid")
+	assert.Error(t, err)
+}
+
+// TestSendPdpUpdateResponse_ReportsUpdateGroup tests that the response reports the group of the PDP_UPDATE
+func TestSendPdpUpdateResponse_ReportsUpdateGroup(t *testing.T) {
+	original := pdpattributes.GetPdpGroups()
+	defer pdpattributes.SetPdpGroups(original)
+	pdpattributes.SetPdpGroups([]string{"opaGroup"})
+	pdpattributes.SetPdpGroupSubgroup("tenantGroup", "opa-tenant")
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.PdpGroup == "tenantGroup" && *pdpStatus.PdpSubgroup == "opa-tenant"
+	})).Return(nil)
+
+	err := SendPdpUpdateResponse(mockSender, &model.PdpUpdate{RequestId: "test-request-id", PdpGroup: "tenantGroup"})
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendPdpUpdateResponse_ReportsPolicies tests that the update response lists the policies deployed in the group
+func TestSendPdpUpdateResponse_ReportsPolicies(t *testing.T) {
+	defer policyregistry.Clear()
+	policyregistry.Set("tenantGroup", []model.ToscaConceptIdentifier{{Name: "role", Version: "1.0.0"}})
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return len(pdpStatus.Policies) == 1 && pdpStatus.Policies[0].Name == "role"
+	})).Return(nil)
+
+	err := SendPdpUpdateResponse(mockSender, &model.PdpUpdate{RequestId: "test-request-id", PdpGroup: "tenantGroup"})
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendStateChangeResponse_PrimaryGroup tests that a state change without group reports the primary group
+func TestSendStateChangeResponse_PrimaryGroup(t *testing.T) {
+	original := pdpattributes.GetPdpGroups()
+	defer pdpattributes.SetPdpGroups(original)
+	pdpattributes.SetPdpGroups([]string{"opaGroup"})
+	pdpattributes.SetPdpGroupSubgroup("opaGroup", "opa")
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.PdpGroup == "opaGroup" && *pdpStatus.PdpSubgroup == "opa"
+	})).Return(nil)
+
+	err := SendStateChangeResponse(mockSender, &model.PdpStateChange{RequestId: "test-request-id"})
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendHealthCheckResponse_ReportsHealth tests that the health check response carries the evaluated health
+func TestSendHealthCheckResponse_ReportsHealth(t *testing.T) {
+	report := pdphealth.Report{Status: model.NotHealthy, Failures: map[string]string{"bundle": "bundle missing"}}
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.Healthy == model.NotHealthy &&
+			*pdpStatus.PdpResponse.ResponseTo == "hc-1" &&
+			*pdpStatus.PdpResponse.ResponseMessage == "PDP Health Check: NOT_HEALTHY (bundle: bundle missing)"
+	})).Return(nil)
+
+	err := SendHealthCheckResponse(mockSender, &model.PdpHealthCheck{RequestId: "hc-1"}, report)
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendResponses_ReportHealth tests that the update and state change responses carry the evaluated health
+func TestSendResponses_ReportHealth(t *testing.T) {
+	pdphealth.Reset()
+	defer pdphealth.Reset()
+	pdphealth.Register("bundle", func() error { return errors.New("bundle missing") })
+
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return pdpStatus.Healthy == model.NotHealthy
+	})).Return(nil).Times(3)
+
+	assert.NoError(t, SendPdpUpdateResponse(mockSender, &model.PdpUpdate{RequestId: "update-1"}))
+	assert.NoError(t, SendStateChangeResponse(mockSender, &model.PdpStateChange{RequestId: "state-1"}))
+	assert.NoError(t, SendStateChangeFailureResponse(mockSender, &model.PdpStateChange{RequestId: "state-2"}, "invalid state"))
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendHealthCheckResponse_Failure tests SendHealthCheckResponse when SendPdpStatus fails
+func TestSendHealthCheckResponse_Failure(t *testing.T) {
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(errors.New("mock send error"))
+
+	err := SendHealthCheckResponse(mockSender, &model.PdpHealthCheck{RequestId: "hc-1"}, pdphealth.Report{Status: model.Healthy})
+	assert.Error(t, err)
+}
+
+// TestSendTopicCheckResponse_Success tests that the topic check response references the request
+func TestSendTopicCheckResponse_Success(t *testing.T) {
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.MatchedBy(func(pdpStatus model.PdpStatus) bool {
+		return *pdpStatus.PdpResponse.ResponseTo == "tc-1" && *pdpStatus.PdpResponse.ResponseStatus == model.Success
+	})).Return(nil)
+
+	err := SendTopicCheckResponse(mockSender, &model.PdpTopicCheck{RequestId: "tc-1"})
+	assert.NoError(t, err)
+	mockSender.AssertExpectations(t)
+}
+
+// TestSendTopicCheckResponse_Failure tests SendTopicCheckResponse when SendPdpStatus fails
+func TestSendTopicCheckResponse_Failure(t *testing.T) {
+	mockSender := new(mocks.PdpStatusSender)
+	mockSender.On("SendPdpStatus", mock.Anything).Return(errors.New("mock send error"))
+
+	err := SendTopicCheckResponse(mockSender, &model.PdpTopicCheck{RequestId: "tc-1"})
+	assert.Error(t, err)
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -28,8 +28,34 @@ var DenyDecisionsCount int64
 var TotalErrorCount int64
 var QuerySuccessCount int64
 var QueryFailureCount int64
+var DeploySuccessCount int64
+var DeployFailureCount int64
+var UndeploySuccessCount int64
+var UndeployFailureCount int64
+var DeadLetterCount int64
+var BundleVerificationFailureCount int64
+var RateLimitedCount int64
+var EvaluationErrorCount int64 // decisions OPA failed to evaluate or did not evaluate in time
 var mu sync.Mutex
 
+// Statistics is a consistent snapshot of the counters.
+type Statistics struct {
+	IndeterminantDecisionsCount    int64
+	PermitDecisionsCount           int64
+	DenyDecisionsCount             int64
+	TotalErrorCount                int64
+	QuerySuccessCount              int64
+	QueryFailureCount              int64
+	DeploySuccessCount             int64
+	DeployFailureCount             int64
+	UndeploySuccessCount           int64
+	UndeployFailureCount           int64
+	DeadLetterCount                int64
+	BundleVerificationFailureCount int64
+	RateLimitedCount               int64
+	EvaluationErrorCount           int64
+}
+
 // Increment counter
 func IncrementIndeterminantDecisionsCount() {
 	mu.Lock()
@@ -115,3 +141,130 @@ func TotalQueryFailureCountRef() *int64 {
 	return &QueryFailureCount
 
 }
+
+// Increment counter
+func IncrementDeploySuccessCount() {
+	mu.Lock()
+	DeploySuccessCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func DeploySuccessCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &DeploySuccessCount
+}
+
+// Increment counter
+func IncrementDeployFailureCount() {
+	mu.Lock()
+	DeployFailureCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func DeployFailureCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &DeployFailureCount
+}
+
+// Increment counter
+func IncrementUndeploySuccessCount() {
+	mu.Lock()
+	UndeploySuccessCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func UndeploySuccessCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &UndeploySuccessCount
+}
+
+// Increment counter
+func IncrementUndeployFailureCount() {
+	mu.Lock()
+	UndeployFailureCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func UndeployFailureCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &UndeployFailureCount
+}
+
+// Increment counter
+func IncrementDeadLetterCount() {
+	mu.Lock()
+	DeadLetterCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func DeadLetterCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &DeadLetterCount
+}
+
+// Increment counter
+func IncrementBundleVerificationFailureCount() {
+	mu.Lock()
+	BundleVerificationFailureCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func BundleVerificationFailureCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &BundleVerificationFailureCount
+}
+
+// Increment counter
+func IncrementRateLimitedCount() {
+	mu.Lock()
+	RateLimitedCount++
+	mu.Unlock()
+}
+
+// returns pointer to the counter
+func RateLimitedCountRef() *int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return &RateLimitedCount
+}
+
+// Increment counter
+func IncrementEvaluationErrorCount() {
+	mu.Lock()
+	EvaluationErrorCount++
+	mu.Unlock()
+}
+
+// returns a snapshot of all counters taken at the same time
+func GetStatistics() Statistics {
+	mu.Lock()
+	defer mu.Unlock()
+	return Statistics{
+		IndeterminantDecisionsCount:    IndeterminantDecisionsCount,
+		PermitDecisionsCount:           PermitDecisionsCount,
+		DenyDecisionsCount:             DenyDecisionsCount,
+		TotalErrorCount:                TotalErrorCount,
+		QuerySuccessCount:              QuerySuccessCount,
+		QueryFailureCount:              QueryFailureCount,
+		DeploySuccessCount:             DeploySuccessCount,
+		DeployFailureCount:             DeployFailureCount,
+		UndeploySuccessCount:           UndeploySuccessCount,
+		UndeployFailureCount:           UndeployFailureCount,
+		DeadLetterCount:                DeadLetterCount,
+		BundleVerificationFailureCount: BundleVerificationFailureCount,
+		RateLimitedCount:               RateLimitedCount,
+		EvaluationErrorCount:           EvaluationErrorCount,
+	}
+}
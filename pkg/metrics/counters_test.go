@@ -122,3 +122,78 @@ func TestCounters(t *testing.T) {
 	assert.Equal(t, int64(3), *TotalQueryFailureCountRef())
 
 }
+
+func TestDeployCounters(t *testing.T) {
+	DeploySuccessCount = 0
+	DeployFailureCount = 0
+	UndeploySuccessCount = 0
+	UndeployFailureCount = 0
+
+	IncrementDeploySuccessCount()
+	IncrementDeploySuccessCount()
+	IncrementDeployFailureCount()
+	IncrementUndeploySuccessCount()
+	IncrementUndeployFailureCount()
+	IncrementUndeployFailureCount()
+
+	assert.Equal(t, int64(2), *DeploySuccessCountRef())
+	assert.Equal(t, int64(1), *DeployFailureCountRef())
+	assert.Equal(t, int64(1), *UndeploySuccessCountRef())
+	assert.Equal(t, int64(2), *UndeployFailureCountRef())
+}
+
+func TestDeadLetterCounter(t *testing.T) {
+	DeadLetterCount = 0
+
+	IncrementDeadLetterCount()
+
+	assert.Equal(t, int64(1), *DeadLetterCountRef())
+}
+
+func TestBundleVerificationFailureCounter(t *testing.T) {
+	BundleVerificationFailureCount = 0
+
+	IncrementBundleVerificationFailureCount()
+
+	assert.Equal(t, int64(1), *BundleVerificationFailureCountRef())
+}
+
+func TestRateLimitedCounter(t *testing.T) {
+	RateLimitedCount = 0
+
+	IncrementRateLimitedCount()
+
+	assert.Equal(t, int64(1), *RateLimitedCountRef())
+}
+
+func TestGetStatistics(t *testing.T) {
+	IndeterminantDecisionsCount = 1
+	PermitDecisionsCount = 2
+	DenyDecisionsCount = 3
+	TotalErrorCount = 4
+	QuerySuccessCount = 5
+	QueryFailureCount = 6
+	DeploySuccessCount = 7
+	UndeploySuccessCount = 8
+	DeadLetterCount = 9
+	BundleVerificationFailureCount = 10
+	RateLimitedCount = 11
+	DeployFailureCount = 12
+	UndeployFailureCount = 13
+
+	assert.Equal(t, Statistics{
+		IndeterminantDecisionsCount:    1,
+		PermitDecisionsCount:           2,
+		DenyDecisionsCount:             3,
+		TotalErrorCount:                4,
+		QuerySuccessCount:              5,
+		QueryFailureCount:              6,
+		DeploySuccessCount:             7,
+		DeployFailureCount:             12,
+		UndeploySuccessCount:           8,
+		UndeployFailureCount:           13,
+		DeadLetterCount:                9,
+		BundleVerificationFailureCount: 10,
+		RateLimitedCount:               11,
+	}, GetStatistics())
+}
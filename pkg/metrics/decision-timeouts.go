@@ -0,0 +1,66 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// Keeps the number of decisions whose evaluation timed out, per policy.
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// DecisionTimeoutStatistics is the number of timed out decisions of one policy.
+type DecisionTimeoutStatistics struct {
+	PolicyName   string
+	TimeoutCount int64
+}
+
+// UnknownPolicy labels the timed out decisions of policies that are not deployed, so that callers
+// cannot add a label for every name they ask for.
+const UnknownPolicy = "unknown"
+
+var (
+	decisionTimeouts   = make(map[string]int64) // timed out decisions keyed by policy name
+	decisionTimeoutsMu sync.Mutex
+)
+
+// Records a decision of the policy whose evaluation timed out.
+func ObserveDecisionTimeout(policyName string) {
+	decisionTimeoutsMu.Lock()
+	defer decisionTimeoutsMu.Unlock()
+	decisionTimeouts[policyName]++
+}
+
+// returns the timed out decisions of all policies sorted by policy name
+func GetDecisionTimeoutStatistics() []DecisionTimeoutStatistics {
+	decisionTimeoutsMu.Lock()
+	defer decisionTimeoutsMu.Unlock()
+	statistics := make([]DecisionTimeoutStatistics, 0, len(decisionTimeouts))
+	for policyName, count := range decisionTimeouts {
+		statistics = append(statistics, DecisionTimeoutStatistics{PolicyName: policyName, TimeoutCount: count})
+	}
+	sort.Slice(statistics, func(i, j int) bool { return statistics[i].PolicyName < statistics[j].PolicyName })
+	return statistics
+}
+
+// Forgets all timed out decisions.
+func ResetDecisionTimeoutStatistics() {
+	decisionTimeoutsMu.Lock()
+	defer decisionTimeoutsMu.Unlock()
+	decisionTimeouts = make(map[string]int64)
+}
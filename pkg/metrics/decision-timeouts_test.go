@@ -0,0 +1,61 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisionTimeoutStatistics(t *testing.T) {
+	ResetDecisionTimeoutStatistics()
+	defer ResetDecisionTimeoutStatistics()
+
+	ObserveDecisionTimeout("role.policy")
+	ObserveDecisionTimeout("abac.policy")
+	ObserveDecisionTimeout("role.policy")
+
+	assert.Equal(t, []DecisionTimeoutStatistics{
+		{PolicyName: "abac.policy", TimeoutCount: 1},
+		{PolicyName: "role.policy", TimeoutCount: 2},
+	}, GetDecisionTimeoutStatistics())
+
+	ResetDecisionTimeoutStatistics()
+	assert.Empty(t, GetDecisionTimeoutStatistics())
+}
+
+func TestDecisionTimeoutStatistics_Concurrent(t *testing.T) {
+	ResetDecisionTimeoutStatistics()
+	defer ResetDecisionTimeoutStatistics()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ObserveDecisionTimeout("abac.policy")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []DecisionTimeoutStatistics{{PolicyName: "abac.policy", TimeoutCount: 50}}, GetDecisionTimeoutStatistics())
+}
@@ -0,0 +1,109 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// Keeps the depth and processing latency of the queues PAP messages wait in before being processed.
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MessageQueueStatistics is a snapshot of the queue of one PAP message type.
+type MessageQueueStatistics struct {
+	MessageName    string
+	Depth          int
+	Capacity       int
+	ProcessedCount int64
+	AverageLatency time.Duration
+	MaxLatency     time.Duration
+}
+
+type messageQueue struct {
+	depth          int
+	capacity       int
+	processedCount int64
+	totalLatency   time.Duration
+	maxLatency     time.Duration
+}
+
+var (
+	messageQueues  = make(map[string]*messageQueue) // queues keyed by message name
+	messageQueueMu sync.Mutex
+)
+
+// returns the queue of the message type, the caller holds messageQueueMu
+func getMessageQueue(messageName string) *messageQueue {
+	queue, ok := messageQueues[messageName]
+	if !ok {
+		queue = &messageQueue{}
+		messageQueues[messageName] = queue
+	}
+	return queue
+}
+
+// Records the number of messages waiting in the queue of the message type.
+func SetMessageQueueDepth(messageName string, depth int, capacity int) {
+	messageQueueMu.Lock()
+	defer messageQueueMu.Unlock()
+	queue := getMessageQueue(messageName)
+	queue.depth = depth
+	queue.capacity = capacity
+}
+
+// Records the time taken to process a message of the message type.
+func ObserveMessageLatency(messageName string, latency time.Duration) {
+	messageQueueMu.Lock()
+	defer messageQueueMu.Unlock()
+	queue := getMessageQueue(messageName)
+	queue.processedCount++
+	queue.totalLatency += latency
+	if latency > queue.maxLatency {
+		queue.maxLatency = latency
+	}
+}
+
+// returns a snapshot of all message queues sorted by message name
+func GetMessageQueueStatistics() []MessageQueueStatistics {
+	messageQueueMu.Lock()
+	defer messageQueueMu.Unlock()
+	statistics := make([]MessageQueueStatistics, 0, len(messageQueues))
+	for name, queue := range messageQueues {
+		stats := MessageQueueStatistics{
+			MessageName:    name,
+			Depth:          queue.depth,
+			Capacity:       queue.capacity,
+			ProcessedCount: queue.processedCount,
+			MaxLatency:     queue.maxLatency,
+		}
+		if queue.processedCount > 0 {
+			stats.AverageLatency = queue.totalLatency / time.Duration(queue.processedCount)
+		}
+		statistics = append(statistics, stats)
+	}
+	sort.Slice(statistics, func(i, j int) bool { return statistics[i].MessageName < statistics[j].MessageName })
+	return statistics
+}
+
+// Forgets all message queues.
+func ResetMessageQueueStatistics() {
+	messageQueueMu.Lock()
+	defer messageQueueMu.Unlock()
+	messageQueues = make(map[string]*messageQueue)
+}
@@ -0,0 +1,66 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageQueueStatistics(t *testing.T) {
+	ResetMessageQueueStatistics()
+	defer ResetMessageQueueStatistics()
+
+	SetMessageQueueDepth("PDP_UPDATE", 3, 10)
+	ObserveMessageLatency("PDP_UPDATE", 10*time.Millisecond)
+	ObserveMessageLatency("PDP_UPDATE", 30*time.Millisecond)
+	SetMessageQueueDepth("PDP_STATE_CHANGE", 0, 10)
+
+	assert.Equal(t, []MessageQueueStatistics{
+		{MessageName: "PDP_STATE_CHANGE", Capacity: 10},
+		{MessageName: "PDP_UPDATE", Depth: 3, Capacity: 10, ProcessedCount: 2,
+			AverageLatency: 20 * time.Millisecond, MaxLatency: 30 * time.Millisecond},
+	}, GetMessageQueueStatistics())
+}
+
+func TestMessageQueueStatistics_Concurrent(t *testing.T) {
+	ResetMessageQueueStatistics()
+	defer ResetMessageQueueStatistics()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			SetMessageQueueDepth("PDP_UPDATE", i%10, 10)
+			ObserveMessageLatency("PDP_UPDATE", time.Millisecond)
+		}(i)
+	}
+	wg.Wait()
+
+	statistics := GetMessageQueueStatistics()
+	if assert.Len(t, statistics, 1) {
+		assert.Equal(t, int64(100), statistics[0].ProcessedCount)
+		assert.Equal(t, time.Millisecond, statistics[0].AverageLatency)
+	}
+}
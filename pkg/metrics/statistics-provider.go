@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -71,10 +71,15 @@ func FetchCurrentStatistics(res http.ResponseWriter, req *http.Request) {
 	onevalue := int64(1)
 	statReport.TotalPoliciesCount = &zerovalue
 	statReport.TotalPolicyTypesCount = &onevalue
-	statReport.DeployFailureCount = &zerovalue
-	statReport.DeploySuccessCount = &zerovalue
-	statReport.UndeployFailureCount = &zerovalue
-	statReport.UndeploySuccessCount = &zerovalue
+	statReport.DeployFailureCount = DeployFailureCountRef()
+	statReport.DeploySuccessCount = DeploySuccessCountRef()
+	statReport.UndeployFailureCount = UndeployFailureCountRef()
+	statReport.UndeploySuccessCount = UndeploySuccessCountRef()
+	statReport.DeadLetterCount = DeadLetterCountRef()
+	statReport.BundleVerificationFailureCount = BundleVerificationFailureCountRef()
+	statReport.RateLimitedCount = RateLimitedCountRef()
+	statReport.MessageQueues = messageQueueReport()
+	statReport.DecisionTimeouts = decisionTimeoutReport()
 
 	value := int32(200)
 	statReport.Code = &value
@@ -84,3 +89,45 @@ func FetchCurrentStatistics(res http.ResponseWriter, req *http.Request) {
 	json.NewEncoder(res).Encode(statReport)
 
 }
+
+// converts the message queue statistics to the report model
+func messageQueueReport() *[]oapicodegen.MessageQueueStatistics {
+	var report []oapicodegen.MessageQueueStatistics
+	for _, queue := range GetMessageQueueStatistics() {
+		messageName := queue.MessageName
+		depth := int32(queue.Depth)
+		capacity := int32(queue.Capacity)
+		processedCount := queue.ProcessedCount
+		averageLatencyMs := queue.AverageLatency.Milliseconds()
+		maxLatencyMs := queue.MaxLatency.Milliseconds()
+		report = append(report, oapicodegen.MessageQueueStatistics{
+			MessageName:      &messageName,
+			Depth:            &depth,
+			Capacity:         &capacity,
+			ProcessedCount:   &processedCount,
+			AverageLatencyMs: &averageLatencyMs,
+			MaxLatencyMs:     &maxLatencyMs,
+		})
+	}
+	if report == nil {
+		return nil
+	}
+	return &report
+}
+
+// converts the decision timeout statistics to the report model
+func decisionTimeoutReport() *[]oapicodegen.DecisionTimeoutStatistics {
+	var report []oapicodegen.DecisionTimeoutStatistics
+	for _, policy := range GetDecisionTimeoutStatistics() {
+		policyName := policy.PolicyName
+		timeoutCount := policy.TimeoutCount
+		report = append(report, oapicodegen.DecisionTimeoutStatistics{
+			PolicyName:   &policyName,
+			TimeoutCount: &timeoutCount,
+		})
+	}
+	if report == nil {
+		return nil
+	}
+	return &report
+}
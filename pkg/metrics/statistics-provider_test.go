@@ -25,6 +25,7 @@ import (
 	"net/http/httptest"
 	"policy-opa-pdp/pkg/model/oapicodegen"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -35,6 +36,13 @@ func TestFetchCurrentStatistics(t *testing.T) {
 	PermitDecisionsCount = 15
 	DenyDecisionsCount = 20
 	TotalErrorCount = 5
+	DeploySuccessCount = 3
+	DeployFailureCount = 4
+	UndeploySuccessCount = 1
+	UndeployFailureCount = 6
+	DeadLetterCount = 2
+	BundleVerificationFailureCount = 1
+	RateLimitedCount = 2
 
 	// Create a new HTTP request
 	req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
@@ -61,10 +69,13 @@ func TestFetchCurrentStatistics(t *testing.T) {
 	assert.Equal(t, int64(5), *statReport.TotalErrorCount)
 	assert.Equal(t, int64(0), *statReport.TotalPoliciesCount)
 	assert.Equal(t, int64(1), *statReport.TotalPolicyTypesCount)
-	assert.Equal(t, int64(0), *statReport.DeployFailureCount)
-	assert.Equal(t, int64(0), *statReport.DeploySuccessCount)
-	assert.Equal(t, int64(0), *statReport.UndeployFailureCount)
-	assert.Equal(t, int64(0), *statReport.UndeploySuccessCount)
+	assert.Equal(t, int64(4), *statReport.DeployFailureCount)
+	assert.Equal(t, int64(3), *statReport.DeploySuccessCount)
+	assert.Equal(t, int64(6), *statReport.UndeployFailureCount)
+	assert.Equal(t, int64(1), *statReport.UndeploySuccessCount)
+	assert.Equal(t, int64(2), *statReport.DeadLetterCount)
+	assert.Equal(t, int64(1), *statReport.BundleVerificationFailureCount)
+	assert.Equal(t, int64(2), *statReport.RateLimitedCount)
 
 	assert.Equal(t, int32(200), *statReport.Code)
 }
@@ -88,3 +99,56 @@ func TestFetchCurrentStatistics_ValidRequestID(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.Code)
 
 }
+
+func TestFetchCurrentStatistics_MessageQueues(t *testing.T) {
+	ResetMessageQueueStatistics()
+	defer ResetMessageQueueStatistics()
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+	res := httptest.NewRecorder()
+	FetchCurrentStatistics(res, req)
+	var statReport oapicodegen.StatisticsReport
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &statReport))
+	assert.Nil(t, statReport.MessageQueues, "Expected no message queues before any message is queued")
+
+	SetMessageQueueDepth("PDP_UPDATE", 2, 10)
+	ObserveMessageLatency("PDP_UPDATE", 40*time.Millisecond)
+
+	res = httptest.NewRecorder()
+	FetchCurrentStatistics(res, req)
+	statReport = oapicodegen.StatisticsReport{}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &statReport))
+	if assert.NotNil(t, statReport.MessageQueues) && assert.Len(t, *statReport.MessageQueues, 1) {
+		queue := (*statReport.MessageQueues)[0]
+		assert.Equal(t, "PDP_UPDATE", *queue.MessageName)
+		assert.Equal(t, int32(2), *queue.Depth)
+		assert.Equal(t, int32(10), *queue.Capacity)
+		assert.Equal(t, int64(1), *queue.ProcessedCount)
+		assert.Equal(t, int64(40), *queue.AverageLatencyMs)
+		assert.Equal(t, int64(40), *queue.MaxLatencyMs)
+	}
+}
+
+func TestFetchCurrentStatistics_DecisionTimeouts(t *testing.T) {
+	ResetDecisionTimeoutStatistics()
+	defer ResetDecisionTimeoutStatistics()
+
+	req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+	res := httptest.NewRecorder()
+	FetchCurrentStatistics(res, req)
+	var statReport oapicodegen.StatisticsReport
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &statReport))
+	assert.Nil(t, statReport.DecisionTimeouts, "Expected no decision timeouts before any decision timed out")
+
+	ObserveDecisionTimeout("abac.policy")
+
+	res = httptest.NewRecorder()
+	FetchCurrentStatistics(res, req)
+	statReport = oapicodegen.StatisticsReport{}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &statReport))
+	if assert.NotNil(t, statReport.DecisionTimeouts) && assert.Len(t, *statReport.DecisionTimeouts, 1) {
+		policy := (*statReport.DecisionTimeouts)[0]
+		assert.Equal(t, "abac.policy", *policy.PolicyName)
+		assert.Equal(t, int64(1), *policy.TimeoutCount)
+	}
+}
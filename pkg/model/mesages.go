@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -79,6 +79,7 @@ type PdpStatus struct {
 	PdpSubgroup            *string                  `json:"pdpSubgroup"`
 	TimestampMs            string                   `json:"timestampMs"`
 	DeploymentInstanceInfo string                   `json:"deploymentInstanceInfo"`
+	Statistics             *PdpStatistics           `json:"statistics"`
 }
 
 // PDP_UPDATE sent by PAP to PDP.
@@ -89,7 +90,7 @@ type PdpUpdate struct {
 	PdpHeartbeatIntervalMs int64                    `json:"pdpHeartbeatIntervalMs" validate:"required"`
 	MessageType            string                   `json:"messageName" validate:"required"`
 	PoliciesToBeDeloyed    []string                 `json:"policiesToBeDeployed" validate:"required"`
-	policiesToBeUndeployed []ToscaConceptIdentifier `json:"policiesToBeUndeployed"`
+	PoliciesToBeUndeployed []ToscaConceptIdentifier `json:"policiesToBeUndeployed"`
 	Name                   string                   `json:"name" validate:"required"`
 	TimestampMs            int64                    `json:"timestampMs" validate:"required"`
 	PdpGroup               string                   `json:"pdpGroup" validate:"required"`
@@ -110,3 +111,29 @@ type PdpStateChange struct {
 	PdpSubgroup string `json:"pdpSubgroup"`
 	RequestId   string `json:"requestId"`
 }
+
+// PDP_HEALTH_CHECK sent by PAP to PDP.
+// https://github.com/onap/policy-models
+// models-pdp/src/main/java/org/onap/policy/models/pdp/concepts/PdpHealthCheck.java
+type PdpHealthCheck struct {
+	Source      string `json:"source"`
+	MessageType string `json:"messageName"`
+	Name        string `json:"name"`
+	TimestampMs int64  `json:"timestampMs"`
+	PdpGroup    string `json:"pdpGroup"`
+	PdpSubgroup string `json:"pdpSubgroup"`
+	RequestId   string `json:"requestId"`
+}
+
+// PDP_TOPIC_CHECK sent by PAP to verify the round-trip over the PDP-PAP topic.
+// https://github.com/onap/policy-models
+// models-pdp/src/main/java/org/onap/policy/models/pdp/concepts/PdpTopicCheck.java
+type PdpTopicCheck struct {
+	Source      string `json:"source"`
+	MessageType string `json:"messageName"`
+	Name        string `json:"name"`
+	TimestampMs int64  `json:"timestampMs"`
+	PdpGroup    string `json:"pdpGroup"`
+	PdpSubgroup string `json:"pdpSubgroup"`
+	RequestId   string `json:"requestId"`
+}
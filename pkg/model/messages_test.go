@@ -315,3 +315,29 @@ func TestPdpMessageType_String_Failure(t *testing.T) {
 	}
 
 }
+
+// TestPdpHealthCheckDeserialization_Success tests the deserialization of a PDP_HEALTH_CHECK sent by PAP.
+func TestPdpHealthCheckDeserialization_Success(t *testing.T) {
+	message := `{"source":"pap-1","messageName":"PDP_HEALTH_CHECK","name":"opa-1","timestampMs":1633017600000,"pdpGroup":"opaGroup","pdpSubgroup":"opa","requestId":"hc-1"}`
+
+	var pdpHealthCheck PdpHealthCheck
+	if err := json.Unmarshal([]byte(message), &pdpHealthCheck); err != nil {
+		t.Fatalf("Expected no error while unmarshaling PdpHealthCheck, got: %v", err)
+	}
+	if pdpHealthCheck.RequestId != "hc-1" || pdpHealthCheck.Name != "opa-1" || pdpHealthCheck.PdpGroup != "opaGroup" {
+		t.Errorf("Unexpected PdpHealthCheck: %+v", pdpHealthCheck)
+	}
+}
+
+// TestPdpTopicCheckDeserialization_Success tests the deserialization of a PDP_TOPIC_CHECK sent by PAP.
+func TestPdpTopicCheckDeserialization_Success(t *testing.T) {
+	message := `{"source":"pap-1","messageName":"PDP_TOPIC_CHECK","name":"opa-1","timestampMs":1633017600000,"requestId":"tc-1"}`
+
+	var pdpTopicCheck PdpTopicCheck
+	if err := json.Unmarshal([]byte(message), &pdpTopicCheck); err != nil {
+		t.Fatalf("Expected no error while unmarshaling PdpTopicCheck, got: %v", err)
+	}
+	if pdpTopicCheck.RequestId != "tc-1" || pdpTopicCheck.MessageType != "PDP_TOPIC_CHECK" {
+		t.Errorf("Unexpected PdpTopicCheck: %+v", pdpTopicCheck)
+	}
+}
@@ -49,6 +49,12 @@ const (
 	PERMIT        OPADecisionResponseDecision = "PERMIT"
 )
 
+// DecisionTimeoutStatistics defines model for DecisionTimeoutStatistics.
+type DecisionTimeoutStatistics struct {
+	PolicyName   *string `json:"policyName,omitempty"`
+	TimeoutCount *int64  `json:"timeoutCount,omitempty"`
+}
+
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
 	ErrorDetails *[]string                  `json:"errorDetails,omitempty"`
@@ -69,6 +75,16 @@ type HealthCheckReport struct {
 	Url     *string `json:"url,omitempty"`
 }
 
+// MessageQueueStatistics defines model for MessageQueueStatistics.
+type MessageQueueStatistics struct {
+	AverageLatencyMs *int64  `json:"averageLatencyMs,omitempty"`
+	Capacity         *int32  `json:"capacity,omitempty"`
+	Depth            *int32  `json:"depth,omitempty"`
+	MaxLatencyMs     *int64  `json:"maxLatencyMs,omitempty"`
+	MessageName      *string `json:"messageName,omitempty"`
+	ProcessedCount   *int64  `json:"processedCount,omitempty"`
+}
+
 // OPADecisionRequest defines model for OPADecisionRequest.
 type OPADecisionRequest struct {
 	CurrentDate     *openapi_types.Date     `json:"currentDate,omitempty"`
@@ -101,25 +117,33 @@ type OPADecisionResponseDecision string
 
 // StatisticsReport defines model for StatisticsReport.
 type StatisticsReport struct {
-	Code                        *int32 `json:"code,omitempty"`
-	DenyDecisionsCount          *int64 `json:"denyDecisionsCount,omitempty"`
-	DeployFailureCount          *int64 `json:"deployFailureCount,omitempty"`
-	DeploySuccessCount          *int64 `json:"deploySuccessCount,omitempty"`
-	IndeterminantDecisionsCount *int64 `json:"indeterminantDecisionsCount,omitempty"`
-	PermitDecisionsCount        *int64 `json:"permitDecisionsCount,omitempty"`
-	QueryFailureCount           *int64 `json:"queryFailureCount,omitempty"`
-	QuerySuccessCount           *int64 `json:"querySuccessCount,omitempty"`
-	TotalErrorCount             *int64 `json:"totalErrorCount,omitempty"`
-	TotalPoliciesCount          *int64 `json:"totalPoliciesCount,omitempty"`
-	TotalPolicyTypesCount       *int64 `json:"totalPolicyTypesCount,omitempty"`
-	UndeployFailureCount        *int64 `json:"undeployFailureCount,omitempty"`
-	UndeploySuccessCount        *int64 `json:"undeploySuccessCount,omitempty"`
+	BundleVerificationFailureCount *int64                       `json:"bundleVerificationFailureCount,omitempty"`
+	Code                           *int32                       `json:"code,omitempty"`
+	DeadLetterCount                *int64                       `json:"deadLetterCount,omitempty"`
+	DecisionTimeouts               *[]DecisionTimeoutStatistics `json:"decisionTimeouts,omitempty"`
+	DenyDecisionsCount             *int64                       `json:"denyDecisionsCount,omitempty"`
+	DeployFailureCount             *int64                       `json:"deployFailureCount,omitempty"`
+	DeploySuccessCount             *int64                       `json:"deploySuccessCount,omitempty"`
+	IndeterminantDecisionsCount    *int64                       `json:"indeterminantDecisionsCount,omitempty"`
+	MessageQueues                  *[]MessageQueueStatistics    `json:"messageQueues,omitempty"`
+	PermitDecisionsCount           *int64                       `json:"permitDecisionsCount,omitempty"`
+	QueryFailureCount              *int64                       `json:"queryFailureCount,omitempty"`
+	QuerySuccessCount              *int64                       `json:"querySuccessCount,omitempty"`
+	RateLimitedCount               *int64                       `json:"rateLimitedCount,omitempty"`
+	TotalErrorCount                *int64                       `json:"totalErrorCount,omitempty"`
+	TotalPoliciesCount             *int64                       `json:"totalPoliciesCount,omitempty"`
+	TotalPolicyTypesCount          *int64                       `json:"totalPolicyTypesCount,omitempty"`
+	UndeployFailureCount           *int64                       `json:"undeployFailureCount,omitempty"`
+	UndeploySuccessCount           *int64                       `json:"undeploySuccessCount,omitempty"`
 }
 
 // DecisionParams defines parameters for Decision.
 type DecisionParams struct {
 	// XONAPRequestID RequestID for http transaction
 	XONAPRequestID *openapi_types.UUID `json:"X-ONAP-RequestID,omitempty"`
+
+	// XDecisionTimeout Maximum time in milliseconds the decision may take, lowers the timeout configured in the PDP
+	XDecisionTimeout *int64 `json:"X-Decision-Timeout,omitempty"`
 }
 
 // HealthcheckParams defines parameters for Healthcheck.
@@ -0,0 +1,38 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// represent the PDP statistics reported to PAP in heartbeats.
+// https://github.com/onap/policy-models/blob/master/models-pdp
+// models-pdp/src/main/java/org/onap/policy/models/pdp/concepts/PdpStatistics.java
+package model
+
+type PdpStatistics struct {
+	PdpInstanceId              string `json:"pdpInstanceId"`
+	TimeStamp                  string `json:"timeStamp"`
+	PdpGroupName               string `json:"pdpGroupName"`
+	PdpSubGroupName            string `json:"pdpSubGroupName"`
+	PolicyDeployCount          int64  `json:"policyDeployCount"`
+	PolicyDeploySuccessCount   int64  `json:"policyDeploySuccessCount"`
+	PolicyDeployFailCount      int64  `json:"policyDeployFailCount"`
+	PolicyUndeployCount        int64  `json:"policyUndeployCount"`
+	PolicyUndeploySuccessCount int64  `json:"policyUndeploySuccessCount"`
+	PolicyUndeployFailCount    int64  `json:"policyUndeployFailCount"`
+	PolicyExecutedCount        int64  `json:"policyExecutedCount"`
+	PolicyExecutedSuccessCount int64  `json:"policyExecutedSuccessCount"`
+	PolicyExecutedFailCount    int64  `json:"policyExecutedFailCount"`
+}
@@ -0,0 +1,55 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Positive test for JSON marshaling of PdpStatistics using the field names PAP expects
+func TestPdpStatistics_MarshalJSON_Success(t *testing.T) {
+	statistics := PdpStatistics{
+		PdpInstanceId:              "opa-1",
+		TimeStamp:                  "2025-01-01T00:00:00Z",
+		PdpGroupName:               "opaGroup",
+		PdpSubGroupName:            "opa",
+		PolicyDeployCount:          2,
+		PolicyDeploySuccessCount:   2,
+		PolicyUndeployCount:        1,
+		PolicyUndeploySuccessCount: 1,
+		PolicyExecutedCount:        5,
+		PolicyExecutedSuccessCount: 4,
+		PolicyExecutedFailCount:    1,
+	}
+
+	expectedJSON := `{"pdpInstanceId":"opa-1","timeStamp":"2025-01-01T00:00:00Z","pdpGroupName":"opaGroup","pdpSubGroupName":"opa",` +
+		`"policyDeployCount":2,"policyDeploySuccessCount":2,"policyDeployFailCount":0,` +
+		`"policyUndeployCount":1,"policyUndeploySuccessCount":1,"policyUndeployFailCount":0,` +
+		`"policyExecutedCount":5,"policyExecutedSuccessCount":4,"policyExecutedFailCount":1}`
+	got, err := json.Marshal(statistics)
+	if err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+
+	if string(got) != expectedJSON {
+		t.Errorf("json.Marshal() = %v, want %v", string(got), expectedJSON)
+	}
+}
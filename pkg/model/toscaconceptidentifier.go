@@ -23,11 +23,12 @@ package model
 
 import (
 	"fmt"
+	"strings"
 )
 
 type ToscaConceptIdentifier struct {
-	Name    string
-	Version string
+	Name    string `json:"name"`
+	Version string `json:"version"`
 }
 
 func NewToscaConceptIdentifier(name, version string) *ToscaConceptIdentifier {
@@ -37,6 +38,14 @@ func NewToscaConceptIdentifier(name, version string) *ToscaConceptIdentifier {
 	}
 }
 
+// Parses an identifier given as "name:version", the version is optional.
+func ParseToscaConceptIdentifier(id string) *ToscaConceptIdentifier {
+	if idx := strings.LastIndex(id, ":"); idx > 0 {
+		return NewToscaConceptIdentifier(id[:idx], id[idx+1:])
+	}
+	return NewToscaConceptIdentifier(id, "")
+}
+
 func NewToscaConceptIdentifierFromKey(key PfKey) *ToscaConceptIdentifier {
 	return &ToscaConceptIdentifier{
 		Name:    key.Name,
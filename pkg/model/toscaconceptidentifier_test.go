@@ -105,3 +105,16 @@ func TestToscaConceptIdentifier_ValidatePapRest_Failure(t *testing.T) {
 		}
 	}
 }
+
+// Test for ParseToscaConceptIdentifier with and without a version
+func TestParseToscaConceptIdentifier(t *testing.T) {
+	id := ParseToscaConceptIdentifier("onap.policy.zone:1.0.0")
+	if id.Name != "onap.policy.zone" || id.Version != "1.0.0" {
+		t.Errorf("Unexpected identifier: %+v", id)
+	}
+
+	id = ParseToscaConceptIdentifier("zone")
+	if id.Name != "zone" || id.Version != "" {
+		t.Errorf("Unexpected identifier: %+v", id)
+	}
+}
@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -25,20 +25,27 @@ package opasdk
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/bundleserver"
 	"policy-opa-pdp/pkg/log"
 	"sync"
+	"time"
 
+	bundleplugin "github.com/open-policy-agent/opa/plugins/bundle"
 	"github.com/open-policy-agent/opa/sdk"
 )
 
 // Define the structs
 var (
-	opaInstance *sdk.OPA  //A singleton instance of the OPA object
-	once        sync.Once //A sync.Once variable used to ensure that the OPA instance is initialized only once,
+	opaInstance  *sdk.OPA             //A singleton instance of the OPA object
+	once         sync.Once            //A sync.Once variable used to ensure that the OPA instance is initialized only once,
+	healthMu     sync.RWMutex         // guards initErr and bundleStatus, and opaInstance for the health check
+	initErr      error                // the error that stopped the OPA instance from being created or configured
+	bundleStatus *bundleplugin.Status // the last status reported by the bundle plugin
 )
 
 // reads JSON configuration from a file and return a jsonReader
@@ -64,10 +71,17 @@ func getJSONReader(filePath string, openFunc func(string) (*os.File, error),
 func GetOPASingletonInstance() (*sdk.OPA, error) {
 	var err error
 	once.Do(func() {
+		var instance *sdk.OPA
+		defer func() {
+			healthMu.Lock()
+			defer healthMu.Unlock()
+			opaInstance, initErr = instance, err
+		}()
 		var opaErr error
-		opaInstance, opaErr = sdk.New(context.Background(), sdk.Options{
+		instance, opaErr = sdk.New(context.Background(), sdk.Options{
 			// Configure your OPA instance here
 			V1Compatible: true,
+			Plugins:      bundleserver.SDKPlugins(),
 		})
 		log.Debugf("Create an instance of OPA Object")
 		if opaErr != nil {
@@ -81,13 +95,91 @@ func GetOPASingletonInstance() (*sdk.OPA, error) {
 				err = jsonErr
 				return
 			}
+			config, configErr := bundleserver.PrepareSDKConfig(jsonReader)
+			if configErr != nil {
+				log.Warnf("Error preparing OPA SDK config: %s", configErr)
+				err = configErr
+				return
+			}
 			log.Debugf("Configure an instance of OPA Object")
 
-			opaInstance.Configure(context.Background(), sdk.ConfigOptions{
-				Config: jsonReader,
-			})
+			// Configure returns once the plugins are started, the bundle status listener is
+			// registered before waiting for the first bundle to be activated. A bundle that is not
+			// activated in time, e.g. because it failed verification, is reported by the health
+			// check while the plugin keeps polling for it.
+			ready := make(chan struct{})
+			if configureErr := instance.Configure(context.Background(), sdk.ConfigOptions{
+				Config: config,
+				Ready:  ready,
+			}); configureErr != nil {
+				log.Warnf("Error configuring OPA instance: %s", configureErr)
+				err = configureErr
+				return
+			}
+			registerBundleStatusListener(instance)
+			select {
+			case <-ready:
+				seedBundleStatus(instance)
+			case <-time.After(time.Duration(consts.OpaReadyTimeoutSeconds) * time.Second):
+				log.Errorf("OPA bundle not activated within %d seconds, the PDP reports NOT_HEALTHY until it is", consts.OpaReadyTimeoutSeconds)
+			}
 		}
 	})
 
 	return opaInstance, err
 }
+
+// observes the status of the bundles downloaded by the OPA instance for the health check
+func registerBundleStatusListener(opa *sdk.OPA) {
+	plugin, ok := opa.Plugin(bundleplugin.Name).(*bundleplugin.Plugin)
+	if !ok {
+		log.Debugf("OPA instance has no bundle plugin, bundle verification is not monitored")
+		return
+	}
+	plugin.Register("opa-pdp", observeBundleStatus)
+}
+
+// records the bundles activated before the listener was registered, the OPA instance is only
+// ready once the bundle plugin has activated every configured bundle
+func seedBundleStatus(opa *sdk.OPA) {
+	plugin, ok := opa.Plugin(bundleplugin.Name).(*bundleplugin.Plugin)
+	if !ok {
+		return
+	}
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if bundleStatus != nil {
+		return
+	}
+	for name := range plugin.Config().Bundles {
+		bundleStatus = &bundleplugin.Status{Name: name}
+		return
+	}
+}
+
+// remembers the bundle status for the health check
+func observeBundleStatus(status bundleplugin.Status) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	bundleStatus = &status
+}
+
+// Reports whether the OPA instance was created and configured and has activated a bundle,
+// used as the health check of the OPA component.
+func CheckHealth() error {
+	healthMu.RLock()
+	defer healthMu.RUnlock()
+	if initErr != nil {
+		return fmt.Errorf("OPA instance failed to initialize: %w", initErr)
+	}
+	if opaInstance == nil {
+		return errors.New("OPA instance is not initialized")
+	}
+	if bundleStatus == nil {
+		return errors.New("OPA bundle has not been loaded yet")
+	}
+	if bundleStatus.Code != "" {
+		return fmt.Errorf("OPA bundle %s failed to load: %s", bundleStatus.Name, bundleStatus.Message)
+	}
+	return nil
+}
@@ -22,16 +22,23 @@ package opasdk
 import (
 	"errors"
 	"io"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/bundleserver"
+	"policy-opa-pdp/pkg/metrics"
 	"testing"
 	"sync"
+	"time"
         "context"
 	"fmt"
 	"bou.ke/monkey"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/open-policy-agent/opa/sdk"
+	bundleplugin "github.com/open-policy-agent/opa/plugins/bundle"
 )
 
 // Mock for os.Open
@@ -215,6 +222,8 @@ func TestGetOPASingletonInstance(t *testing.T) {
 func resetSingleton() {
 	opaInstance = nil
 	once = sync.Once{}
+	initErr = nil
+	bundleStatus = nil
 }
 
 // Test sdk.New failure scenario
@@ -230,3 +239,120 @@ func TestGetOPASingletonInstance_SdkNewFails(t *testing.T) {
 	assert.Error(t, err, "Expected an error when sdk.New fails")
 	assert.Contains(t, err.Error(), "mocked error in sdk.New")
 }
+
+func TestCheckHealth(t *testing.T) {
+	resetSingleton()
+	defer resetSingleton()
+	assert.Error(t, CheckHealth(), "Expected an error before the OPA instance is created")
+
+	opaInstance = &sdk.OPA{}
+	err := CheckHealth()
+	assert.Error(t, err, "Expected an error before a bundle is loaded")
+	assert.Contains(t, err.Error(), "not been loaded")
+
+	observeBundleStatus(bundleplugin.Status{Name: "opa-pdp", Code: "bundle_error", Message: "server replied with not found"})
+	err = CheckHealth()
+	assert.Error(t, err, "Expected an error while the bundle fails to load")
+	assert.Contains(t, err.Error(), "server replied with not found")
+
+	observeBundleStatus(bundleplugin.Status{Name: "opa-pdp"})
+	assert.NoError(t, CheckHealth())
+}
+
+func TestCheckHealth_InitializationFailed(t *testing.T) {
+	resetSingleton()
+	defer resetSingleton()
+	originalPath := consts.OpasdkConfigPath
+	consts.OpasdkConfigPath = "/nonexistent/config.json"
+	defer func() { consts.OpasdkConfigPath = originalPath }()
+
+	instance, err := GetOPASingletonInstance()
+	assert.NotNil(t, instance, "The OPA instance is created before the configuration is read")
+	assert.Error(t, err)
+	observeBundleStatus(bundleplugin.Status{Name: "opa-pdp"})
+
+	err = CheckHealth()
+	assert.Error(t, err, "Expected an error when the configuration was not loaded")
+	assert.Contains(t, err.Error(), "failed to initialize")
+}
+
+// serves a bundle built from the policy over HTTP and points the OPA SDK config at it
+func setupBundleServer(t *testing.T, policy string) {
+	policies, data, bundleFile, configPath := consts.Policies, consts.Data, consts.BundleTarGzFile, consts.OpasdkConfigPath
+	allowPlainHTTP, signingKey := cfg.AllowPlainHTTP, cfg.BundleSigningKey
+	t.Cleanup(func() {
+		consts.Policies, consts.Data, consts.BundleTarGzFile, consts.OpasdkConfigPath = policies, data, bundleFile, configPath
+		cfg.AllowPlainHTTP, cfg.BundleSigningKey = allowPlainHTTP, signingKey
+	})
+	cfg.AllowPlainHTTP, cfg.BundleSigningKey = true, ""
+	consts.Policies, consts.Data = t.TempDir(), t.TempDir()
+	consts.BundleTarGzFile = filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.NoError(t, os.WriteFile(filepath.Join(consts.Policies, "policy.rego"), []byte(policy), 0644))
+	assert.NoError(t, bundleserver.BuildBundle())
+
+	server := httptest.NewServer(bundleserver.Authenticate(bundleserver.GetBundle))
+	t.Cleanup(server.Close)
+	consts.OpasdkConfigPath = filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(consts.OpasdkConfigPath, []byte(`{
+  "services": [{"name": "opa-bundle-server", "url": "`+server.URL+consts.BundleServerPath+`"}],
+  "bundles": {"opabundle": {"service": "opa-bundle-server", "resource": "bundle.tar.gz", "polling": {"min_delay_seconds": 1, "max_delay_seconds": 1}}}
+}`), 0644))
+}
+
+// the bundle activated while the OPA instance is configured is observed by the health check,
+// as are the bundles of later polls
+func TestGetOPASingletonInstance_ConfiguresBundle(t *testing.T) {
+	resetSingleton()
+	defer resetSingleton()
+	setupBundleServer(t, "package example\n\nallow := true\n")
+
+	instance, err := GetOPASingletonInstance()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer instance.Stop(context.Background())
+	assert.NoError(t, CheckHealth())
+
+	result, err := instance.Decision(context.Background(), sdk.DecisionOptions{Path: "/example/allow"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, result.Result)
+
+	// later polls are observed too
+	assert.NoError(t, os.Remove(consts.BundleTarGzFile))
+	assert.Eventually(t, func() bool { return CheckHealth() != nil }, 5*time.Second, 50*time.Millisecond)
+	assert.ErrorContains(t, CheckHealth(), "Internal Server Error")
+}
+
+// a bundle that never activates bounds the startup wait, after which the PDP runs reporting the failure
+func TestGetOPASingletonInstance_BundleNotActivated(t *testing.T) {
+	resetSingleton()
+	defer resetSingleton()
+	setupBundleServer(t, "package example\n\nallow := true\n")
+	readyTimeout, signingAlg, verifyKey, keyId := consts.OpaReadyTimeoutSeconds, cfg.BundleSigningAlg, cfg.BundleVerifyKey, cfg.BundleKeyId
+	defer func() {
+		consts.OpaReadyTimeoutSeconds = readyTimeout
+		cfg.BundleSigningAlg, cfg.BundleVerifyKey, cfg.BundleKeyId = signingAlg, verifyKey, keyId
+	}()
+	consts.OpaReadyTimeoutSeconds = 1
+	metrics.BundleVerificationFailureCount = 0
+
+	// the bundle is signed with another secret than the one the OPA instance verifies with
+	cfg.BundleSigningKey = filepath.Join(t.TempDir(), "signing.key")
+	cfg.BundleSigningAlg, cfg.BundleVerifyKey, cfg.BundleKeyId = "HS256", "", "test-key"
+	assert.NoError(t, os.WriteFile(cfg.BundleSigningKey, []byte("the-secret-the-bundle-is-signed-with"), 0600))
+	assert.NoError(t, bundleserver.BuildBundle())
+	assert.NoError(t, os.WriteFile(cfg.BundleSigningKey, []byte("the-secret-the-bundle-is-verified-with"), 0600))
+
+	instance, err := GetOPASingletonInstance()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer instance.Stop(context.Background())
+	assert.Error(t, CheckHealth())
+	assert.Error(t, bundleserver.CheckVerification())
+	assert.Equal(t, int64(1), metrics.BundleVerificationFailureCount)
+
+	// the instance keeps polling and activates the bundle once it verifies
+	assert.NoError(t, bundleserver.BuildBundle())
+	assert.Eventually(t, func() bool { return CheckHealth() == nil && bundleserver.CheckVerification() == nil }, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,360 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The papsim package simulates the Policy Administration Point (PAP) side of the PDP-PAP
+// protocol. It talks to a PDP over a transport, sends PDP_UPDATE, PDP_STATE_CHANGE,
+// PDP_HEALTH_CHECK and PDP_TOPIC_CHECK messages and checks the PDP_STATUS responses and
+// heartbeats, so the protocol can be tested without the docker-compose stack.
+package papsim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/transport"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second // the default time to wait for a PDP_STATUS
+	heartbeatBacklog = 100              // the number of unread heartbeats kept
+)
+
+// Status is a PDP_STATUS as received by PAP.
+type Status struct {
+	MessageName            string                         `json:"messageName"`
+	PdpType                string                         `json:"pdpType"`
+	State                  string                         `json:"state"`
+	Healthy                string                         `json:"healthy"`
+	Description            string                         `json:"description"`
+	Response               *model.PdpResponseDetails      `json:"response"`
+	Policies               []model.ToscaConceptIdentifier `json:"policies"`
+	Name                   string                         `json:"name"`
+	RequestID              string                         `json:"requestId"`
+	PdpGroup               string                         `json:"pdpGroup"`
+	PdpSubgroup            *string                        `json:"pdpSubgroup"`
+	TimestampMs            string                         `json:"timestampMs"`
+	DeploymentInstanceInfo string                         `json:"deploymentInstanceInfo"`
+	Statistics             *model.PdpStatistics           `json:"statistics"`
+	ReceivedAt             time.Time                      `json:"-"`
+}
+
+// Returns an error unless the status is a successful response.
+func (s *Status) Succeeded() error {
+	if s.Response == nil || s.Response.ResponseStatus == nil {
+		return fmt.Errorf("PDP_STATUS %s carries no response", s.RequestID)
+	}
+	if *s.Response.ResponseStatus != model.Success {
+		message := ""
+		if s.Response.ResponseMessage != nil {
+			message = *s.Response.ResponseMessage
+		}
+		return fmt.Errorf("PDP responded with %s: %s", *s.Response.ResponseStatus, message)
+	}
+	return nil
+}
+
+// HasPolicy reports whether the status lists the policy as deployed.
+func (s *Status) HasPolicy(policy model.ToscaConceptIdentifier) bool {
+	for _, deployed := range s.Policies {
+		if deployed == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Target identifies the PDP the simulator talks to.
+type Target struct {
+	Name     string
+	Group    string
+	Subgroup string
+}
+
+// Simulator plays PAP towards a single PDP.
+type Simulator struct {
+	Timeout time.Duration // the time to wait for a PDP_STATUS
+
+	transport  transport.Transport
+	source     string
+	heartbeats chan *Status // PDP_STATUS messages that are not responses: registration and heartbeats
+
+	mu      sync.Mutex
+	target  Target
+	waiters map[string]chan *Status // pending requests keyed by request id
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Creates a simulator using the PAP side of a transport, the PDP is assigned to subgroup on update.
+func New(t transport.Transport, subgroup string) *Simulator {
+	return &Simulator{
+		Timeout:    defaultTimeout,
+		transport:  t,
+		source:     "pap-simulator-" + uuid.New().String(),
+		heartbeats: make(chan *Status, heartbeatBacklog),
+		target:     Target{Subgroup: subgroup},
+		waiters:    make(map[string]chan *Status),
+	}
+}
+
+// Starts receiving PDP_STATUS messages, does nothing when already started.
+func (s *Simulator) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go s.receive(ctx, s.done)
+}
+
+// Stops receiving and waits for the receiver to exit. The transport is left open.
+func (s *Simulator) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Returns the PDP the simulator talks to.
+func (s *Simulator) Target() Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target
+}
+
+// Addresses the simulator to a PDP without waiting for its registration.
+func (s *Simulator) SetTarget(target Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.target = target
+}
+
+// the receive loop, dispatching responses to their waiters and queueing everything else as heartbeats
+func (s *Simulator) receive(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for ctx.Err() == nil {
+		message, err := s.transport.Receive(ctx)
+		if errors.Is(err, transport.ErrClosed) {
+			return
+		}
+		if err != nil || message == nil {
+			continue
+		}
+		var status Status
+		if err := json.Unmarshal(message.Value, &status); err != nil {
+			log.Warnf("PAP simulator failed to unmarshal message: %v", err)
+			continue
+		}
+		if status.MessageName != model.PDP_STATUS.String() {
+			// PAP reads its own messages back when PDP and PAP share a topic
+			continue
+		}
+		status.ReceivedAt = time.Now()
+		s.dispatch(&status)
+	}
+}
+
+func (s *Simulator) dispatch(status *Status) {
+	if status.Response != nil && status.Response.ResponseTo != nil {
+		s.mu.Lock()
+		waiter, ok := s.waiters[*status.Response.ResponseTo]
+		delete(s.waiters, *status.Response.ResponseTo)
+		s.mu.Unlock()
+		if ok {
+			waiter <- status
+		} else {
+			log.Debugf("PAP simulator discarding response to unknown request %s", *status.Response.ResponseTo)
+		}
+		return
+	}
+	select {
+	case s.heartbeats <- status:
+	default:
+		log.Warnf("PAP simulator heartbeat backlog is full, discarding PDP_STATUS %s", status.RequestID)
+	}
+}
+
+// Waits for the PDP to register and addresses the simulator to it.
+func (s *Simulator) AwaitRegistration(ctx context.Context) (*Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no PDP registration received: %w", ctx.Err())
+	case status := <-s.heartbeats:
+		s.mu.Lock()
+		s.target.Name = status.Name
+		s.target.Group = status.PdpGroup
+		s.mu.Unlock()
+		return status, nil
+	}
+}
+
+// Collects the next count heartbeats, discarding those received before the call.
+func (s *Simulator) AwaitHeartbeats(ctx context.Context, count int) ([]*Status, error) {
+	for drained := false; !drained; {
+		select {
+		case <-s.heartbeats:
+		default:
+			drained = true
+		}
+	}
+	heartbeats := make([]*Status, 0, count)
+	for len(heartbeats) < count {
+		waitCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+		select {
+		case <-waitCtx.Done():
+			cancel()
+			return heartbeats, fmt.Errorf("received %d of %d heartbeats: %w", len(heartbeats), count, waitCtx.Err())
+		case status := <-s.heartbeats:
+			cancel()
+			heartbeats = append(heartbeats, status)
+		}
+	}
+	return heartbeats, nil
+}
+
+// Sends a PDP_UPDATE deploying and undeploying policies and waits for the response.
+func (s *Simulator) Update(ctx context.Context, heartbeatIntervalMs int64, deploy []model.ToscaConceptIdentifier,
+	undeploy []model.ToscaConceptIdentifier) (*Status, error) {
+	target := s.Target()
+	toBeDeployed := make([]string, 0, len(deploy))
+	for _, policy := range deploy {
+		toBeDeployed = append(toBeDeployed, policy.Name+":"+policy.Version)
+	}
+	if undeploy == nil {
+		undeploy = []model.ToscaConceptIdentifier{}
+	}
+	update := model.PdpUpdate{
+		Source:                 s.source,
+		PdpHeartbeatIntervalMs: heartbeatIntervalMs,
+		MessageType:            model.PDP_UPDATE.String(),
+		PoliciesToBeDeloyed:    toBeDeployed,
+		PoliciesToBeUndeployed: undeploy,
+		Name:                   target.Name,
+		TimestampMs:            time.Now().UnixMilli(),
+		PdpGroup:               target.Group,
+		PdpSubgroup:            target.Subgroup,
+		RequestId:              uuid.New().String(),
+	}
+	return s.request(ctx, update.RequestId, update)
+}
+
+// Sends a PDP_STATE_CHANGE and waits for the response.
+func (s *Simulator) StateChange(ctx context.Context, state model.PdpState) (*Status, error) {
+	target := s.Target()
+	stateChange := model.PdpStateChange{
+		Source:      s.source,
+		State:       state.String(),
+		MessageType: model.PDP_STATE_CHANGE.String(),
+		Name:        target.Name,
+		TimestampMs: time.Now().UnixMilli(),
+		PdpGroup:    target.Group,
+		PdpSubgroup: target.Subgroup,
+		RequestId:   uuid.New().String(),
+	}
+	return s.request(ctx, stateChange.RequestId, stateChange)
+}
+
+// Sends a PDP_HEALTH_CHECK and waits for the response.
+func (s *Simulator) HealthCheck(ctx context.Context) (*Status, error) {
+	target := s.Target()
+	healthCheck := model.PdpHealthCheck{
+		Source:      s.source,
+		MessageType: model.PDP_HEALTH_CHECK.String(),
+		Name:        target.Name,
+		TimestampMs: time.Now().UnixMilli(),
+		PdpGroup:    target.Group,
+		PdpSubgroup: target.Subgroup,
+		RequestId:   uuid.New().String(),
+	}
+	return s.request(ctx, healthCheck.RequestId, healthCheck)
+}
+
+// Sends a PDP_TOPIC_CHECK and waits for the response.
+func (s *Simulator) TopicCheck(ctx context.Context) (*Status, error) {
+	target := s.Target()
+	topicCheck := model.PdpTopicCheck{
+		Source:      s.source,
+		MessageType: model.PDP_TOPIC_CHECK.String(),
+		Name:        target.Name,
+		TimestampMs: time.Now().UnixMilli(),
+		PdpGroup:    target.Group,
+		PdpSubgroup: target.Subgroup,
+		RequestId:   uuid.New().String(),
+	}
+	return s.request(ctx, topicCheck.RequestId, topicCheck)
+}
+
+// sends the message and waits for the PDP_STATUS responding to requestId
+func (s *Simulator) request(ctx context.Context, requestId string, message interface{}) (*Status, error) {
+	value, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	waiter := make(chan *Status, 1)
+	s.mu.Lock()
+	s.waiters[requestId] = waiter
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.waiters, requestId)
+		s.mu.Unlock()
+	}()
+
+	if err := s.transport.Send(value); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response to request %s: %w", requestId, ctx.Err())
+	case status := <-waiter:
+		return status, nil
+	}
+}
+
+// Checks that consecutive heartbeats were received the interval apart, allowing a deviation of
+// tolerancePercent of the interval.
+func CheckHeartbeatTiming(heartbeats []*Status, interval time.Duration, tolerancePercent int) error {
+	if len(heartbeats) < 2 {
+		return fmt.Errorf("at least 2 heartbeats are needed to check their timing, got %d", len(heartbeats))
+	}
+	tolerance := interval * time.Duration(tolerancePercent) / 100
+	for i := 1; i < len(heartbeats); i++ {
+		gap := heartbeats[i].ReceivedAt.Sub(heartbeats[i-1].ReceivedAt)
+		if gap < interval-tolerance || gap > interval+tolerance {
+			return fmt.Errorf("heartbeat %d arrived %v after the previous one, expected %v ± %v", i, gap, interval, tolerance)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package papsim
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/transport"
+	"testing"
+	"time"
+)
+
+// answers every request received on the PDP side of tr with a PDP_STATUS built by respond
+func fakePdp(ctx context.Context, tr *transport.InMemory, respond func(request map[string]interface{}) map[string]interface{}) {
+	go func() {
+		for ctx.Err() == nil {
+			message, err := tr.Receive(ctx)
+			if err != nil || message == nil {
+				continue
+			}
+			var request map[string]interface{}
+			if json.Unmarshal(message.Value, &request) != nil {
+				continue
+			}
+			if status := respond(request); status != nil {
+				value, _ := json.Marshal(status)
+				tr.Send(value)
+			}
+		}
+	}()
+}
+
+func successResponse(request map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"messageName": "PDP_STATUS",
+		"state":       request["state"],
+		"healthy":     "HEALTHY",
+		"policies":    []map[string]string{},
+		"response":    map[string]interface{}{"responseTo": request["requestId"], "responseStatus": "SUCCESS"},
+	}
+}
+
+func TestSimulator_AwaitRegistration(t *testing.T) {
+	tr := transport.NewInMemory(10)
+	defer tr.Close()
+	sim := New(tr.Peer(), "opa")
+	sim.Start(context.Background())
+	defer sim.Stop()
+
+	// messages PAP reads back from a shared topic are ignored
+	tr.Send([]byte(`{"messageName":"PDP_UPDATE","name":"opa-1"}`))
+	tr.Send([]byte(`{"messageName":"PDP_STATUS","name":"opa-1","pdpGroup":"opaGroup","state":"PASSIVE"}`))
+
+	status, err := sim.AwaitRegistration(context.Background())
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) {
+		assert.Equal(t, "PASSIVE", status.State)
+		assert.False(t, status.ReceivedAt.IsZero())
+	}
+	assert.Equal(t, Target{Name: "opa-1", Group: "opaGroup", Subgroup: "opa"}, sim.Target())
+}
+
+func TestSimulator_AwaitRegistrationTimeout(t *testing.T) {
+	tr := transport.NewInMemory(10)
+	defer tr.Close()
+	sim := New(tr.Peer(), "opa")
+	sim.Timeout = 50 * time.Millisecond
+	sim.Start(context.Background())
+	defer sim.Stop()
+
+	_, err := sim.AwaitRegistration(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSimulator_Requests(t *testing.T) {
+	tr := transport.NewInMemory(10)
+	defer tr.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var received []map[string]interface{}
+	requests := make(chan map[string]interface{}, 10)
+	fakePdp(ctx, tr, func(request map[string]interface{}) map[string]interface{} {
+		requests <- request
+		return successResponse(request)
+	})
+	sim := New(tr.Peer(), "opa")
+	sim.SetTarget(Target{Name: "opa-1", Group: "opaGroup", Subgroup: "opa"})
+	sim.Start(ctx)
+	defer sim.Stop()
+
+	policy := model.ToscaConceptIdentifier{Name: "role", Version: "1.0.0"}
+	status, err := sim.Update(ctx, 1000, []model.ToscaConceptIdentifier{policy}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, status.Succeeded())
+	received = append(received, <-requests)
+
+	status, err = sim.StateChange(ctx, model.Active)
+	assert.NoError(t, err)
+	assert.Equal(t, "ACTIVE", status.State)
+	received = append(received, <-requests)
+
+	_, err = sim.HealthCheck(ctx)
+	assert.NoError(t, err)
+	received = append(received, <-requests)
+
+	_, err = sim.TopicCheck(ctx)
+	assert.NoError(t, err)
+	received = append(received, <-requests)
+
+	assert.Equal(t, "PDP_UPDATE", received[0]["messageName"])
+	assert.Equal(t, []interface{}{"role:1.0.0"}, received[0]["policiesToBeDeployed"])
+	assert.Equal(t, float64(1000), received[0]["pdpHeartbeatIntervalMs"])
+	assert.Equal(t, "PDP_STATE_CHANGE", received[1]["messageName"])
+	assert.Equal(t, "PDP_HEALTH_CHECK", received[2]["messageName"])
+	assert.Equal(t, "PDP_TOPIC_CHECK", received[3]["messageName"])
+	for _, request := range received {
+		assert.Equal(t, "opa-1", request["name"])
+		assert.Equal(t, "opaGroup", request["pdpGroup"])
+		assert.Equal(t, "opa", request["pdpSubgroup"])
+	}
+}
+
+func TestSimulator_RequestTimeout(t *testing.T) {
+	tr := transport.NewInMemory(10)
+	defer tr.Close()
+	sim := New(tr.Peer(), "opa")
+	sim.Timeout = 50 * time.Millisecond
+	sim.Start(context.Background())
+	defer sim.Stop()
+
+	_, err := sim.StateChange(context.Background(), model.Active)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSimulator_AwaitHeartbeats(t *testing.T) {
+	tr := transport.NewInMemory(10)
+	defer tr.Close()
+	sim := New(tr.Peer(), "opa")
+	sim.Timeout = 200 * time.Millisecond
+	sim.Start(context.Background())
+	defer sim.Stop()
+
+	tr.Send([]byte(`{"messageName":"PDP_STATUS","requestId":"stale"}`))
+	time.Sleep(150 * time.Millisecond)
+	go func() {
+		tr.Send([]byte(`{"messageName":"PDP_STATUS","requestId":"1"}`))
+		tr.Send([]byte(`{"messageName":"PDP_STATUS","requestId":"2"}`))
+	}()
+
+	heartbeats, err := sim.AwaitHeartbeats(context.Background(), 2)
+	assert.NoError(t, err)
+	if assert.Len(t, heartbeats, 2) {
+		assert.Equal(t, "1", heartbeats[0].RequestID, "Heartbeats received before the call should be discarded")
+		assert.Equal(t, "2", heartbeats[1].RequestID)
+	}
+
+	heartbeats, err = sim.AwaitHeartbeats(context.Background(), 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, heartbeats)
+}
+
+func TestStatus_Succeeded(t *testing.T) {
+	success, failure, message := model.Success, model.Failure, "bad policy"
+	assert.NoError(t, (&Status{Response: &model.PdpResponseDetails{ResponseStatus: &success}}).Succeeded())
+	assert.EqualError(t, (&Status{Response: &model.PdpResponseDetails{ResponseStatus: &failure, ResponseMessage: &message}}).Succeeded(),
+		"PDP responded with FAILURE: bad policy")
+	assert.Error(t, (&Status{RequestID: "1"}).Succeeded())
+}
+
+func TestCheckHeartbeatTiming(t *testing.T) {
+	start := time.Now()
+	heartbeats := []*Status{{ReceivedAt: start}, {ReceivedAt: start.Add(time.Second)}, {ReceivedAt: start.Add(2100 * time.Millisecond)}}
+	assert.NoError(t, CheckHeartbeatTiming(heartbeats, time.Second, 20))
+	assert.Error(t, CheckHeartbeatTiming(heartbeats, time.Second, 5))
+	assert.Error(t, CheckHeartbeatTiming(heartbeats[:1], time.Second, 20))
+}
@@ -0,0 +1,180 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package papsim
+
+import (
+	"context"
+	"fmt"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"time"
+)
+
+// the number of heartbeats whose timing is checked by the lifecycle scenario
+const lifecycleHeartbeats = 3
+
+// Step is one action of a protocol scenario.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, s *Simulator) error
+}
+
+// Scenario is an ordered list of steps played against one PDP.
+type Scenario struct {
+	Name  string
+	Steps []Step
+}
+
+// Runs the steps in order and stops at the first failing one.
+func (sc Scenario) Run(ctx context.Context, s *Simulator) error {
+	for _, step := range sc.Steps {
+		log.Infof("PAP simulator scenario %q: %s", sc.Name, step.Name)
+		if err := step.Run(ctx, s); err != nil {
+			return fmt.Errorf("scenario %q failed at step %q: %w", sc.Name, step.Name, err)
+		}
+	}
+	log.Infof("PAP simulator scenario %q passed", sc.Name)
+	return nil
+}
+
+// Waits for the PDP to register.
+func ExpectRegistration() Step {
+	return Step{Name: "expect registration", Run: func(ctx context.Context, s *Simulator) error {
+		status, err := s.AwaitRegistration(ctx)
+		if err != nil {
+			return err
+		}
+		if status.Name == "" || status.PdpGroup == "" {
+			return fmt.Errorf("registration without PDP name or group: name=%q group=%q", status.Name, status.PdpGroup)
+		}
+		return nil
+	}}
+}
+
+// Deploys the policies with a PDP_UPDATE and checks they are reported as deployed.
+func Deploy(heartbeatInterval time.Duration, policies ...model.ToscaConceptIdentifier) Step {
+	return Step{Name: fmt.Sprintf("deploy %d policies", len(policies)), Run: func(ctx context.Context, s *Simulator) error {
+		status, err := s.Update(ctx, heartbeatInterval.Milliseconds(), policies, nil)
+		if err != nil {
+			return err
+		}
+		if err := status.Succeeded(); err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			if !status.HasPolicy(policy) {
+				return fmt.Errorf("policy %s:%s is not reported as deployed", policy.Name, policy.Version)
+			}
+		}
+		return nil
+	}}
+}
+
+// Undeploys the policies with a PDP_UPDATE and checks they are no longer reported.
+func Undeploy(heartbeatInterval time.Duration, policies ...model.ToscaConceptIdentifier) Step {
+	return Step{Name: fmt.Sprintf("undeploy %d policies", len(policies)), Run: func(ctx context.Context, s *Simulator) error {
+		status, err := s.Update(ctx, heartbeatInterval.Milliseconds(), nil, policies)
+		if err != nil {
+			return err
+		}
+		if err := status.Succeeded(); err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			if status.HasPolicy(policy) {
+				return fmt.Errorf("policy %s:%s is still reported as deployed", policy.Name, policy.Version)
+			}
+		}
+		return nil
+	}}
+}
+
+// Moves the PDP to state with a PDP_STATE_CHANGE and checks the reported state.
+func ChangeState(state model.PdpState) Step {
+	return Step{Name: "change state to " + state.String(), Run: func(ctx context.Context, s *Simulator) error {
+		status, err := s.StateChange(ctx, state)
+		if err != nil {
+			return err
+		}
+		if err := status.Succeeded(); err != nil {
+			return err
+		}
+		if status.State != state.String() {
+			return fmt.Errorf("PDP reports state %s, expected %s", status.State, state)
+		}
+		return nil
+	}}
+}
+
+// Sends a PDP_HEALTH_CHECK and expects a healthy PDP.
+func ExpectHealthy() Step {
+	return Step{Name: "expect healthy", Run: func(ctx context.Context, s *Simulator) error {
+		status, err := s.HealthCheck(ctx)
+		if err != nil {
+			return err
+		}
+		if err := status.Succeeded(); err != nil {
+			return err
+		}
+		if status.Healthy != model.Healthy.String() {
+			return fmt.Errorf("PDP reports %s", status.Healthy)
+		}
+		return nil
+	}}
+}
+
+// Sends a PDP_TOPIC_CHECK and expects the PDP to answer it.
+func ExpectTopicCheck() Step {
+	return Step{Name: "expect topic check", Run: func(ctx context.Context, s *Simulator) error {
+		status, err := s.TopicCheck(ctx)
+		if err != nil {
+			return err
+		}
+		return status.Succeeded()
+	}}
+}
+
+// Collects count heartbeats and checks they arrive the interval apart within tolerancePercent.
+func ExpectHeartbeats(count int, interval time.Duration, tolerancePercent int) Step {
+	return Step{Name: fmt.Sprintf("expect %d heartbeats every %v", count, interval), Run: func(ctx context.Context, s *Simulator) error {
+		heartbeats, err := s.AwaitHeartbeats(ctx, count)
+		if err != nil {
+			return err
+		}
+		return CheckHeartbeatTiming(heartbeats, interval, tolerancePercent)
+	}}
+}
+
+// Plays the lifecycle of a PDP: registration, deployment, activation, health and topic checks,
+// heartbeats, undeployment and deactivation.
+func Lifecycle(heartbeatInterval time.Duration, tolerancePercent int, policies ...model.ToscaConceptIdentifier) Scenario {
+	return Scenario{
+		Name: "lifecycle",
+		Steps: []Step{
+			ExpectRegistration(),
+			Deploy(heartbeatInterval, policies...),
+			ChangeState(model.Active),
+			ExpectHealthy(),
+			ExpectTopicCheck(),
+			ExpectHeartbeats(lifecycleHeartbeats, heartbeatInterval, tolerancePercent),
+			Undeploy(heartbeatInterval, policies...),
+			ChangeState(model.Passive),
+		},
+	}
+}
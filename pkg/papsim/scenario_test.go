@@ -0,0 +1,80 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package papsim
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"policy-opa-pdp/pkg/kafkacomm/handler"
+	"policy-opa-pdp/pkg/kafkacomm/publisher"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/transport"
+	"testing"
+	"time"
+)
+
+// runs the PDP message handler, heartbeats and registration on the PDP side of an in-memory transport
+func startPdp(t *testing.T, ctx context.Context) *transport.InMemory {
+	assert.NoError(t, pdpstate.TransitionTo(model.Passive))
+	tr := transport.NewInMemory(100)
+	sender := &publisher.RealPdpStatusSender{Transport: tr}
+	heartbeater := publisher.NewHeartbeater(sender, 0)
+	publisher.SetDefaultHeartbeater(heartbeater)
+	heartbeater.Start(ctx)
+	go handler.PdpMessageHandler(ctx, tr, sender)
+	assert.NoError(t, publisher.SendPdpPapRegistration(sender))
+	return tr
+}
+
+func TestLifecycle_InMemoryPdp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr := startPdp(t, ctx)
+	defer tr.Close()
+	defer publisher.StopHeartbeat()
+
+	sim := New(tr.Peer(), "opa")
+	sim.Timeout = 2 * time.Second
+	sim.Start(ctx)
+	defer sim.Stop()
+
+	scenario := Lifecycle(200*time.Millisecond, 50,
+		model.ToscaConceptIdentifier{Name: "role", Version: "1.0.0"},
+		model.ToscaConceptIdentifier{Name: "abac", Version: "1.0.1"})
+	assert.NoError(t, scenario.Run(ctx, sim))
+	assert.Equal(t, model.Passive, pdpstate.GetState())
+}
+
+func TestScenario_StopsAtFailingStep(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) Step {
+		return Step{Name: name, Run: func(ctx context.Context, s *Simulator) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+	scenario := Scenario{Name: "failing", Steps: []Step{step("first", nil), step("second", errors.New("boom")), step("third", nil)}}
+
+	err := scenario.Run(context.Background(), New(transport.NewInMemory(1).Peer(), "opa"))
+	assert.EqualError(t, err, `scenario "failing" failed at step "second": boom`)
+	assert.Equal(t, []string{"first", "second"}, ran)
+}
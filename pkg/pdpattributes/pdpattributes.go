@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -17,24 +17,116 @@
 //   ========================LICENSE_END===================================
 
 // The pdpattributes package provides utilities for managing and configuring attributes related to the
-// Policy Decision Point (PDP). This includes generating unique PDP names, and setting or retrieving
+// Policy Decision Point (PDP). This includes resolving the PDP name, and setting or retrieving
 // subgroup and heartbeat interval values.
 package pdpattributes
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"os"
+	"policy-opa-pdp/cfg"
 	"policy-opa-pdp/pkg/log"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
 )
 
 var (
-	PdpName              string // A unique identifier for the PDP instance
-	PdpSubgroup          string
-	PdpHeartbeatInterval int64 // The interval (in seconds) at which the PDP sends heartbeat signals
+	PdpName                string // A unique identifier for the PDP instance
+	PdpGroup               string // The primary PDP group, PdpSubgroup is the subgroup assigned within it
+	PdpSubgroup            string
+	PdpHeartbeatInterval   int64             // The interval (in seconds) at which the PDP sends heartbeat signals
+	DeploymentInstanceInfo string            // The pod and node identity reported to PAP
+	pdpNameConfigured      bool              // true when PdpName was built from configuration
+	groupSubgroups         map[string]string // subgroups assigned in the groups other than the primary one
+	groupsMu               sync.RWMutex      // guards the groups, subgroups and heartbeat interval
 )
 
+// InstanceIdentity holds the values available to the PDP name template.
+type InstanceIdentity struct {
+	PodName   string
+	Namespace string
+	NodeName  string
+	Hostname  string
+}
+
 func init() {
-	PdpName = GenerateUniquePdpName()
+	identity := currentInstanceIdentity()
+	PdpName, pdpNameConfigured = ResolvePdpName(cfg.PdpNameTemplate, identity)
+	DeploymentInstanceInfo = BuildDeploymentInstanceInfo(identity)
+	SetPdpGroups(cfg.PdpGroups)
 	log.Debugf("Name: %s", PdpName)
+	log.Debugf("Groups: %v", GetPdpGroups())
+}
+
+// collects the identity of the running instance from the configuration and the host
+func currentInstanceIdentity() InstanceIdentity {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Warnf("Unable to determine hostname: %v", err)
+	}
+	return InstanceIdentity{
+		PodName:   cfg.PodName,
+		Namespace: cfg.PodNamespace,
+		NodeName:  cfg.NodeName,
+		Hostname:  hostname,
+	}
+}
+
+// Resolves the PDP name from the configured template, or from the pod name when no template is set.
+// A unique name is generated only when neither yields a name. The second return value reports
+// whether the name came from configuration.
+func ResolvePdpName(nameTemplate string, identity InstanceIdentity) (string, bool) {
+	if nameTemplate != "" {
+		name, err := renderPdpName(nameTemplate, identity)
+		if err != nil {
+			log.Warnf("Invalid PDP name template %q: %v", nameTemplate, err)
+		} else if name != "" {
+			return name, true
+		}
+	}
+	if identity.PodName != "" {
+		return identity.PodName, true
+	}
+	return GenerateUniquePdpName(), false
+}
+
+// renders the PDP name template, e.g. "opa-{{.PodName}}"
+func renderPdpName(nameTemplate string, identity InstanceIdentity) (string, error) {
+	tmpl, err := template.New("pdpName").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", err
+	}
+	var name strings.Builder
+	if err := tmpl.Execute(&name, identity); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(name.String()), nil
+}
+
+// Builds the deploymentInstanceInfo reported in PDP_STATUS from the instance identity.
+func BuildDeploymentInstanceInfo(identity InstanceIdentity) string {
+	var parts []string
+	if identity.PodName != "" {
+		parts = append(parts, fmt.Sprintf("pod=%s", identity.PodName))
+	}
+	if identity.Namespace != "" {
+		parts = append(parts, fmt.Sprintf("namespace=%s", identity.Namespace))
+	}
+	if identity.NodeName != "" {
+		parts = append(parts, fmt.Sprintf("node=%s", identity.NodeName))
+	}
+	if identity.Hostname != "" {
+		parts = append(parts, fmt.Sprintf("host=%s", identity.Hostname))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Reports whether the PDP name was taken from configuration rather than generated.
+var IsPdpNameConfigured = func() bool {
+	return pdpNameConfigured
 }
 
 // Generates a unique PDP name by appending a randomly generated UUID
@@ -44,21 +136,98 @@ func GenerateUniquePdpName() string {
 
 // sets the Pdp Subgroup retrieved from the message from Pap
 func SetPdpSubgroup(pdpsubgroup string) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
 	PdpSubgroup = pdpsubgroup
 }
 
 // Retrieves the current PDP subgroup value.
 func GetPdpSubgroup() string {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
 	return PdpSubgroup
 }
 
+// Sets the PDP groups this PDP belongs to, the first one becomes the primary group.
+// Subgroup assignments of the previous groups are discarded.
+func SetPdpGroups(groups []string) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	PdpGroup = ""
+	PdpSubgroup = ""
+	groupSubgroups = make(map[string]string)
+	for i, group := range groups {
+		if i == 0 {
+			PdpGroup = group
+			continue
+		}
+		groupSubgroups[group] = ""
+	}
+}
+
+// Retrieves the primary PDP group.
+func GetPdpGroup() string {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+	return PdpGroup
+}
+
+// Retrieves every PDP group this PDP belongs to, the primary group first.
+func GetPdpGroups() []string {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+	others := make([]string, 0, len(groupSubgroups))
+	for group := range groupSubgroups {
+		others = append(others, group)
+	}
+	sort.Strings(others)
+	return append([]string{PdpGroup}, others...)
+}
+
+// Reports whether this PDP belongs to the group, either by configuration or by a PAP assignment.
+func IsPdpGroupMember(group string) bool {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+	if group == PdpGroup {
+		return true
+	}
+	_, ok := groupSubgroups[group]
+	return ok
+}
+
+// Sets the subgroup PAP assigned to this PDP within the group, joining the group if needed.
+// An empty group refers to the primary group.
+func SetPdpGroupSubgroup(group string, subgroup string) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	if group == "" || group == PdpGroup {
+		PdpSubgroup = subgroup
+		return
+	}
+	groupSubgroups[group] = subgroup
+}
+
+// Retrieves the subgroup assigned to this PDP within the group. An empty group refers to the primary group.
+func GetPdpGroupSubgroup(group string) string {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+	if group == "" || group == PdpGroup {
+		return PdpSubgroup
+	}
+	return groupSubgroups[group]
+}
+
 // sets the PdpHeratbeatInterval retrieved from the message from Pap
 func SetPdpHeartbeatInterval(pdpHeartbeatInterval int64) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
 	PdpHeartbeatInterval = pdpHeartbeatInterval
 }
 
 // Retrieves the current PDP heartbeat interval value.
 func GetPdpHeartbeatInterval() int64 {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
 	return PdpHeartbeatInterval
 
 }
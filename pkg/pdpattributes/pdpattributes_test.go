@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -20,6 +20,7 @@
 package pdpattributes
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -86,3 +87,97 @@ func TestSetPdpHeartbeatInterval_Failure(t *testing.T) {
 		assert.Equal(t, largeInterval, GetPdpHeartbeatInterval(), "Expected PDP heartbeat interval to handle large values")
 	})
 }
+
+func TestResolvePdpName(t *testing.T) {
+	identity := InstanceIdentity{PodName: "policy-opa-pdp-0", Namespace: "onap", NodeName: "worker-1", Hostname: "host-1"}
+
+	t.Run("Template", func(t *testing.T) {
+		name, configured := ResolvePdpName("opa-{{.Namespace}}-{{.PodName}}", identity)
+		assert.Equal(t, "opa-onap-policy-opa-pdp-0", name)
+		assert.True(t, configured)
+	})
+
+	t.Run("PlainName", func(t *testing.T) {
+		name, configured := ResolvePdpName("opa-pdp", identity)
+		assert.Equal(t, "opa-pdp", name)
+		assert.True(t, configured)
+	})
+
+	t.Run("PodNameWhenNoTemplate", func(t *testing.T) {
+		name, configured := ResolvePdpName("", identity)
+		assert.Equal(t, "policy-opa-pdp-0", name)
+		assert.True(t, configured)
+	})
+
+	t.Run("InvalidTemplateFallsBack", func(t *testing.T) {
+		name, configured := ResolvePdpName("opa-{{.Unknown}}", identity)
+		assert.Equal(t, "policy-opa-pdp-0", name)
+		assert.True(t, configured)
+	})
+
+	t.Run("GeneratedWhenNothingSet", func(t *testing.T) {
+		name, configured := ResolvePdpName("", InstanceIdentity{Hostname: "host-1"})
+		assert.Len(t, name, len("opa-")+36)
+		assert.False(t, configured)
+	})
+}
+
+func TestBuildDeploymentInstanceInfo(t *testing.T) {
+	info := BuildDeploymentInstanceInfo(InstanceIdentity{PodName: "policy-opa-pdp-0", Namespace: "onap", NodeName: "worker-1", Hostname: "host-1"})
+	assert.Equal(t, "pod=policy-opa-pdp-0,namespace=onap,node=worker-1,host=host-1", info)
+
+	assert.Equal(t, "host=host-1", BuildDeploymentInstanceInfo(InstanceIdentity{Hostname: "host-1"}))
+	assert.Equal(t, "", BuildDeploymentInstanceInfo(InstanceIdentity{}))
+}
+
+func TestPdpGroups(t *testing.T) {
+	original := GetPdpGroups()
+	defer SetPdpGroups(original)
+
+	SetPdpGroups([]string{"tenantA", "tenantC", "tenantB"})
+	assert.Equal(t, "tenantA", GetPdpGroup())
+	assert.Equal(t, []string{"tenantA", "tenantB", "tenantC"}, GetPdpGroups())
+	assert.True(t, IsPdpGroupMember("tenantB"))
+	assert.False(t, IsPdpGroupMember("tenantD"))
+
+	SetPdpGroupSubgroup("tenantA", "opa-a")
+	SetPdpGroupSubgroup("tenantB", "opa-b")
+	assert.Equal(t, "opa-a", GetPdpSubgroup(), "The primary group subgroup should be the PDP subgroup")
+	assert.Equal(t, "opa-a", GetPdpGroupSubgroup(""))
+	assert.Equal(t, "opa-b", GetPdpGroupSubgroup("tenantB"))
+	assert.Equal(t, "", GetPdpGroupSubgroup("tenantC"))
+
+	SetPdpGroupSubgroup("tenantD", "opa-d")
+	assert.True(t, IsPdpGroupMember("tenantD"), "A PAP assignment should join the group")
+	assert.Equal(t, "opa-d", GetPdpGroupSubgroup("tenantD"))
+}
+
+func TestPdpAttributes_Concurrent(t *testing.T) {
+	originalGroups, originalInterval := GetPdpGroups(), GetPdpHeartbeatInterval()
+	defer func() {
+		SetPdpGroups(originalGroups)
+		SetPdpHeartbeatInterval(originalInterval)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			SetPdpSubgroup("opa")
+			SetPdpHeartbeatInterval(60000)
+		}()
+		go func() {
+			defer wg.Done()
+			SetPdpGroupSubgroup("", "opa")
+		}()
+		go func() {
+			defer wg.Done()
+			GetPdpSubgroup()
+			GetPdpHeartbeatInterval()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "opa", GetPdpSubgroup())
+	assert.Equal(t, int64(60000), GetPdpHeartbeatInterval())
+}
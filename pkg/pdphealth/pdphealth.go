@@ -0,0 +1,87 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The pdphealth package aggregates the health of the PDP components. Components register a
+// check under a name and the PDP reports NOT_HEALTHY to PAP as soon as one of them fails.
+// A healthy PDP in TEST state reports TEST_IN_PROGRESS.
+package pdphealth
+
+import (
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpstate"
+	"sort"
+	"sync"
+)
+
+// Check reports the health of a PDP component, returning an error when it is not healthy.
+type Check func() error
+
+// Report is the outcome of evaluating every registered check.
+type Report struct {
+	Status   model.PdpHealthStatus
+	Failures map[string]string // failing components and the reason they fail
+}
+
+var (
+	checks = make(map[string]Check)
+	mu     sync.RWMutex
+)
+
+// Registers the check of a component, replacing any check registered under the same name.
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = check
+}
+
+// Removes every registered check.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = make(map[string]Check)
+}
+
+// Runs every registered check. The PDP is HEALTHY when all of them pass, or TEST_IN_PROGRESS
+// when they pass while the PDP is in TEST state.
+func Evaluate() Report {
+	mu.RLock()
+	defer mu.RUnlock()
+	report := Report{Status: model.Healthy, Failures: make(map[string]string)}
+	for name, check := range checks {
+		if err := check(); err != nil {
+			log.Warnf("Health check %s failed: %v", name, err)
+			report.Failures[name] = err.Error()
+			report.Status = model.NotHealthy
+		}
+	}
+	if report.Status == model.Healthy && pdpstate.GetCurrentState() == model.Test {
+		report.Status = model.TestInProgress
+	}
+	return report
+}
+
+// Returns the failing components sorted by name.
+func (r Report) FailedComponents() []string {
+	names := make([]string, 0, len(r.Failures))
+	for name := range r.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
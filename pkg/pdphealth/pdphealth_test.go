@@ -0,0 +1,80 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package pdphealth
+
+import (
+	"errors"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpstate"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate_NoChecks(t *testing.T) {
+	Reset()
+	report := Evaluate()
+	assert.Equal(t, model.Healthy, report.Status)
+	assert.Empty(t, report.Failures)
+}
+
+func TestEvaluate_AllHealthy(t *testing.T) {
+	Reset()
+	defer Reset()
+	Register("opa", func() error { return nil })
+	Register("bundle", func() error { return nil })
+
+	assert.Equal(t, model.Healthy, Evaluate().Status)
+}
+
+func TestEvaluate_FailingComponent(t *testing.T) {
+	Reset()
+	defer Reset()
+	Register("opa", func() error { return nil })
+	Register("bundle", func() error { return errors.New("bundle missing") })
+	Register("kafka", func() error { return errors.New("no producer") })
+
+	report := Evaluate()
+	assert.Equal(t, model.NotHealthy, report.Status)
+	assert.Equal(t, "bundle missing", report.Failures["bundle"])
+	assert.Equal(t, []string{"bundle", "kafka"}, report.FailedComponents())
+}
+
+func TestEvaluate_TestInProgress(t *testing.T) {
+	Reset()
+	defer Reset()
+	original := pdpstate.GetCurrentState
+	defer func() { pdpstate.GetCurrentState = original }()
+	pdpstate.GetCurrentState = func() model.PdpState { return model.Test }
+
+	Register("opa", func() error { return nil })
+	assert.Equal(t, model.TestInProgress, Evaluate().Status)
+
+	Register("bundle", func() error { return errors.New("bundle missing") })
+	assert.Equal(t, model.NotHealthy, Evaluate().Status, "A failing component wins over TEST state")
+}
+
+func TestRegister_ReplacesCheck(t *testing.T) {
+	Reset()
+	defer Reset()
+	Register("bundle", func() error { return errors.New("bundle missing") })
+	Register("bundle", func() error { return nil })
+
+	assert.Equal(t, model.Healthy, Evaluate().Status)
+}
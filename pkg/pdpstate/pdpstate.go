@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -18,29 +18,125 @@
 
 // The pdpstate package manages the state of the Policy Decision Point (PDP), allowing for dynamic updates
 // and retrieval of the PDP's current operational state. States are represented using the model.PdpState type.
+// State changes are validated against the allowed transitions and can be observed by other components.
 package pdpstate
 
 import (
+	"fmt"
+	"policy-opa-pdp/pkg/log"
 	"policy-opa-pdp/pkg/model"
+	"sync"
 )
 
+// StateObserver is notified after the PDP has moved from oldState to newState.
+// Observers run synchronously and must not change the state themselves.
+type StateObserver func(oldState, newState model.PdpState)
+
 var (
-	State           model.PdpState = model.Passive // The current state of the PDP.
-	GetCurrentState                = GetState      // An alias for the GetState function.
+	state           model.PdpState = model.Passive // The current state of the PDP.
+	stateMu         sync.RWMutex                   // guards state
+	transitionMu    sync.Mutex                     // serialises transitions and observer notification
+	observers       []subscription
+	nextObserverId  int
+	GetCurrentState = GetState // An alias for the GetState function.
 )
 
+type subscription struct {
+	id       int
+	observer StateObserver
+}
+
+// allowedTransitions lists the states each state may move to. A PASSIVE PDP has to be
+// activated before it can enter SAFE or TEST, SAFE and TEST return to ACTIVE or PASSIVE
+// rather than switching into each other, and TERMINATED is final.
+var allowedTransitions = map[model.PdpState][]model.PdpState{
+	model.Passive:    {model.Active, model.Terminated},
+	model.Active:     {model.Passive, model.Safe, model.Test, model.Terminated},
+	model.Safe:       {model.Active, model.Passive, model.Terminated},
+	model.Test:       {model.Active, model.Passive, model.Terminated},
+	model.Terminated: {},
+}
+
 // sets the Pdp State retrieved from the message from Pap
 func SetState(stringState string) error {
 	newState, err := model.ConvertStringToEnumState(stringState)
 	if err != nil {
 		return err
 	}
+	return TransitionTo(newState)
+}
+
+// Moves the PDP to newState if the transition is allowed and notifies the observers.
+// Requesting the current state again is accepted and does not notify anyone.
+func TransitionTo(newState model.PdpState) error {
+	transitionMu.Lock()
+	defer transitionMu.Unlock()
+
+	oldState := GetState()
+	if oldState == newState {
+		return nil
+	}
+	if !IsTransitionAllowed(oldState, newState) {
+		return fmt.Errorf("PDP state transition from %s to %s is not allowed", oldState, newState)
+	}
+
+	stateMu.Lock()
+	state = newState
+	stateMu.Unlock()
+	log.Infof("PDP state changed from %s to %s", oldState, newState)
 
-	State = newState
+	for _, sub := range observers {
+		sub.observer(oldState, newState)
+	}
 	return nil
 }
 
+// Reports whether the PDP may move from one state to another.
+func IsTransitionAllowed(from, to model.PdpState) bool {
+	if from == to {
+		return true
+	}
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Registers an observer that is called after every state change. The returned function
+// removes the observer again.
+func Subscribe(observer StateObserver) (unsubscribe func()) {
+	transitionMu.Lock()
+	defer transitionMu.Unlock()
+	nextObserverId++
+	id := nextObserverId
+	observers = append(observers, subscription{id: id, observer: observer})
+	return func() {
+		transitionMu.Lock()
+		defer transitionMu.Unlock()
+		for i, sub := range observers {
+			if sub.id == id {
+				observers = append(observers[:i:i], observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Puts the PDP back to PASSIVE without validating the transition or notifying observers,
+// as on a restart of the PDP.
+func Reset() {
+	transitionMu.Lock()
+	defer transitionMu.Unlock()
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state = model.Passive
+}
+
 // Retrieves the current PDP state.
 func GetState() model.PdpState {
-	return State
+	stateMu.RLock()
+	defer stateMu.RUnlock()
+	return state
 }
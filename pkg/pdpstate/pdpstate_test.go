@@ -1,6 +1,6 @@
 // -
 //   ========================LICENSE_START=================================
-//   Copyright (C) 2024: Deutsche Telekom
+//   Copyright (C) 2024-2025: Deutsche Telekom
 //
 //   Licensed under the Apache License, Version 2.0 (the "License");
 //   you may not use this file except in compliance with the License.
@@ -20,12 +20,31 @@
 package pdpstate
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"policy-opa-pdp/pkg/model"
 )
 
+// resets the package state between tests
+func resetState(t *testing.T) {
+	stateMu.Lock()
+	state = model.Passive
+	stateMu.Unlock()
+	transitionMu.Lock()
+	observers = nil
+	transitionMu.Unlock()
+	t.Cleanup(func() {
+		stateMu.Lock()
+		state = model.Passive
+		stateMu.Unlock()
+		transitionMu.Lock()
+		observers = nil
+		transitionMu.Unlock()
+	})
+}
+
 func TestSetState_Success(t *testing.T) {
 	t.Run("ValidState", func(t *testing.T) {
 		err := SetState("ACTIVE")
@@ -35,10 +54,123 @@ func TestSetState_Success(t *testing.T) {
 }
 
 func TestSetState_Failure(t *testing.T) {
-	State = model.Passive
+	resetState(t)
 	t.Run("InvalidState", func(t *testing.T) {
 		err := SetState("InvalidState")
 		assert.Error(t, err, "Expected an error for invalid state")
 		assert.Equal(t, model.Passive, GetState(), "Expected state to remain unchanged when setting invalid state")
 	})
 }
+
+func TestSetState_TerminatedIsFinal(t *testing.T) {
+	resetState(t)
+	assert.NoError(t, SetState("TERMINATED"))
+
+	err := SetState("ACTIVE")
+	assert.Error(t, err, "Expected an error when leaving TERMINATED")
+	assert.Equal(t, model.Terminated, GetState())
+}
+
+func TestIsTransitionAllowed(t *testing.T) {
+	tests := []struct {
+		from, to model.PdpState
+		allowed  bool
+	}{
+		{model.Passive, model.Active, true},
+		{model.Passive, model.Terminated, true},
+		{model.Passive, model.Safe, false},
+		{model.Passive, model.Test, false},
+		{model.Active, model.Passive, true},
+		{model.Active, model.Safe, true},
+		{model.Active, model.Test, true},
+		{model.Active, model.Terminated, true},
+		{model.Safe, model.Active, true},
+		{model.Safe, model.Passive, true},
+		{model.Safe, model.Test, false},
+		{model.Test, model.Active, true},
+		{model.Test, model.Passive, true},
+		{model.Test, model.Safe, false},
+		{model.Test, model.Test, true},
+		{model.Terminated, model.Passive, false},
+		{model.Terminated, model.Active, false},
+		{model.PdpState(-1), model.Active, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, IsTransitionAllowed(tt.from, tt.to), "%s to %s", tt.from, tt.to)
+	}
+}
+
+func TestTransitionTo_NotAllowed(t *testing.T) {
+	resetState(t)
+
+	err := TransitionTo(model.Safe)
+	assert.Error(t, err, "Expected an error entering SAFE from PASSIVE")
+	assert.Equal(t, model.Passive, GetState())
+}
+
+func TestSubscribe_NotifiedOnChange(t *testing.T) {
+	resetState(t)
+
+	var transitions [][2]model.PdpState
+	Subscribe(func(oldState, newState model.PdpState) {
+		transitions = append(transitions, [2]model.PdpState{oldState, newState})
+	})
+
+	assert.NoError(t, TransitionTo(model.Active))
+	assert.NoError(t, TransitionTo(model.Active))
+	assert.NoError(t, TransitionTo(model.Test))
+
+	assert.Equal(t, [][2]model.PdpState{
+		{model.Passive, model.Active},
+		{model.Active, model.Test},
+	}, transitions, "Observers should only be told about actual changes")
+}
+
+func TestTransitionTo_Concurrent(t *testing.T) {
+	resetState(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			TransitionTo(model.Active)
+		}()
+		go func() {
+			defer wg.Done()
+			GetState()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, model.Active, GetState())
+}
+
+func TestSubscribe_Unsubscribe(t *testing.T) {
+	resetState(t)
+
+	var first, second int
+	unsubscribeFirst := Subscribe(func(oldState, newState model.PdpState) { first++ })
+	Subscribe(func(oldState, newState model.PdpState) { second++ })
+
+	assert.NoError(t, TransitionTo(model.Active))
+	unsubscribeFirst()
+	unsubscribeFirst()
+	assert.NoError(t, TransitionTo(model.Passive))
+
+	assert.Equal(t, 1, first, "An unsubscribed observer should not be notified")
+	assert.Equal(t, 2, second)
+}
+
+func TestReset(t *testing.T) {
+	resetState(t)
+
+	notified := false
+	Subscribe(func(oldState, newState model.PdpState) { notified = true })
+	stateMu.Lock()
+	state = model.Terminated
+	stateMu.Unlock()
+
+	Reset()
+	assert.Equal(t, model.Passive, GetState())
+	assert.False(t, notified, "Reset should not notify observers")
+}
@@ -0,0 +1,206 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The persistence package optionally stores what PAP has told the PDP in a local directory,
+// so that a restarted instance can restore its name, PDP_UPDATE attributes, policies and state
+// and serve decisions before PAP re-sends everything. Files are written atomically by writing
+// a temporary file in the same directory and renaming it over the previous snapshot.
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/log"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
+	"sync"
+)
+
+// Snapshot is the PDP state kept in the persistence directory.
+type Snapshot struct {
+	PdpName   string                   `json:"pdpName"`
+	State     string                   `json:"state"`
+	PdpUpdate *model.PdpUpdate         `json:"pdpUpdate,omitempty"` // the last applied PDP_UPDATE
+	Groups    map[string]GroupSnapshot `json:"groups,omitempty"`
+}
+
+// GroupSnapshot is the subgroup assignment and policy set of a PDP group.
+type GroupSnapshot struct {
+	PdpSubgroup string                         `json:"pdpSubgroup"`
+	Policies    []model.ToscaConceptIdentifier `json:"policies"`
+}
+
+var mu sync.Mutex // serialises read-modify-write cycles on the snapshot file
+
+// Reports whether a persistence directory is configured.
+func Enabled() bool {
+	return cfg.PersistenceDir != ""
+}
+
+func snapshotPath() string {
+	return filepath.Join(cfg.PersistenceDir, consts.PersistenceSnapshotFile)
+}
+
+// Reads the persisted snapshot, returning nil if nothing has been stored yet.
+func Load() (*Snapshot, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+func load() (*Snapshot, error) {
+	data, err := os.ReadFile(snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Atomically replaces the persisted snapshot.
+func save(snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding snapshot: %w", err)
+	}
+	if err := os.MkdirAll(cfg.PersistenceDir, 0o750); err != nil {
+		return fmt.Errorf("error creating persistence directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(cfg.PersistenceDir, consts.PersistenceSnapshotFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing temporary snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing temporary snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), snapshotPath()); err != nil {
+		return fmt.Errorf("error replacing snapshot: %w", err)
+	}
+	return nil
+}
+
+// Loads the snapshot, lets modify change it and stores the result.
+func update(modify func(*Snapshot)) error {
+	if !Enabled() {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	snapshot, err := load()
+	if err != nil {
+		log.Warnf("Discarding unreadable snapshot: %v", err)
+	}
+	if snapshot == nil {
+		snapshot = &Snapshot{State: model.Passive.String()}
+	}
+	snapshot.PdpName = pdpattributes.PdpName
+	modify(snapshot)
+	return save(snapshot)
+}
+
+// Stores the last applied PDP_UPDATE together with the resulting policy set of its group.
+func SavePdpUpdate(pdpUpdate *model.PdpUpdate, policies []model.ToscaConceptIdentifier) error {
+	return update(func(snapshot *Snapshot) {
+		snapshot.PdpUpdate = pdpUpdate
+		if snapshot.Groups == nil {
+			snapshot.Groups = make(map[string]GroupSnapshot)
+		}
+		snapshot.Groups[pdpUpdate.PdpGroup] = GroupSnapshot{PdpSubgroup: pdpUpdate.PdpSubgroup, Policies: policies}
+	})
+}
+
+// Stores the current PDP state.
+func SaveState(state model.PdpState) error {
+	return update(func(snapshot *Snapshot) {
+		snapshot.State = state.String()
+	})
+}
+
+// Restores the persisted PDP name, PDP_UPDATE attributes, policies and state.
+// A TERMINATED state is not restored so that the new instance can be reused by PAP.
+func Restore() error {
+	if !Enabled() {
+		return nil
+	}
+	snapshot, err := Load()
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		log.Debugf("No persisted PDP snapshot found in %s", cfg.PersistenceDir)
+		return nil
+	}
+
+	// a name taken from configuration wins over the persisted one
+	if snapshot.PdpName != "" && !pdpattributes.IsPdpNameConfigured() {
+		pdpattributes.PdpName = snapshot.PdpName
+	}
+	if snapshot.PdpUpdate != nil {
+		pdpattributes.SetPdpHeartbeatInterval(snapshot.PdpUpdate.PdpHeartbeatIntervalMs)
+	}
+	policies := 0
+	for group, groupSnapshot := range snapshot.Groups {
+		pdpattributes.SetPdpGroupSubgroup(group, groupSnapshot.PdpSubgroup)
+		policyregistry.Set(group, groupSnapshot.Policies)
+		policies += len(groupSnapshot.Policies)
+	}
+
+	state, err := model.ConvertStringToEnumState(snapshot.State)
+	if err != nil {
+		return err
+	}
+	if state == model.Terminated {
+		log.Infof("Persisted PDP was TERMINATED, starting PASSIVE")
+		state = model.Passive
+	}
+	if !pdpstate.IsTransitionAllowed(pdpstate.GetState(), state) {
+		// SAFE and TEST are only entered from ACTIVE
+		if err := pdpstate.TransitionTo(model.Active); err != nil {
+			return err
+		}
+	}
+	if err := pdpstate.TransitionTo(state); err != nil {
+		return err
+	}
+	log.Infof("Restored PDP %s in state %s with %d policies in %d groups", pdpattributes.PdpName, state, policies, len(snapshot.Groups))
+	return nil
+}
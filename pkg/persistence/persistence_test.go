@@ -0,0 +1,136 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/consts"
+	"policy-opa-pdp/pkg/model"
+	"policy-opa-pdp/pkg/pdpattributes"
+	"policy-opa-pdp/pkg/pdpstate"
+	"policy-opa-pdp/pkg/policyregistry"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// points the persistence directory to a temporary directory for the test
+func withPersistenceDir(t *testing.T) string {
+	dir := t.TempDir()
+	original := cfg.PersistenceDir
+	cfg.PersistenceDir = dir
+	t.Cleanup(func() { cfg.PersistenceDir = original })
+	return dir
+}
+
+func TestDisabled_NoOp(t *testing.T) {
+	original := cfg.PersistenceDir
+	cfg.PersistenceDir = ""
+	defer func() { cfg.PersistenceDir = original }()
+
+	assert.False(t, Enabled())
+	assert.NoError(t, SaveState(model.Active))
+	assert.NoError(t, Restore())
+}
+
+func TestLoad_NoSnapshot(t *testing.T) {
+	withPersistenceDir(t)
+
+	snapshot, err := Load()
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+}
+
+func TestLoad_CorruptSnapshot(t *testing.T) {
+	dir := withPersistenceDir(t)
+	os.WriteFile(filepath.Join(dir, consts.PersistenceSnapshotFile), []byte("{not json"), 0o600)
+
+	_, err := Load()
+	assert.Error(t, err)
+	assert.Error(t, Restore())
+}
+
+func TestSaveAndRestore(t *testing.T) {
+	dir := withPersistenceDir(t)
+
+	pdpattributes.PdpName = "opa-persisted"
+	pdpUpdate := &model.PdpUpdate{PdpGroup: pdpattributes.GetPdpGroup(), PdpSubgroup: "opa", PdpHeartbeatIntervalMs: 60000, RequestId: "req-1"}
+	policies := []model.ToscaConceptIdentifier{{Name: "zone", Version: "1.0.0"}}
+	assert.NoError(t, SavePdpUpdate(pdpUpdate, policies))
+	tenantUpdate := &model.PdpUpdate{PdpGroup: "tenantGroup", PdpSubgroup: "opa-tenant", PdpHeartbeatIntervalMs: 60000, RequestId: "req-2"}
+	tenantPolicies := []model.ToscaConceptIdentifier{{Name: "account", Version: "2.0.0"}}
+	assert.NoError(t, SavePdpUpdate(tenantUpdate, tenantPolicies))
+	assert.NoError(t, SaveState(model.Active))
+
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1, "Temporary files should not be left behind")
+
+	// simulate a restart
+	pdpattributes.PdpName = "opa-new"
+	pdpattributes.SetPdpSubgroup("")
+	pdpattributes.SetPdpHeartbeatInterval(0)
+	pdpattributes.SetPdpGroups([]string{pdpattributes.GetPdpGroup()})
+	policyregistry.Clear()
+
+	assert.NoError(t, Restore())
+	assert.Equal(t, "opa-persisted", pdpattributes.PdpName)
+	assert.Equal(t, "opa", pdpattributes.GetPdpSubgroup())
+	assert.Equal(t, int64(60000), pdpattributes.GetPdpHeartbeatInterval())
+	assert.Equal(t, "opa-tenant", pdpattributes.GetPdpGroupSubgroup("tenantGroup"))
+	assert.Equal(t, policies, policyregistry.ListGroup(pdpattributes.GetPdpGroup()))
+	assert.Equal(t, tenantPolicies, policyregistry.ListGroup("tenantGroup"))
+	assert.Equal(t, model.Active, pdpstate.GetState())
+}
+
+func TestRestore_TerminatedStartsPassive(t *testing.T) {
+	withPersistenceDir(t)
+	assert.NoError(t, pdpstate.TransitionTo(model.Passive))
+	assert.NoError(t, SaveState(model.Terminated))
+
+	assert.NoError(t, Restore())
+	assert.Equal(t, model.Passive, pdpstate.GetState())
+}
+
+func TestRestore_SafeState(t *testing.T) {
+	withPersistenceDir(t)
+	pdpstate.Reset()
+	t.Cleanup(pdpstate.Reset)
+	assert.NoError(t, SaveState(model.Safe))
+
+	assert.NoError(t, Restore())
+	assert.Equal(t, model.Safe, pdpstate.GetState())
+}
+
+func TestRestore_ConfiguredNameWins(t *testing.T) {
+	withPersistenceDir(t)
+
+	pdpattributes.PdpName = "opa-persisted"
+	assert.NoError(t, SaveState(model.Passive))
+
+	original := pdpattributes.IsPdpNameConfigured
+	pdpattributes.IsPdpNameConfigured = func() bool { return true }
+	defer func() { pdpattributes.IsPdpNameConfigured = original }()
+	pdpattributes.PdpName = "opa-pod-0"
+
+	assert.NoError(t, Restore())
+	assert.Equal(t, "opa-pod-0", pdpattributes.PdpName)
+}
@@ -0,0 +1,170 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The policyregistry package keeps track of the policies PAP has deployed to this PDP.
+// PDP_UPDATE messages carry the policies to deploy and undeploy for a PDP group, and the
+// registry holds the resulting policy set of every group apart, so that the PDP_STATUS of a
+// group only reports the policies deployed through that group.
+package policyregistry
+
+import (
+	"policy-opa-pdp/pkg/model"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type policySet map[string]model.ToscaConceptIdentifier // deployed policies keyed by name
+
+var (
+	groups = make(map[string]policySet) // policy sets keyed by PDP group
+	mu     sync.RWMutex
+)
+
+// ApplyResult counts the policies of a PDP_UPDATE that were deployed and undeployed, and those
+// that could not be.
+type ApplyResult struct {
+	Deployed       int
+	DeployFailed   int
+	Undeployed     int
+	UndeployFailed int
+}
+
+// Applies a PDP_UPDATE to the policy set of the group, deploying first and then undeploying.
+// A policy without a name cannot be deployed, and a policy that is not deployed in the group,
+// or is deployed in another version, cannot be undeployed.
+func Apply(group string, toBeDeployed []string, toBeUndeployed []model.ToscaConceptIdentifier) ApplyResult {
+	mu.Lock()
+	defer mu.Unlock()
+	var result ApplyResult
+	policies, ok := groups[group]
+	if !ok {
+		policies = make(policySet)
+		groups[group] = policies
+	}
+	for _, policy := range toBeDeployed {
+		id := model.ParseToscaConceptIdentifier(policy)
+		if id.Name == "" {
+			result.DeployFailed++
+			continue
+		}
+		policies[id.Name] = *id
+		result.Deployed++
+	}
+	for _, id := range toBeUndeployed {
+		deployed, ok := policies[id.Name]
+		if !ok || (id.Version != "" && id.Version != deployed.Version) {
+			result.UndeployFailed++
+			continue
+		}
+		delete(policies, id.Name)
+		result.Undeployed++
+	}
+	return result
+}
+
+// Replaces the policy set of the group, used when restoring a persisted PDP.
+func Set(group string, deployed []model.ToscaConceptIdentifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	policies := make(policySet, len(deployed))
+	for _, id := range deployed {
+		policies[id.Name] = id
+	}
+	groups[group] = policies
+}
+
+// Removes every policy set.
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	groups = make(map[string]policySet)
+}
+
+// Returns the policies deployed through the group sorted by name.
+func ListGroup(group string) []model.ToscaConceptIdentifier {
+	mu.RLock()
+	defer mu.RUnlock()
+	return sorted(groups[group])
+}
+
+// Returns the policies deployed through any group sorted by name.
+func List() []model.ToscaConceptIdentifier {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make(policySet)
+	for _, policies := range groups {
+		for name, id := range policies {
+			all[name] = id
+		}
+	}
+	return sorted(all)
+}
+
+// Returns true when a policy of the name is deployed through any group.
+func isDeployed(name string) bool {
+	for _, policies := range groups {
+		if _, ok := policies[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the deployed policy a decision path such as abac/policy/allow is evaluated in. OPA
+// looks the path up segment by segment under data, where a policy declares its dotted name as
+// package, so the policy is the longest deployed name the leading segments spell. A segment
+// holding a dot is a key of its own and never part of a package name.
+func PolicyOfDecisionPath(path string) (string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if strings.Contains(segment, ".") {
+			segments = segments[:i]
+			break
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for n := len(segments); n > 0; n-- {
+		if name := strings.Join(segments[:n], "."); isDeployed(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Returns the groups holding a policy set sorted by name.
+func Groups() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sorted(policies policySet) []model.ToscaConceptIdentifier {
+	deployed := make([]model.ToscaConceptIdentifier, 0, len(policies))
+	for _, id := range policies {
+		deployed = append(deployed, id)
+	}
+	sort.Slice(deployed, func(i, j int) bool { return deployed[i].Name < deployed[j].Name })
+	return deployed
+}
@@ -0,0 +1,103 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package policyregistry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"policy-opa-pdp/pkg/model"
+	"testing"
+)
+
+func TestApply_DeployAndUndeploy(t *testing.T) {
+	Clear()
+	Apply("opaGroup", []string{"zone:1.0.0", "account"}, nil)
+	assert.Equal(t, []model.ToscaConceptIdentifier{
+		{Name: "account"},
+		{Name: "zone", Version: "1.0.0"},
+	}, List())
+
+	Apply("opaGroup", []string{"role:2.0.0"}, []model.ToscaConceptIdentifier{{Name: "account"}})
+	assert.Equal(t, []model.ToscaConceptIdentifier{
+		{Name: "role", Version: "2.0.0"},
+		{Name: "zone", Version: "1.0.0"},
+	}, List())
+}
+
+func TestApply_Result(t *testing.T) {
+	Clear()
+	result := Apply("opaGroup", []string{"zone:1.0.0", "account", ""}, nil)
+	assert.Equal(t, ApplyResult{Deployed: 2, DeployFailed: 1}, result)
+
+	result = Apply("opaGroup", nil, []model.ToscaConceptIdentifier{
+		{Name: "zone", Version: "2.0.0"},
+		{Name: "account"},
+		{Name: "role", Version: "1.0.0"},
+	})
+	assert.Equal(t, ApplyResult{Undeployed: 1, UndeployFailed: 2}, result)
+	assert.Equal(t, []model.ToscaConceptIdentifier{{Name: "zone", Version: "1.0.0"}}, List(),
+		"A policy deployed in another version should stay deployed")
+}
+
+func TestPolicyOfDecisionPath(t *testing.T) {
+	Clear()
+	Apply("opaGroup", []string{"abac:1.0.0", "abac.policy:1.0.0"}, nil)
+	Apply("otherGroup", []string{"role"}, nil)
+	for path, expected := range map[string]string{
+		"abac/policy/allow":  "abac.policy",
+		"/abac/policy/allow": "abac.policy",
+		"abac/policy":        "abac.policy",
+		"abac/allow":         "abac",
+		"role/allow":         "role",
+	} {
+		name, ok := PolicyOfDecisionPath(path)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, name, path)
+	}
+	for _, path := range []string{"account/allow", "abac.policy", "", "policy/abac/allow"} {
+		_, ok := PolicyOfDecisionPath(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestSet_ReplacesPolicies(t *testing.T) {
+	Clear()
+	Apply("opaGroup", []string{"zone"}, nil)
+	Set("opaGroup", []model.ToscaConceptIdentifier{{Name: "abac", Version: "1.0.0"}})
+	assert.Equal(t, []model.ToscaConceptIdentifier{{Name: "abac", Version: "1.0.0"}}, List())
+
+	Set("opaGroup", nil)
+	assert.Empty(t, List())
+}
+
+func TestGroups_KeptApart(t *testing.T) {
+	Clear()
+	Apply("tenantA", []string{"zone:1.0.0"}, nil)
+	Apply("tenantB", []string{"account:2.0.0"}, nil)
+	Apply("tenantB", nil, []model.ToscaConceptIdentifier{{Name: "zone"}})
+
+	assert.Equal(t, []model.ToscaConceptIdentifier{{Name: "zone", Version: "1.0.0"}}, ListGroup("tenantA"))
+	assert.Equal(t, []model.ToscaConceptIdentifier{{Name: "account", Version: "2.0.0"}}, ListGroup("tenantB"))
+	assert.Empty(t, ListGroup("unknown"))
+	assert.Equal(t, []string{"tenantA", "tenantB"}, Groups())
+	assert.Equal(t, []model.ToscaConceptIdentifier{
+		{Name: "account", Version: "2.0.0"},
+		{Name: "zone", Version: "1.0.0"},
+	}, List())
+}
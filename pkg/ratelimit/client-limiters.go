@@ -0,0 +1,79 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The ratelimit package keeps a token bucket per client, so that the requests of each client are
+// limited apart. Buckets of clients that have gone idle are dropped.
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"policy-opa-pdp/consts"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ClientLimiters holds the token buckets of the clients. It is not safe for concurrent use, its
+// users guard it with their own lock.
+type ClientLimiters struct {
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientLimiter
+	lastPrune time.Time
+}
+
+// clientLimiter is the token bucket of one client
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Creates the token buckets of clients allowed limit requests per second and burst at once.
+func NewClientLimiters(limit rate.Limit, burst int) *ClientLimiters {
+	return &ClientLimiters{limit: limit, burst: burst, clients: make(map[string]*clientLimiter), lastPrune: time.Now()}
+}
+
+// Returns the token bucket of the client, created on its first request. Buckets of clients idle
+// for consts.ClientLimiterIdleSeconds are dropped, they would be full again anyway.
+func (l *ClientLimiters) Limiter(client string, now time.Time) *rate.Limiter {
+	idle := time.Duration(consts.ClientLimiterIdleSeconds) * time.Second
+	if now.Sub(l.lastPrune) >= idle {
+		for key, limiter := range l.clients {
+			if now.Sub(limiter.lastSeen) >= idle {
+				delete(l.clients, key)
+			}
+		}
+		l.lastPrune = now
+	}
+	limiter, exists := l.clients[client]
+	if !exists {
+		limiter = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = limiter
+	}
+	limiter.lastSeen = now
+	return limiter.limiter
+}
+
+// Returns the address of the client a request came from, without its port.
+func ClientAddress(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
@@ -0,0 +1,63 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestClientLimiters_PerClient(t *testing.T) {
+	limiters := NewClientLimiters(rate.Limit(1), 1)
+	now := time.Now()
+
+	assert.True(t, limiters.Limiter("pep-1", now).AllowN(now, 1))
+	assert.False(t, limiters.Limiter("pep-1", now).AllowN(now, 1), "the bucket of a client is kept between requests")
+	assert.True(t, limiters.Limiter("pep-2", now).AllowN(now, 1), "other clients have their own bucket")
+}
+
+func TestClientLimiters_PrunesIdleClients(t *testing.T) {
+	limiters := NewClientLimiters(rate.Limit(10), 10)
+	now := time.Now()
+
+	limiters.Limiter("pep-1", now)
+	limiters.Limiter("pep-2", now)
+	assert.Len(t, limiters.clients, 2)
+
+	limiters.clients["pep-1"].lastSeen = now.Add(-time.Hour)
+	limiters.lastPrune = now.Add(-time.Hour)
+	limiters.Limiter("pep-2", now)
+	assert.Len(t, limiters.clients, 1)
+	assert.Contains(t, limiters.clients, "pep-2")
+}
+
+func TestClientAddress(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	assert.Equal(t, "192.0.2.1", ClientAddress(req))
+	req.RemoteAddr = "[2001:db8::1]:1234"
+	assert.Equal(t, "2001:db8::1", ClientAddress(req))
+	req.RemoteAddr = "pipe"
+	assert.Equal(t, "pipe", ClientAddress(req))
+}
@@ -0,0 +1,147 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The servertls package builds the TLS configuration of the PDP HTTP server. The server
+// certificate is reloaded when its files change, so rotated certificates are picked up
+// without a restart. With a client CA configured, callers may present a client certificate.
+package servertls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/log"
+	"sync"
+	"time"
+)
+
+// the minimum TLS versions that can be configured
+var minVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Builds the TLS configuration of the HTTP server from TLS_CERT_FILE, TLS_KEY_FILE and TLS_MIN_VERSION.
+func NewConfig() (*tls.Config, error) {
+	if cfg.TlsCertFile == "" || cfg.TlsKeyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE are required unless ALLOW_PLAIN_HTTP is set")
+	}
+	minVersion, ok := minVersions[cfg.TlsMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS_MIN_VERSION %q, use 1.2 or 1.3", cfg.TlsMinVersion)
+	}
+
+	reloader, err := NewCertReloader(cfg.TlsCertFile, cfg.TlsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	// client certificates are optional, callers without one authenticate with a password
+	if cfg.TlsClientCaFile != "" {
+		clientCAs, err := loadCertPool(cfg.TlsClientCaFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		log.Infof("Client certificates issued by %s are accepted", cfg.TlsClientCaFile)
+	}
+	return tlsConfig, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pemCerts, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", caFile)
+	}
+	return pool, nil
+}
+
+// CertReloader serves a certificate and key pair from files, loading them again
+// when the modification time of either file changes.
+type CertReloader struct {
+	certFile    string
+	keyFile     string
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// Loads the certificate and key, failing when they cannot be loaded.
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Returns the current certificate, used as tls.Config.GetCertificate. A certificate that
+// changed is loaded first; while the new files cannot be loaded the previous certificate is kept.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.changed() {
+		if err := r.reload(); err != nil {
+			log.Warnf("Failed to reload the TLS certificate, keeping the previous one: %v", err)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *CertReloader) changed() bool {
+	certInfo, certErr := os.Stat(r.certFile)
+	keyInfo, keyErr := os.Stat(r.keyFile)
+	if certErr != nil || keyErr != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+}
+
+func (r *CertReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("error reading TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error reading TLS key: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	log.Infof("Loaded TLS certificate %s", r.certFile)
+	return nil
+}
@@ -0,0 +1,265 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"policy-opa-pdp/cfg"
+	"policy-opa-pdp/pkg/identity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writes a self-signed certificate for localhost with the given common name
+func writeCertificate(t *testing.T, certFile string, keyFile string, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func setupTLS(t *testing.T) {
+	certFile, keyFile, minVersion := cfg.TlsCertFile, cfg.TlsKeyFile, cfg.TlsMinVersion
+	t.Cleanup(func() { cfg.TlsCertFile, cfg.TlsKeyFile, cfg.TlsMinVersion = certFile, keyFile, minVersion })
+
+	dir := t.TempDir()
+	cfg.TlsCertFile = filepath.Join(dir, "tls.crt")
+	cfg.TlsKeyFile = filepath.Join(dir, "tls.key")
+	cfg.TlsMinVersion = "1.2"
+	writeCertificate(t, cfg.TlsCertFile, cfg.TlsKeyFile, "first")
+}
+
+// serves HTTPS with the TLS configuration and returns the URL of the server
+func startServer(t *testing.T, tlsConfig *tls.Config) string {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	assert.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if caller, ok := identity.FromRequest(req); ok {
+			res.Write([]byte(caller.Name))
+		}
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return "https://" + listener.Addr().String()
+}
+
+// returns the common name of the certificate the server presents
+func presentedCommonName(t *testing.T, url string, clientConfig *tls.Config) (string, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig, DisableKeepAlives: true}}
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	return res.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestNewConfig(t *testing.T) {
+	setupTLS(t)
+
+	tlsConfig, err := NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+
+	server := startServer(t, tlsConfig)
+	commonName, err := presentedCommonName(t, server, &tls.Config{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "first", commonName)
+}
+
+func TestNewConfig_Invalid(t *testing.T) {
+	setupTLS(t)
+
+	cfg.TlsMinVersion = "1.0"
+	_, err := NewConfig()
+	assert.ErrorContains(t, err, "TLS_MIN_VERSION")
+
+	cfg.TlsMinVersion = "1.2"
+	cfg.TlsKeyFile = filepath.Join(t.TempDir(), "missing.key")
+	_, err = NewConfig()
+	assert.Error(t, err)
+
+	cfg.TlsCertFile = ""
+	_, err = NewConfig()
+	assert.ErrorContains(t, err, "ALLOW_PLAIN_HTTP")
+}
+
+func TestNewConfig_MinVersion(t *testing.T) {
+	setupTLS(t)
+	cfg.TlsMinVersion = "1.3"
+
+	tlsConfig, err := NewConfig()
+	assert.NoError(t, err)
+	server := startServer(t, tlsConfig)
+
+	_, err = presentedCommonName(t, server, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+	assert.Error(t, err, "Expected a TLS 1.2 client to be refused")
+	_, err = presentedCommonName(t, server, &tls.Config{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+}
+
+func TestCertReloader_Rotation(t *testing.T) {
+	setupTLS(t)
+	tlsConfig, err := NewConfig()
+	assert.NoError(t, err)
+	server := startServer(t, tlsConfig)
+	clientConfig := func() *tls.Config { return &tls.Config{InsecureSkipVerify: true} }
+
+	// a rotated certificate is served from the next handshake on
+	writeCertificate(t, cfg.TlsCertFile, cfg.TlsKeyFile, "second")
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(cfg.TlsCertFile, later, later))
+	assert.NoError(t, os.Chtimes(cfg.TlsKeyFile, later, later))
+	commonName, err := presentedCommonName(t, server, clientConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", commonName)
+
+	// a broken certificate keeps the previous one in service
+	assert.NoError(t, os.WriteFile(cfg.TlsCertFile, []byte("not a certificate"), 0644))
+	evenLater := later.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(cfg.TlsCertFile, evenLater, evenLater))
+	commonName, err = presentedCommonName(t, server, clientConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", commonName)
+}
+
+// creates a CA and returns it with its key, written as PEM to caFile
+func writeCA(t *testing.T, caFile string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "client ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	return ca, key
+}
+
+// issues a client certificate carrying the SPIFFE id
+func issueClientCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, spiffeID string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	uri, err := url.Parse(spiffeID)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "pep"},
+		URIs:         []*url.URL{uri},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func requestCaller(t *testing.T, url string, clientConfig *tls.Config) (string, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig, DisableKeepAlives: true}}
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestNewConfig_ClientCertificates(t *testing.T) {
+	setupTLS(t)
+	clientCaFile := cfg.TlsClientCaFile
+	defer func() { cfg.TlsClientCaFile = clientCaFile }()
+	cfg.TlsClientCaFile = filepath.Join(t.TempDir(), "client-ca.crt")
+	ca, caKey := writeCA(t, cfg.TlsClientCaFile)
+	otherCA, otherKey := writeCA(t, filepath.Join(t.TempDir(), "other-ca.crt"))
+
+	tlsConfig, err := NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+	url := startServer(t, tlsConfig)
+
+	// a certificate issued by the client CA identifies the caller
+	caller, err := requestCaller(t, url, &tls.Config{InsecureSkipVerify: true,
+		Certificates: []tls.Certificate{issueClientCertificate(t, ca, caKey, "spiffe://example.org/pep")}})
+	assert.NoError(t, err)
+	assert.Equal(t, "spiffe://example.org/pep", caller)
+
+	// callers without a certificate are still served, they authenticate with a password
+	caller, err = requestCaller(t, url, &tls.Config{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	assert.Empty(t, caller)
+
+	// a certificate issued by another CA is refused
+	_, err = requestCaller(t, url, &tls.Config{InsecureSkipVerify: true,
+		Certificates: []tls.Certificate{issueClientCertificate(t, otherCA, otherKey, "spiffe://example.org/intruder")}})
+	assert.Error(t, err)
+}
+
+func TestNewConfig_InvalidClientCA(t *testing.T) {
+	setupTLS(t)
+	clientCaFile := cfg.TlsClientCaFile
+	defer func() { cfg.TlsClientCaFile = clientCaFile }()
+
+	cfg.TlsClientCaFile = filepath.Join(t.TempDir(), "missing.crt")
+	_, err := NewConfig()
+	assert.Error(t, err)
+
+	cfg.TlsClientCaFile = cfg.TlsKeyFile
+	_, err = NewConfig()
+	assert.ErrorContains(t, err, "no certificates found")
+}
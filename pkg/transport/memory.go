@@ -0,0 +1,156 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+package transport
+
+import (
+	"context"
+	"fmt"
+	"policy-opa-pdp/pkg/log"
+	"sync"
+	"time"
+)
+
+// the time Receive waits for a message before returning, matching the Kafka poll timeout
+const memoryPollInterval = 100 * time.Millisecond
+
+// InMemory is a Transport backed by buffered channels. The PAP side of the conversation
+// publishes messages with Publish and reads what the PDP sent from Sent.
+type InMemory struct {
+	inbound  chan *Message
+	outbound chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// Creates an in-memory transport holding up to buffer messages in each direction.
+func NewInMemory(buffer int) *InMemory {
+	return &InMemory{
+		inbound:  make(chan *Message, buffer),
+		outbound: make(chan []byte, buffer),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Waits up to the poll interval for the next message published by PAP.
+func (t *InMemory) Receive(ctx context.Context) (*Message, error) {
+	timer := time.NewTimer(memoryPollInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.closed:
+		return nil, ErrClosed
+	case message := <-t.inbound:
+		return message, nil
+	case <-timer.C:
+		return nil, nil
+	}
+}
+
+// Hands a message sent by the PDP to the PAP side, failing when the buffer is full.
+func (t *InMemory) Send(message []byte) error {
+	select {
+	case <-t.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case t.outbound <- message:
+		log.Debugf("[OUT|MEMORY]\n%s", string(message))
+		return nil
+	default:
+		return fmt.Errorf("in-memory transport buffer of %d messages is full", cap(t.outbound))
+	}
+}
+
+// Closes the transport, pending and later Receive and Send calls fail with ErrClosed.
+func (t *InMemory) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	return nil
+}
+
+// Delivers a message from PAP to the PDP, blocking while the buffer is full.
+func (t *InMemory) Publish(ctx context.Context, value []byte, headers map[string]string) error {
+	select {
+	case <-t.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.closed:
+		return ErrClosed
+	case t.inbound <- &Message{Value: value, Headers: headers}:
+		return nil
+	}
+}
+
+// Returns the channel of the messages sent by the PDP.
+func (t *InMemory) Sent() <-chan []byte {
+	return t.outbound
+}
+
+// Returns the PAP side of the transport, receiving what the PDP sent and sending to the PDP.
+// Closing the peer closes the transport.
+func (t *InMemory) Peer() Transport {
+	return &inMemoryPeer{transport: t}
+}
+
+// inMemoryPeer is the PAP end of an InMemory transport.
+type inMemoryPeer struct {
+	transport *InMemory
+}
+
+// Waits up to the poll interval for the next message sent by the PDP.
+func (p *inMemoryPeer) Receive(ctx context.Context) (*Message, error) {
+	timer := time.NewTimer(memoryPollInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.transport.closed:
+		return nil, ErrClosed
+	case value := <-p.transport.outbound:
+		return &Message{Value: value}, nil
+	case <-timer.C:
+		return nil, nil
+	}
+}
+
+// Hands a message from PAP to the PDP, failing when the buffer is full.
+func (p *inMemoryPeer) Send(message []byte) error {
+	select {
+	case <-p.transport.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case p.transport.inbound <- &Message{Value: message}:
+		log.Debugf("[IN|MEMORY]\n%s", string(message))
+		return nil
+	default:
+		return fmt.Errorf("in-memory transport buffer of %d messages is full", cap(p.transport.inbound))
+	}
+}
+
+// Closes the transport shared with the PDP.
+func (p *inMemoryPeer) Close() error {
+	return p.transport.Close()
+}
@@ -0,0 +1,112 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+//
+
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemory_PublishAndReceive(t *testing.T) {
+	tr := NewInMemory(1)
+	defer tr.Close()
+
+	assert.NoError(t, tr.Publish(context.Background(), []byte(`{"messageName":"PDP_UPDATE"}`), map[string]string{"key": "value"}))
+	message, err := tr.Receive(context.Background())
+	assert.NoError(t, err)
+	if assert.NotNil(t, message) {
+		assert.Equal(t, `{"messageName":"PDP_UPDATE"}`, string(message.Value))
+		assert.Equal(t, "value", message.Headers["key"])
+	}
+}
+
+func TestInMemory_ReceiveTimesOut(t *testing.T) {
+	tr := NewInMemory(1)
+	defer tr.Close()
+
+	start := time.Now()
+	message, err := tr.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, message)
+	assert.GreaterOrEqual(t, time.Since(start), memoryPollInterval)
+}
+
+func TestInMemory_ReceiveContextCancelled(t *testing.T) {
+	tr := NewInMemory(1)
+	defer tr.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := tr.Receive(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestInMemory_Send(t *testing.T) {
+	tr := NewInMemory(1)
+	defer tr.Close()
+
+	assert.NoError(t, tr.Send([]byte("status")))
+	assert.Error(t, tr.Send([]byte("status")), "Expected an error when the buffer is full")
+	assert.Equal(t, "status", string(<-tr.Sent()))
+}
+
+func TestInMemory_Closed(t *testing.T) {
+	tr := NewInMemory(1)
+	assert.NoError(t, tr.Close())
+	assert.NoError(t, tr.Close(), "Closing twice should not fail")
+
+	_, err := tr.Receive(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, tr.Send([]byte("status")), ErrClosed)
+	assert.ErrorIs(t, tr.Publish(context.Background(), []byte("update"), nil), ErrClosed)
+}
+
+func TestInMemory_Peer(t *testing.T) {
+	tr := NewInMemory(1)
+	peer := tr.Peer()
+
+	assert.NoError(t, peer.Send([]byte("update")))
+	assert.Error(t, peer.Send([]byte("update")), "Expected an error when the buffer is full")
+	message, err := tr.Receive(context.Background())
+	assert.NoError(t, err)
+	if assert.NotNil(t, message) {
+		assert.Equal(t, "update", string(message.Value))
+	}
+
+	assert.NoError(t, tr.Send([]byte("status")))
+	message, err = peer.Receive(context.Background())
+	assert.NoError(t, err)
+	if assert.NotNil(t, message) {
+		assert.Equal(t, "status", string(message.Value))
+	}
+	message, err = peer.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, message, "Expected nothing after the poll interval")
+
+	assert.NoError(t, peer.Close())
+	_, err = tr.Receive(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = peer.Receive(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, peer.Send([]byte("update")), ErrClosed)
+}
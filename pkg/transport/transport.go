@@ -0,0 +1,47 @@
+// -
+//   ========================LICENSE_START=================================
+//   Copyright (C) 2025: Deutsche Telekom
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//   SPDX-License-Identifier: Apache-2.0
+//   ========================LICENSE_END===================================
+
+// The transport package defines how the PDP exchanges messages with PAP. PAP messages are
+// received and PDP_STATUS messages are sent through a Transport, so the PDP protocol can run
+// over Kafka in production and over an in-memory channel in unit tests and local development.
+package transport
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned when a closed Transport is used.
+var ErrClosed = errors.New("transport is closed")
+
+// Message is a message received from PAP.
+type Message struct {
+	Value   []byte
+	Headers map[string]string
+}
+
+// Transport carries the messages exchanged between PAP and the PDP.
+type Transport interface {
+	// Waits for the next message from PAP. A nil message without error is returned when no
+	// message arrived within the transport poll interval, so callers can check for shutdown.
+	Receive(ctx context.Context) (*Message, error)
+	// Publishes a message, e.g. a PDP_STATUS, to PAP.
+	Send(message []byte) error
+	// Releases the resources held by the transport.
+	Close() error
+}